@@ -0,0 +1,4 @@
+// Package cachev1 содержит сгенерированный из cache.proto код gRPC API кэша (lrucache.v1).
+package cachev1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative cache/v1/cache.proto
@@ -2,11 +2,30 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/instinctG/lru-cache/internal/cluster"
 	"github.com/instinctG/lru-cache/internal/config"
+	grpcserver "github.com/instinctG/lru-cache/internal/grpc-server"
 	transportHTTP "github.com/instinctG/lru-cache/internal/http-server/handler"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	mw_compress "github.com/instinctG/lru-cache/internal/http-server/middleware/compress"
+	mw_ratelimit "github.com/instinctG/lru-cache/internal/http-server/middleware/ratelimit"
+	"github.com/instinctG/lru-cache/internal/http-server/tlsconfig"
 	sl "github.com/instinctG/lru-cache/internal/logger"
-	"github.com/instinctG/lru-cache/internal/lru"
+	"github.com/instinctG/lru-cache/internal/memcache"
+	"github.com/instinctG/lru-cache/internal/namespace"
+	"github.com/instinctG/lru-cache/internal/replication"
+	"github.com/instinctG/lru-cache/internal/resp"
+	"github.com/instinctG/lru-cache/internal/tenant"
+	"github.com/instinctG/lru-cache/pkg/lrucache"
 	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
 )
 
 // Run конфигурирует и запускает сервер с LRU-кэшом.
@@ -19,20 +38,314 @@ func Run() error {
 	log.Info("starting lru-cache", slog.String("LOG-LEVEL", cfg.LogLevel))
 	log.Debug("debug messages are enabled")
 
-	LRUCache := lru.NewLRUCache(cfg.CacheSize, cfg.DefaultCacheTTL)
+	// Профили конкуренции за мьютексы и блокировок доступны на диагностическом сервере.
+	runtime.SetMutexProfileFraction(cfg.MutexProfileFraction)
+	runtime.SetBlockProfileRate(cfg.BlockProfileRate)
 
-	handler := transportHTTP.NewHandler(LRUCache, cfg.Port, log)
+	auth, err := newAuthConfig(cfg)
+	if err != nil {
+		log.Error("invalid auth configuration", sl.Err(err))
+		return err
+	}
+
+	// Текстовый протокол memcached не поддерживает аутентификацию, поэтому при включенной аутентификации
+	// сервер memcached открыл бы кэш в обход нее.
+	if cfg.MemcacheAddress != "" && auth.Enabled() && !cfg.MemcacheAllowUnauthenticated {
+		err := errors.New("memcached protocol has no authentication, set MEMCACHE_ALLOW_UNAUTHENTICATED=true to run it with API authentication enabled")
+		log.Error("invalid memcache configuration", sl.Err(err))
+		return err
+	}
+
+	var tlsCfg *tls.Config
+	var reloader *tlsconfig.CertReloader
+	tlsSettings := tlsconfig.Config{
+		CertFile:     cfg.TLSCertFile,
+		KeyFile:      cfg.TLSKeyFile,
+		ClientCAFile: cfg.TLSClientCAFile,
+		ClientAuth:   cfg.TLSClientAuth,
+		MinVersion:   cfg.TLSMinVersion,
+	}
+	if tlsSettings.Enabled() {
+		if tlsCfg, reloader, err = tlsconfig.New(tlsSettings); err != nil {
+			log.Error("invalid tls configuration", sl.Err(err))
+			return err
+		}
+		go reloader.Watch(context.Background(), log, cfg.TLSReloadInterval)
+	}
+
+	rateLimit := mw_ratelimit.Config{Default: mw_ratelimit.Limit{Rate: cfg.RateLimit, Burst: cfg.RateLimitBurst}}
+	if rateLimit.AuthFailures, err = mw_ratelimit.ParseLimit(cfg.RateLimitAuthFailures); err != nil {
+		log.Error("invalid rate limit configuration", sl.Err(err))
+		return err
+	}
+	if cfg.RateLimitRoutes != "" {
+		if rateLimit.Routes, err = mw_ratelimit.ParseRoutes(cfg.RateLimitRoutes); err != nil {
+			log.Error("invalid rate limit configuration", sl.Err(err))
+			return err
+		}
+	}
+
+	socketMode, err := strconv.ParseUint(cfg.UnixSocketMode, 8, 32)
+	if err != nil {
+		log.Error("invalid unix socket mode", sl.Err(err))
+		return err
+	}
+
+	limits := transportHTTP.Limits{
+		MaxBodyBytes:          cfg.MaxBodyBytes,
+		MaxKeyLength:          cfg.MaxKeyLength,
+		MaxValueSize:          cfg.MaxValueSize,
+		DisallowUnknownFields: cfg.DisallowUnknownFields,
+	}
+
+	policy, err := lrucache.ParsePolicy(cfg.CachePolicy)
+	if err != nil {
+		log.Error("invalid cache policy", sl.Err(err))
+		return err
+	}
+
+	// Кэш по умолчанию обслуживает /api/lru, /api/v2, gRPC, RESP и memcached,
+	// именованные кэши доступны только по HTTP в /api/caches/{name}.
+	defaultSpec := namespace.Spec{Capacity: cfg.CacheSize, TTL: cfg.DefaultCacheTTL, Policy: policy}
+	LRUCache := namespace.NewCache(defaultSpec)
+
+	caches := namespace.NewRegistry(defaultSpec)
+	specs, err := namespace.ParseSpecs(cfg.Caches, defaultSpec)
+	if err != nil {
+		log.Error("invalid caches configuration", sl.Err(err))
+		return err
+	}
+	for _, spec := range specs {
+		if _, err := caches.Create(spec); err != nil {
+			log.Error("failed to create cache", slog.String("cache", spec.Name), sl.Err(err))
+			return err
+		}
+	}
+
+	opts := []transportHTTP.Option{
+		transportHTTP.WithDrainDelay(cfg.ShutdownDrainDelay),
+		transportHTTP.WithAdmin(cfg.AdminAddress),
+		transportHTTP.WithDocsAssets(cfg.DocsAssetsURL),
+		transportHTTP.WithAuth(auth),
+		transportHTTP.WithRateLimit(rateLimit),
+		transportHTTP.WithMaxInFlight(cfg.MaxInFlightRequests),
+		transportHTTP.WithTLS(tlsCfg),
+		transportHTTP.WithUnixSocketMode(os.FileMode(socketMode)),
+		transportHTTP.WithLimits(limits),
+	}
+	if cfg.H2C {
+		opts = append(opts, transportHTTP.WithH2C())
+	}
+	if cfg.CompressionEnabled {
+		opts = append(opts, transportHTTP.WithCompression(mw_compress.Config{
+			MinSize: cfg.CompressionMinSize,
+			Level:   cfg.CompressionLevel,
+		}))
+	}
+
+	// Протоколы без перенаправления запросов (gRPC, RESP, memcached) работают с кэшем реплики только на чтение.
+	var protocolCache interface {
+		resp.Cache
+		memcache.Cache
+	} = LRUCache
+
+	replicationCtx, stopReplication := context.WithCancel(context.Background())
+	defer stopReplication()
+
+	switch cfg.ReplicationRole {
+	case "":
+	case replication.RolePrimary:
+		primary := replication.NewPrimary(LRUCache, log, replication.WithBacklog(cfg.ReplicationBacklog))
+		go primary.Run(replicationCtx)
+		log.Info("replication primary enabled", slog.String("id", primary.ID()))
+
+		opts = append(opts, transportHTTP.WithReplicationPrimary(primary))
+	case replication.RoleReplica:
+		replicaOpts := []replication.ReplicaOption{replication.WithAPIKey(cfg.ReplicationAPIKey)}
+		// Реплика подключается к ведущему узлу с сертификатом узла и доверяет CA из TLS_CLIENT_CA_FILE.
+		if tlsCfg != nil {
+			clientTLS, err := tlsconfig.NewClient(tlsSettings, reloader)
+			if err != nil {
+				log.Error("invalid tls configuration", sl.Err(err))
+				return err
+			}
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = clientTLS
+			replicaOpts = append(replicaOpts, replication.WithClient(&http.Client{Transport: transport}))
+		}
+
+		replica, err := replication.NewReplica(cfg.ReplicationPrimary, LRUCache, log, replicaOpts...)
+		if err != nil {
+			log.Error("invalid replication configuration", sl.Err(err))
+			return err
+		}
+		go replica.Run(replicationCtx)
+		log.Info("replication replica enabled", slog.String("primary", replica.Primary()))
+
+		protocolCache = replication.ReadOnly{Cache: LRUCache}
+		opts = append(opts, transportHTTP.WithReplica(replica))
+	default:
+		err := errors.New("unknown replication role " + cfg.ReplicationRole + ", expected primary or replica")
+		log.Error("invalid replication configuration", sl.Err(err))
+		return err
+	}
+
+	if cfg.GRPCAddress != "" {
+		svc := grpcserver.NewService(protocolCache, log, grpcserver.WithLimits(limits), grpcserver.WithAuth(auth))
+		opts = append(opts, transportHTTP.WithGRPC(cfg.GRPCAddress, grpcserver.NewServer(svc, tlsCfg)))
+	}
+
+	// Арендаторы работают с собственными кэшами, поэтому вытеснение из-за одного арендатора не затрагивает других.
+	quotas, err := tenant.ParseQuotas(cfg.Tenants)
+	if err != nil {
+		log.Error("invalid tenants configuration", sl.Err(err))
+		return err
+	}
+	tenants := tenant.NewRegistry(cfg.DefaultCacheTTL)
+	for name, quota := range quotas {
+		if _, _, err := tenants.Set(name, quota); err != nil {
+			log.Error("failed to create tenant", slog.String("tenant", name), sl.Err(err))
+			return err
+		}
+	}
+
+	opts = append(opts, transportHTTP.WithCaches(caches), transportHTTP.WithTenants(tenants, cfg.TenantHeader))
+
+	if cfg.ClusterSelf != "" {
+		c, err := cluster.New(cfg.ClusterSelf, cfg.ClusterPeers, log,
+			cluster.WithVirtualNodes(cfg.ClusterVirtualNodes),
+			cluster.WithForwardTimeout(cfg.ClusterForwardTimeout),
+			cluster.WithSecret(cfg.ClusterSecret),
+		)
+		if err != nil {
+			log.Error("invalid cluster configuration", sl.Err(err))
+			return err
+		}
+		log.Info("cluster mode enabled", slog.String("self", c.Self()), slog.Any("peers", c.Peers()))
+		if cfg.ClusterSecret == "" {
+			log.Warn("CLUSTER_SECRET is not set, requests forwarded by peers are handled as client requests")
+		}
+
+		opts = append(opts, transportHTTP.WithCluster(c))
+	}
+
+	handler := transportHTTP.NewHandler(LRUCache, cfg.Port, log, opts...)
+
+	// Сервер протокола Redis работает с тем же экземпляром кэша, что и HTTP API.
+	var respServer *resp.Server
+	if cfg.RESPAddress != "" {
+		respServer = resp.NewServer(cfg.RESPAddress, protocolCache, log,
+			resp.WithMaxBulkLength(int(cfg.MaxBodyBytes)),
+			resp.WithAuth(auth),
+		)
+		go func() {
+			if err := respServer.ListenAndServe(); err != nil && !errors.Is(err, resp.ErrServerClosed) {
+				log.Error("resp server failed", sl.Err(err))
+			}
+		}()
+	}
+
+	var memcacheServer *memcache.Server
+	if cfg.MemcacheAddress != "" {
+		memcacheServer = memcache.NewServer(cfg.MemcacheAddress, protocolCache, log,
+			memcache.WithMaxItemSize(cfg.MaxValueSize),
+			memcache.WithDefaultTTL(cfg.DefaultCacheTTL),
+		)
+		go func() {
+			if err := memcacheServer.ListenAndServe(); err != nil && !errors.Is(err, memcache.ErrServerClosed) {
+				log.Error("memcache server failed", sl.Err(err))
+			}
+		}()
+	}
 
 	if err := handler.Serve(); err != nil {
 		log.Error("failed to start server")
 		return err
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if respServer != nil {
+		if err := respServer.Shutdown(ctx); err != nil {
+			log.Error("failed to shutdown resp server", sl.Err(err))
+		}
+	}
+	if memcacheServer != nil {
+		if err := memcacheServer.Shutdown(ctx); err != nil {
+			log.Error("failed to shutdown memcache server", sl.Err(err))
+		}
+	}
+
 	log.Info("server started")
 
 	return nil
 }
 
+// newAuthConfig создает параметры аутентификации из конфигурации.
+// Если не задан ни один API-ключ и ни один ключ проверки JWT, аутентификация отключена.
+func newAuthConfig(cfg *config.Config) (mw_auth.Config, error) {
+	auth := mw_auth.Config{PublicPaths: cfg.AuthPublicPaths, DefaultScopes: cfg.AuthDefaultScopes}
+
+	// Клиентский сертификат проверяется при установке соединения, поэтому проверяется первым.
+	if cfg.TLSClientCAFile != "" {
+		auth.Authenticators = append(auth.Authenticators, mw_auth.NewClientCertAuthenticator())
+	}
+
+	if cfg.AuthScopes != "" {
+		scopes, err := mw_auth.ParseScopes(cfg.AuthScopes)
+		if err != nil {
+			return auth, err
+		}
+		auth.Scopes = scopes
+	}
+
+	keys := make(map[string]string)
+	if cfg.APIKeys != "" {
+		parsed, err := mw_auth.ParseAPIKeys(cfg.APIKeys)
+		if err != nil {
+			return auth, err
+		}
+		for name, key := range parsed {
+			keys[name] = key
+		}
+	}
+	if cfg.APIKeysFile != "" {
+		loaded, err := mw_auth.LoadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return auth, err
+		}
+		for name, key := range loaded {
+			keys[name] = key
+		}
+	}
+	if len(keys) > 0 {
+		auth.Authenticators = append(auth.Authenticators, mw_auth.NewAPIKeyAuthenticator(keys))
+	}
+
+	jwtCfg := mw_auth.JWTConfig{
+		HS256Secret: []byte(cfg.JWTHS256Secret),
+		Issuer:      cfg.JWTIssuer,
+		Audience:    cfg.JWTAudience,
+		Leeway:      time.Minute,
+	}
+	if cfg.JWTRS256PublicKeyFile != "" {
+		key, err := mw_auth.LoadRSAPublicKey(cfg.JWTRS256PublicKeyFile)
+		if err != nil {
+			return auth, err
+		}
+		jwtCfg.RS256PublicKey = key
+	}
+	if len(jwtCfg.HS256Secret) > 0 || jwtCfg.RS256PublicKey != nil {
+		jwt, err := mw_auth.NewJWTAuthenticator(jwtCfg)
+		if err != nil {
+			return auth, err
+		}
+		auth.Authenticators = append(auth.Authenticators, jwt)
+	}
+
+	return auth, nil
+}
+
 func main() {
 	if err := Run(); err != nil {
 		slog.Error("could not run the application", sl.Err(err))
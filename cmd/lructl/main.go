@@ -0,0 +1,31 @@
+// Package main - точка входа консольного клиента lructl для операторов сервиса кэша.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/instinctG/lru-cache/internal/lructl"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	cli := &lructl.CLI{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Getenv: os.Getenv,
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		cli.HistoryFile = filepath.Join(home, ".lructl_history")
+	}
+
+	if err := cli.Run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		if errors.Is(err, lructl.ErrUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
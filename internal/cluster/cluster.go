@@ -0,0 +1,360 @@
+// Package cluster реализует кластерный режим: узлы со статическим списком участников распределяют ключи
+// по кольцу консистентного хеширования, а запрос, пришедший не на тот узел, прозрачно пересылается
+// узлу-владельцу ключа по HTTP.
+package cluster
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ForwardedHeader содержит адрес узла, переславшего запрос. Узел обрабатывает такой запрос сам,
+	// даже если по его кольцу ключ принадлежит другому участнику, поэтому запрос не может зациклиться.
+	// Заголовок учитывается, только если запрос подписан общим секретом кластера (см. Forwarded).
+	ForwardedHeader = "X-Lru-Forwarded-By"
+	// SignatureHeader содержит подпись пересланного запроса в формате "<время Unix>.<HMAC-SHA256 в hex>".
+	SignatureHeader = "X-Lru-Forward-Signature"
+	// PrincipalHeader содержит субъекта, аутентифицированного узлом, который получил запрос от клиента,
+	// в виде JSON в base64url. Заголовок входит в подпись и учитывается только в подписанном запросе.
+	PrincipalHeader = "X-Lru-Forwarded-Principal"
+	// OwnerHeader содержит адрес узла, обработавшего запрос с ключом.
+	OwnerHeader = "X-Lru-Owner"
+)
+
+// DefaultForwardTimeout - время ожидания ответа узла-владельца по умолчанию.
+const DefaultForwardTimeout = 5 * time.Second
+
+// MaxSignatureAge - максимальное расхождение времени подписи пересланного запроса и часов узла.
+const MaxSignatureAge = 30 * time.Second
+
+// Cluster описывает кластер с точки зрения одного узла.
+type Cluster struct {
+	self    string                            // Адрес этого узла.
+	ring    *Ring                             // Кольцо участников, неизменяемое после создания.
+	proxies map[string]*httputil.ReverseProxy // Пересылка запросов другим участникам.
+	log     *slog.Logger
+
+	virtualNodes int
+	transport    http.RoundTripper
+	secret       []byte           // Общий секрет для подписи пересланных запросов, пустой - подписи не проверяются.
+	now          func() time.Time // Часы для подписи, подменяются в тестах.
+}
+
+// Option задает параметры кластера.
+type Option func(c *Cluster)
+
+// WithVirtualNodes задает количество виртуальных узлов на участника. По умолчанию DefaultVirtualNodes.
+func WithVirtualNodes(n int) Option {
+	return func(c *Cluster) {
+		c.virtualNodes = n
+	}
+}
+
+// WithTransport задает транспорт для пересылки запросов. По умолчанию используется копия
+// http.DefaultTransport с временем ожидания ответа DefaultForwardTimeout.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Cluster) {
+		c.transport = rt
+	}
+}
+
+// WithSecret задает общий секрет, которым узлы подписывают пересланные запросы. Секрет должен совпадать
+// на всех узлах. Без секрета заголовок ForwardedHeader не учитывается, и пересланный запрос
+// обрабатывается так же, как запрос клиента.
+func WithSecret(secret string) Option {
+	return func(c *Cluster) {
+		c.secret = []byte(secret)
+	}
+}
+
+// WithForwardTimeout задает время ожидания заголовков ответа узла-владельца для транспорта по умолчанию.
+func WithForwardTimeout(d time.Duration) Option {
+	return func(c *Cluster) {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.ResponseHeaderTimeout = d
+		c.transport = t
+	}
+}
+
+// New создает кластер. self - адрес этого узла, peers - адреса всех участников, включая self,
+// в виде базовых URL (например, http://10.0.0.1:8080). Все узлы должны получить одинаковый список участников.
+func New(self string, peers []string, log *slog.Logger, opts ...Option) (*Cluster, error) {
+	c := &Cluster{
+		self:    strings.TrimSuffix(self, "/"),
+		proxies: make(map[string]*httputil.ReverseProxy),
+		log:     log,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.transport == nil {
+		WithForwardTimeout(DefaultForwardTimeout)(c)
+	}
+
+	if _, err := parsePeer(c.self); err != nil {
+		return nil, fmt.Errorf("invalid cluster self address: %w", err)
+	}
+
+	normalized := make([]string, 0, len(peers))
+	hasSelf := false
+	for _, peer := range peers {
+		peer = strings.TrimSuffix(strings.TrimSpace(peer), "/")
+		if peer == "" {
+			continue
+		}
+
+		target, err := parsePeer(peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cluster peer: %w", err)
+		}
+		normalized = append(normalized, peer)
+
+		if peer == c.self {
+			hasSelf = true
+			continue
+		}
+		if _, exists := c.proxies[peer]; !exists {
+			c.proxies[peer] = c.newProxy(peer, target)
+		}
+	}
+	if !hasSelf {
+		return nil, errors.New("cluster peers must include the self address " + c.self)
+	}
+
+	c.ring = NewRing(c.virtualNodes, normalized...)
+
+	return c, nil
+}
+
+// parsePeer разбирает адрес участника: URL со схемой http или https и адресом узла без пути.
+func parsePeer(peer string) (*url.URL, error) {
+	u, err := url.Parse(peer)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" || u.Path != "" {
+		return nil, fmt.Errorf("%q: expected http(s)://host:port", peer)
+	}
+	return u, nil
+}
+
+// newProxy создает пересылку запросов участнику peer. Путь, параметры и заголовки запроса сохраняются,
+// поэтому владелец сам проверяет аутентификацию, права и квоты. Сертификат клиента при пересылке теряется,
+// поэтому субъект передается в PrincipalHeader (см. Authenticate).
+func (c *Cluster) newProxy(peer string, target *url.URL) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			c.stamp(pr.Out)
+		},
+		Transport: c.transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			c.log.Error("failed to forward request", slog.String("peer", peer), sl.Err(err))
+
+			problem.Write(w, r, problem.New(http.StatusBadGateway, problem.CodePeerUnavailable, "peer "+peer+" is unavailable"))
+		},
+	}
+}
+
+// Self возвращает адрес этого узла.
+func (c *Cluster) Self() string {
+	return c.self
+}
+
+// Peers возвращает адреса участников в порядке имен.
+func (c *Cluster) Peers() []string {
+	return c.ring.Peers()
+}
+
+// Owner возвращает адрес участника, которому принадлежит ключ.
+func (c *Cluster) Owner(key string) string {
+	return c.ring.Get(key)
+}
+
+// Forwarded сообщает, переслан ли запрос r другим участником кластера: ForwardedHeader содержит адрес
+// участника, а SignatureHeader - подпись общим секретом, созданную не раньше MaxSignatureAge назад.
+// Без секрета (WithSecret) всегда возвращает false.
+func (c *Cluster) Forwarded(r *http.Request) bool {
+	by := r.Header.Get(ForwardedHeader)
+	if len(c.secret) == 0 || by == "" {
+		return false
+	}
+	if _, ok := c.proxies[by]; !ok {
+		return false
+	}
+
+	signature := r.Header.Get(SignatureHeader)
+	ts, _, _ := strings.Cut(signature, ".")
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := c.now().Sub(time.Unix(unix, 0)); age > MaxSignatureAge || age < -MaxSignatureAge {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(c.sign(by, unix, r)))
+}
+
+// FromPeer сообщает, что ForwardedHeader запроса r содержит адрес другого участника кластера, независимо
+// от подписи. Используется только для того, чтобы не рассылать запрос повторно: копии Broadcast обрабатываются
+// локально и без секрета, иначе каждый получатель снова разослал бы запрос всем узлам. Подделанный заголовок
+// лишь ограничивает ответ данными одного узла, права клиента проверяются как обычно.
+func (c *Cluster) FromPeer(r *http.Request) bool {
+	_, ok := c.proxies[r.Header.Get(ForwardedHeader)]
+	return ok
+}
+
+// Authenticate реализует mw_auth.Authenticator для запросов, пересланных участниками кластера: субъектом
+// считается субъект из PrincipalHeader, которого аутентифицировал узел, получивший запрос от клиента
+// (в том числе по сертификату клиента, который не передается при пересылке). Если запрос не подписан
+// участником кластера или не содержит PrincipalHeader, возвращается mw_auth.ErrNoCredentials.
+func (c *Cluster) Authenticate(r *http.Request) (*mw_auth.Principal, error) {
+	value := r.Header.Get(PrincipalHeader)
+	if value == "" || !c.Forwarded(r) {
+		return nil, mw_auth.ErrNoCredentials
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, mw_auth.ErrInvalidCredentials
+	}
+	var p forwardedPrincipal
+	if err := json.Unmarshal(data, &p); err != nil || p.Name == "" {
+		return nil, mw_auth.ErrInvalidCredentials
+	}
+
+	return &mw_auth.Principal{Name: p.Name, Method: p.Method, Scopes: p.Scopes}, nil
+}
+
+// forwardedPrincipal - представление субъекта в PrincipalHeader.
+type forwardedPrincipal struct {
+	Name   string   `json:"name"`
+	Method string   `json:"method"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// stamp помечает исходящий запрос out как пересланный этим узлом и подписывает его, если задан секрет.
+// Вместе с подписью передается субъект запроса: без секрета владелец не может ему доверять.
+func (c *Cluster) stamp(out *http.Request) {
+	out.Header.Set(ForwardedHeader, c.self)
+	out.Header.Del(SignatureHeader)
+	out.Header.Del(PrincipalHeader)
+	if len(c.secret) == 0 {
+		return
+	}
+
+	if p, ok := mw_auth.FromContext(out.Context()); ok {
+		data, err := json.Marshal(forwardedPrincipal{Name: p.Name, Method: p.Method, Scopes: p.Scopes})
+		if err == nil {
+			out.Header.Set(PrincipalHeader, base64.RawURLEncoding.EncodeToString(data))
+		}
+	}
+	out.Header.Set(SignatureHeader, c.sign(c.self, c.now().Unix(), out))
+}
+
+// sign подписывает пересылку запроса r узлом by: подпись покрывает адрес узла, время, метод, путь запроса
+// и субъекта из PrincipalHeader.
+func (c *Cluster) sign(by string, unix int64, r *http.Request) string {
+	mac := hmac.New(sha256.New, c.secret)
+	_, _ = fmt.Fprintf(mac, "%s\n%d\n%s\n%s\n%s", by, unix, r.Method, r.URL.RequestURI(), r.Header.Get(PrincipalHeader))
+
+	return strconv.FormatInt(unix, 10) + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// PeerResponse - ответ участника на запрос, разосланный Broadcast.
+type PeerResponse struct {
+	Peer   string      // Адрес участника.
+	Status int         // Код ответа.
+	Header http.Header // Заголовки ответа.
+	Body   []byte      // Тело ответа.
+	Err    error       // Ошибка отправки запроса или чтения ответа, тогда остальные поля не заполнены.
+}
+
+// Broadcast отправляет копию запроса r без тела всем участникам, кроме этого узла, как пересланный запрос
+// и возвращает их ответы в порядке Peers. Участники обрабатывают такой запрос локально.
+func (c *Cluster) Broadcast(r *http.Request) []PeerResponse {
+	client := &http.Client{Transport: c.transport}
+
+	var peers []string
+	for _, peer := range c.ring.Peers() {
+		if peer != c.self {
+			peers = append(peers, peer)
+		}
+	}
+
+	responses := make([]PeerResponse, len(peers))
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = c.send(client, r, peer)
+		}()
+	}
+	wg.Wait()
+
+	return responses
+}
+
+// send отправляет копию запроса r участнику peer.
+func (c *Cluster) send(client *http.Client, r *http.Request, peer string) PeerResponse {
+	resp := PeerResponse{Peer: peer}
+
+	out, err := http.NewRequestWithContext(r.Context(), r.Method, peer+r.URL.RequestURI(), nil)
+	if err != nil {
+		resp.Err = err
+		return resp
+	}
+	out.Header = r.Header.Clone()
+	// Тело ответа объединяется с другими ответами, поэтому сжатие оставляется транспорту.
+	out.Header.Del("Accept-Encoding")
+	c.stamp(out)
+
+	res, err := client.Do(out)
+	if err != nil {
+		c.log.Error("failed to broadcast request", slog.String("peer", peer), sl.Err(err))
+		resp.Err = err
+		return resp
+	}
+	defer res.Body.Close()
+
+	if resp.Body, err = io.ReadAll(res.Body); err != nil {
+		resp.Err = err
+		return resp
+	}
+	resp.Status, resp.Header = res.StatusCode, res.Header
+
+	return resp
+}
+
+// Forward пересылает запрос участнику peer и записывает его ответ.
+func (c *Cluster) Forward(w http.ResponseWriter, r *http.Request, peer string) {
+	proxy, ok := c.proxies[peer]
+	if !ok {
+		problem.Write(w, r, problem.New(http.StatusBadGateway, problem.CodePeerUnavailable, "unknown peer "+peer))
+		return
+	}
+
+	proxy.ServeHTTP(w, r)
+}
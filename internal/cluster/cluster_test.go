@@ -0,0 +1,195 @@
+package cluster_test
+
+import (
+	"github.com/instinctG/lru-cache/internal/cluster"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	"github.com/instinctG/lru-cache/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// owners возвращает владельца каждого ключа key-0..key-(n-1).
+func owners(r *cluster.Ring, n int) []string {
+	result := make([]string, n)
+	for i := range result {
+		result[i] = r.Get("key-" + strconv.Itoa(i))
+	}
+	return result
+}
+
+func TestRing_Distribution(t *testing.T) {
+	const keys = 10000
+	peers := []string{"http://a:8080", "http://b:8080", "http://c:8080"}
+
+	counts := make(map[string]int)
+	for _, owner := range owners(cluster.NewRing(0, peers...), keys) {
+		counts[owner]++
+	}
+
+	require.Len(t, counts, len(peers))
+	for peer, count := range counts {
+		// Идеальная доля - 1/3, виртуальные узлы держат отклонение в пределах нескольких процентов.
+		assert.InDelta(t, keys/len(peers), count, keys*0.08, peer)
+	}
+}
+
+func TestRing_Deterministic(t *testing.T) {
+	a := cluster.NewRing(0, "http://a:8080", "http://b:8080", "http://c:8080")
+	b := cluster.NewRing(0, "http://c:8080", "http://a:8080", "http://b:8080", "http://a:8080")
+
+	assert.Equal(t, owners(a, 1000), owners(b, 1000), "the ring must not depend on the order of peers")
+	assert.Equal(t, []string{"http://a:8080", "http://b:8080", "http://c:8080"}, b.Peers())
+	assert.Empty(t, cluster.NewRing(0).Get("key"))
+}
+
+func TestRing_AddRemoveMovesFewKeys(t *testing.T) {
+	const keys = 10000
+	ring := cluster.NewRing(0, "http://a:8080", "http://b:8080", "http://c:8080")
+	before := owners(ring, keys)
+
+	// Новый участник забирает около 1/4 ключей, и только у остальных участников.
+	ring.Add("http://d:8080")
+	after := owners(ring, keys)
+
+	moved := 0
+	for i := range before {
+		if before[i] != after[i] {
+			moved++
+			assert.Equal(t, "http://d:8080", after[i])
+		}
+	}
+	assert.InDelta(t, keys/4, moved, keys*0.08)
+
+	// Удаление участника переносит только его ключи.
+	ring.Remove("http://a:8080")
+	removed := owners(ring, keys)
+
+	moved = 0
+	for i := range after {
+		if after[i] != removed[i] {
+			moved++
+			assert.Equal(t, "http://a:8080", after[i])
+		}
+	}
+	assert.InDelta(t, keys/4, moved, keys*0.08)
+}
+
+func TestNew(t *testing.T) {
+	log := logger.NewDiscardLogger()
+
+	tests := []struct {
+		name    string
+		self    string
+		peers   []string
+		wantErr bool
+	}{
+		{name: "Valid", self: "http://a:8080/", peers: []string{"http://a:8080", " http://b:8080/", ""}},
+		{name: "Self not in peers", self: "http://a:8080", peers: []string{"http://b:8080"}, wantErr: true},
+		{name: "Invalid self", self: "a:8080", peers: []string{"a:8080"}, wantErr: true},
+		{name: "Peer without scheme", self: "http://a:8080", peers: []string{"http://a:8080", "b:8080"}, wantErr: true},
+		{name: "Peer with path", self: "http://a:8080", peers: []string{"http://a:8080", "http://b:8080/api"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := cluster.New(tt.self, tt.peers, log)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "http://a:8080", c.Self())
+			assert.Equal(t, []string{"http://a:8080", "http://b:8080"}, c.Peers())
+		})
+	}
+}
+
+func TestCluster_Forwarded(t *testing.T) {
+	log := logger.NewDiscardLogger()
+
+	var forwarded []bool
+	var receiver *cluster.Cluster
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = append(forwarded, receiver.Forwarded(r))
+	}))
+	defer srv.Close()
+
+	const other = "http://other:8080"
+	peers := []string{srv.URL, other}
+
+	var err error
+	receiver, err = cluster.New(srv.URL, peers, log, cluster.WithSecret("secret"))
+	require.NoError(t, err)
+
+	forward := func(self string, opts ...cluster.Option) {
+		c, err := cluster.New(self, peers, log, opts...)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v2/cache/entries/a%2Fb?x=1", nil)
+		c.Forward(httptest.NewRecorder(), req, srv.URL)
+	}
+
+	forward(other, cluster.WithSecret("secret"))
+	forward(other, cluster.WithSecret("wrong"))
+	forward(other)
+
+	// Клиент не может выдать себя за участника кластера.
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v2/cache/entries/k", nil)
+	require.NoError(t, err)
+	req.Header.Set(cluster.ForwardedHeader, other)
+	req.Header.Set(cluster.SignatureHeader, "0.00")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, []bool{true, false, false, false}, forwarded)
+}
+
+func TestCluster_Authenticate(t *testing.T) {
+	log := logger.NewDiscardLogger()
+
+	var principals []*mw_auth.Principal
+	var errs []error
+	var receiver *cluster.Cluster
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := receiver.Authenticate(r)
+		principals, errs = append(principals, p), append(errs, err)
+	}))
+	defer srv.Close()
+
+	const other = "http://other:8080"
+	peers := []string{srv.URL, other}
+
+	var err error
+	receiver, err = cluster.New(srv.URL, peers, log, cluster.WithSecret("secret"))
+	require.NoError(t, err)
+	sender, err := cluster.New(other, peers, log, cluster.WithSecret("secret"))
+	require.NoError(t, err)
+
+	// Субъект, аутентифицированный отправителем, например по сертификату клиента.
+	principal := &mw_auth.Principal{Name: "svc", Method: mw_auth.MethodClientCert, Scopes: []string{mw_auth.ScopeWrite}}
+	req := httptest.NewRequest(http.MethodDelete, "/api/v2/cache/entries/k", nil)
+	sender.Forward(httptest.NewRecorder(), req.WithContext(mw_auth.NewContext(req.Context(), principal)), srv.URL)
+
+	// Запрос без субъекта.
+	sender.Forward(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v2/cache/entries/k", nil), srv.URL)
+
+	// Клиент не может передать субъекта в заголовке сам.
+	spoofed, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v2/cache/entries/k", nil)
+	require.NoError(t, err)
+	spoofed.Header.Set(cluster.ForwardedHeader, other)
+	spoofed.Header.Set(cluster.PrincipalHeader, "eyJuYW1lIjoiYWRtaW4ifQ")
+	resp, err := http.DefaultClient.Do(spoofed)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Len(t, principals, 3)
+	require.NoError(t, errs[0])
+	assert.Equal(t, principal, principals[0])
+	assert.ErrorIs(t, errs[1], mw_auth.ErrNoCredentials)
+	assert.ErrorIs(t, errs[2], mw_auth.ErrNoCredentials)
+}
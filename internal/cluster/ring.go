@@ -0,0 +1,121 @@
+package cluster
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// DefaultVirtualNodes - количество виртуальных узлов на участника кольца по умолчанию.
+// Чем их больше, тем равномернее ключи распределяются между участниками.
+const DefaultVirtualNodes = 128
+
+// Ring - кольцо консистентного хеширования с виртуальными узлами. Каждый участник занимает на кольце
+// несколько точек, а ключ принадлежит участнику первой точки по часовой стрелке от хеша ключа.
+// Поэтому при добавлении или удалении участника переносится только около 1/N ключей.
+//
+// Ring не потокобезопасен: его нельзя изменять одновременно с чтением.
+type Ring struct {
+	replicas int               // Количество виртуальных узлов на участника.
+	hashes   []uint64          // Отсортированные точки кольца.
+	owners   map[uint64]string // Участник каждой точки кольца.
+	peers    map[string]struct{}
+}
+
+// NewRing создает кольцо с участниками peers. replicas - количество виртуальных узлов на участника,
+// если оно не положительно, используется DefaultVirtualNodes.
+func NewRing(replicas int, peers ...string) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultVirtualNodes
+	}
+
+	r := &Ring{
+		replicas: replicas,
+		owners:   make(map[uint64]string),
+		peers:    make(map[string]struct{}),
+	}
+	r.Add(peers...)
+
+	return r
+}
+
+// Add добавляет участников в кольцо. Уже добавленные участники пропускаются.
+func (r *Ring) Add(peers ...string) {
+	for _, peer := range peers {
+		if _, exists := r.peers[peer]; exists {
+			continue
+		}
+		r.peers[peer] = struct{}{}
+
+		for i := 0; i < r.replicas; i++ {
+			h := hash(strconv.Itoa(i) + "#" + peer)
+			// При совпадении хешей точка остается за участником с меньшим именем, чтобы кольцо
+			// не зависело от порядка добавления участников.
+			if owner, exists := r.owners[h]; exists {
+				if owner > peer {
+					r.owners[h] = peer
+				}
+				continue
+			}
+			r.owners[h] = peer
+			r.hashes = append(r.hashes, h)
+		}
+	}
+
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+}
+
+// Remove удаляет участника из кольца.
+func (r *Ring) Remove(peer string) {
+	if _, exists := r.peers[peer]; !exists {
+		return
+	}
+	delete(r.peers, peer)
+
+	// Точки удаленного участника пересобираются, чтобы совпавшие хеши перешли к оставшимся участникам.
+	peers := r.Peers()
+	r.hashes, r.owners, r.peers = nil, make(map[uint64]string), make(map[string]struct{})
+	r.Add(peers...)
+}
+
+// Get возвращает участника, которому принадлежит ключ. Для пустого кольца возвращается пустая строка.
+func (r *Ring) Get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	h := hash(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+
+	return r.owners[r.hashes[i]]
+}
+
+// Peers возвращает участников кольца в порядке имен.
+func (r *Ring) Peers() []string {
+	peers := make([]string, 0, len(r.peers))
+	for peer := range r.peers {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+
+	return peers
+}
+
+// hash возвращает 64-битный хеш FNV-1a строки. Биты хеша дополнительно перемешиваются финализатором MurmurHash3,
+// потому что у FNV-1a похожие короткие строки (имена виртуальных узлов) дают близкие хеши.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	return x
+}
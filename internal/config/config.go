@@ -9,15 +9,86 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"strings"
 	"time"
 )
 
 // Config содержит значения конфигурации приложения.
 type Config struct {
-	Port            string        `env:"SERVER_HOST_PORT" envDefault:":8080"` // Порт, на котором будет запущен сервер.
+	Port            string        `env:"SERVER_HOST_PORT" envDefault:":8080"` // Адрес сервера: host:port или unix:///path.sock.
 	CacheSize       int           `env:"CACHE_SIZE" envDefault:"10"`          // Максимальное количество элементов в кэше.
 	LogLevel        string        `env:"LOG_LEVEL" envDefault:"WARN"`         // Уровень логирования приложения.
 	DefaultCacheTTL time.Duration `env:"DEFAULT_CACHE_TTL" envDefault:"1m"`   // Время жизни записей в кэше по умолчанию.
+	CachePolicy     string        `env:"CACHE_POLICY" envDefault:"lru"`       // Политика вытеснения кэша по умолчанию: lru или fifo.
+	Caches          string        `env:"CACHES"`                              // Именованные кэши в формате "имя=емкость:ttl[:политика],...".
+
+	// Арендаторы определяются по имени API-ключа (субъекта) или, при отключенной аутентификации, по заголовку TENANT_HEADER.
+	Tenants      string `env:"TENANTS"`       // Квоты арендаторов в формате "имя=элементы[:байты[:ttl]],...".
+	TenantHeader string `env:"TENANT_HEADER"` // Заголовок запроса с именем арендатора, пустой - арендатор определяется только по API-ключу.
+
+	// Кластерный режим включается, если задан адрес узла CLUSTER_SELF.
+	ClusterSelf           string        `env:"CLUSTER_SELF"`                            // Базовый URL этого узла, например http://10.0.0.1:8080.
+	ClusterPeers          []string      `env:"CLUSTER_PEERS" envSeparator:","`          // Базовые URL всех узлов кластера, включая этот.
+	ClusterVirtualNodes   int           `env:"CLUSTER_VIRTUAL_NODES" envDefault:"128"`  // Количество виртуальных узлов на участника кольца.
+	ClusterForwardTimeout time.Duration `env:"CLUSTER_FORWARD_TIMEOUT" envDefault:"5s"` // Время ожидания ответа узла-владельца ключа.
+	ClusterSecret         string        `env:"CLUSTER_SECRET"`                          // Общий секрет узлов для подписи пересланных запросов.
+
+	// Репликация включается ролью REPLICATION_ROLE: primary или replica.
+	ReplicationRole    string `env:"REPLICATION_ROLE"`                       // Роль узла в репликации, пустая - репликация отключена.
+	ReplicationPrimary string `env:"REPLICATION_PRIMARY"`                    // Базовый URL ведущего узла для реплики, например http://10.0.0.1:8080.
+	ReplicationAPIKey  string `env:"REPLICATION_API_KEY"`                    // API-ключ реплики с правом admin на ведущем узле.
+	ReplicationBacklog int    `env:"REPLICATION_BACKLOG" envDefault:"10000"` // Количество изменений, с которых реплика может продолжить поток без снимка.
+
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" envDefault:"5s"` // Время между переходом в состояние "не готов" и остановкой сервера.
+
+	MaxBodyBytes          int64 `env:"MAX_BODY_BYTES" envDefault:"1048576"`        // Максимальный размер тела запроса в байтах, 0 - без ограничения.
+	MaxKeyLength          int   `env:"MAX_KEY_LENGTH" envDefault:"250"`            // Максимальная длина ключа в байтах, 0 - без ограничения.
+	MaxValueSize          int   `env:"MAX_VALUE_SIZE" envDefault:"524288"`         // Максимальный размер значения в байтах, 0 - без ограничения.
+	DisallowUnknownFields bool  `env:"DISALLOW_UNKNOWN_FIELDS" envDefault:"false"` // Отклонять тела запросов с неизвестными полями.
+
+	CompressionEnabled bool `env:"COMPRESSION_ENABLED" envDefault:"true"`  // Сжимать ответы gzip и deflate по заголовку Accept-Encoding.
+	CompressionMinSize int  `env:"COMPRESSION_MIN_SIZE" envDefault:"1024"` // Минимальный размер ответа в байтах, начиная с которого он сжимается.
+	CompressionLevel   int  `env:"COMPRESSION_LEVEL" envDefault:"0"`       // Уровень сжатия от 1 до 9, 0 - уровень по умолчанию.
+
+	UnixSocketMode string `env:"UNIX_SOCKET_MODE" envDefault:"0660"` // Права файла unix-сокета в восьмеричной записи.
+	H2C            bool   `env:"H2C_ENABLED" envDefault:"false"`     // Принимать HTTP/2 без TLS (h2c).
+
+	GRPCAddress                  string `env:"GRPC_HOST_PORT"`                                    // Адрес gRPC-сервера, пустой - сервер отключен.
+	RESPAddress                  string `env:"RESP_HOST_PORT"`                                    // Адрес сервера протокола Redis (RESP), пустой - сервер отключен.
+	MemcacheAddress              string `env:"MEMCACHE_HOST_PORT"`                                // Адрес сервера текстового протокола memcached, пустой - сервер отключен.
+	MemcacheAllowUnauthenticated bool   `env:"MEMCACHE_ALLOW_UNAUTHENTICATED" envDefault:"false"` // Запускать сервер memcached без аутентификации при включенной аутентификации HTTP API.
+
+	DocsAssetsURL string `env:"DOCS_ASSETS_URL" envDefault:"https://unpkg.com/swagger-ui-dist@5.17.14"` // Адрес каталога с ресурсами Swagger UI для /docs.
+
+	AdminAddress         string `env:"ADMIN_HOST_PORT"`                       // Адрес диагностического сервера (pprof, expvar), пустой - сервер отключен.
+	MutexProfileFraction int    `env:"MUTEX_PROFILE_FRACTION" envDefault:"0"` // Доля событий конкуренции за мьютексы для профиля mutex, 0 - отключено.
+	BlockProfileRate     int    `env:"BLOCK_PROFILE_RATE" envDefault:"0"`     // Частота выборки блокировок в наносекундах для профиля block, 0 - отключено.
+
+	// Аутентификация включается, если задан хотя бы один API-ключ или ключ проверки JWT.
+	APIKeys               string   `env:"API_KEYS"`                                                         // API-ключи в формате "имя:ключ,имя:ключ".
+	APIKeysFile           string   `env:"API_KEYS_FILE"`                                                    // Файл с API-ключами, по одному "имя:ключ" в строке.
+	JWTHS256Secret        string   `env:"JWT_HS256_SECRET"`                                                 // Секрет для проверки JWT с алгоритмом HS256.
+	JWTRS256PublicKeyFile string   `env:"JWT_RS256_PUBLIC_KEY_FILE"`                                        // PEM-файл открытого ключа для проверки JWT с алгоритмом RS256.
+	JWTIssuer             string   `env:"JWT_ISSUER"`                                                       // Ожидаемый claim iss, пустой - не проверяется.
+	JWTAudience           string   `env:"JWT_AUDIENCE"`                                                     // Ожидаемый claim aud, пустой - не проверяется.
+	AuthPublicPaths       []string `env:"AUTH_PUBLIC_PATHS" envDefault:"/healthz,/readyz" envSeparator:","` // Пути, доступные без аутентификации.
+	AuthScopes            string   `env:"AUTH_SCOPES"`                                                      // Права субъектов в формате "имя:право,имя:право" (read, write, admin).
+	AuthDefaultScopes     []string `env:"AUTH_DEFAULT_SCOPES" envDefault:"read" envSeparator:","`           // Права субъектов, для которых права не заданы.
+
+	// Ограничение нагрузки от клиентов.
+	RateLimit             float64 `env:"RATE_LIMIT" envDefault:"0"`                  // Запросов в секунду на клиента (API-ключ или IP), 0 - без ограничения.
+	RateLimitBurst        int     `env:"RATE_LIMIT_BURST" envDefault:"0"`            // Максимальное количество запросов клиента подряд, 0 - равно RATE_LIMIT.
+	RateLimitRoutes       string  `env:"RATE_LIMIT_ROUTES"`                          // Бюджеты маршрутов в формате "[метод] маршрут=rate:burst,...".
+	RateLimitAuthFailures string  `env:"RATE_LIMIT_AUTH_FAILURES" envDefault:"1:10"` // Бюджет неудачных попыток аутентификации с одного IP в формате "rate[:burst]", 0 - без ограничения.
+	MaxInFlightRequests   int     `env:"MAX_IN_FLIGHT_REQUESTS" envDefault:"0"`      // Максимальное число одновременно обрабатываемых запросов, 0 - без ограничения.
+
+	// TLS включается, если заданы файлы сертификата и ключа сервера.
+	TLSCertFile       string        `env:"TLS_CERT_FILE"`                        // PEM-файл сертификата сервера.
+	TLSKeyFile        string        `env:"TLS_KEY_FILE"`                         // PEM-файл закрытого ключа сервера.
+	TLSClientCAFile   string        `env:"TLS_CLIENT_CA_FILE"`                   // PEM-файл CA клиентских сертификатов, пустой - mTLS отключен.
+	TLSClientAuth     string        `env:"TLS_CLIENT_AUTH" envDefault:"require"` // Проверка клиентских сертификатов: require или verify_if_given.
+	TLSMinVersion     string        `env:"TLS_MIN_VERSION" envDefault:"1.2"`     // Минимальная версия TLS.
+	TLSReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" envDefault:"10s"` // Период проверки изменения файлов сертификата, 0 - перезагрузка отключена.
 }
 
 // MustLoad загружает конфигурацию приложения.
@@ -54,12 +125,83 @@ func MustLoad() *Config {
 		log.Fatalf("cannot parse config: %s", err)
 	}
 
-	flag.StringVar(&cfg.Port, "server-host-port", cfg.Port, "Address to run the server (e.g., localhost:8080)")
+	flag.StringVar(&cfg.Port, "server-host-port", cfg.Port, "Address to run the server (e.g., localhost:8080 or unix:///run/lru-cache.sock)")
+	flag.StringVar(&cfg.UnixSocketMode, "unix-socket-mode", cfg.UnixSocketMode, "Octal file mode of the unix socket")
+	flag.BoolVar(&cfg.H2C, "h2c", cfg.H2C, "Accept HTTP/2 without TLS (h2c)")
+	flag.StringVar(&cfg.GRPCAddress, "grpc-host-port", cfg.GRPCAddress, "Address to run the gRPC server (e.g., localhost:9090), empty to disable")
+	flag.StringVar(&cfg.RESPAddress, "resp-host-port", cfg.RESPAddress, "Address to run the Redis protocol (RESP) server (e.g., localhost:6379), empty to disable")
+	flag.StringVar(&cfg.MemcacheAddress, "memcache-host-port", cfg.MemcacheAddress, "Address to run the memcached text protocol server (e.g., localhost:11211), empty to disable")
+	flag.BoolVar(&cfg.MemcacheAllowUnauthenticated, "memcache-allow-unauthenticated", cfg.MemcacheAllowUnauthenticated, "Run the memcached server without authentication even when API authentication is enabled")
 	flag.IntVar(&cfg.CacheSize, "cache-size", cfg.CacheSize, "Maximum cache size")
 	flag.DurationVar(&cfg.DefaultCacheTTL, "default-cache-ttl", cfg.DefaultCacheTTL, "Default TTL for cache entries ms,s,m,...")
+	flag.StringVar(&cfg.CachePolicy, "cache-policy", cfg.CachePolicy, "Eviction policy of the default cache: lru or fifo")
+	flag.StringVar(&cfg.Caches, "caches", cfg.Caches, "Named caches as name=capacity:ttl[:policy],... (e.g., sessions=1000:30m,thumbs=500:1h:fifo)")
+	flag.StringVar(&cfg.Tenants, "tenants", cfg.Tenants, "Tenant quotas as name=entries[:bytes[:ttl]],... (e.g., team-a=10000:67108864:5m,team-b=500)")
+	flag.StringVar(&cfg.ClusterSelf, "cluster-self", cfg.ClusterSelf, "Base URL of this node (e.g., http://10.0.0.1:8080), enables cluster mode")
+	flag.Func("cluster-peers", "Comma-separated base URLs of all cluster nodes including this one", func(s string) error {
+		cfg.ClusterPeers = splitList(s)
+		return nil
+	})
+	flag.IntVar(&cfg.ClusterVirtualNodes, "cluster-virtual-nodes", cfg.ClusterVirtualNodes, "Number of virtual nodes per peer on the hash ring")
+	flag.DurationVar(&cfg.ClusterForwardTimeout, "cluster-forward-timeout", cfg.ClusterForwardTimeout, "Time to wait for the key owner to respond to a forwarded request")
+	flag.StringVar(&cfg.ClusterSecret, "cluster-secret", cfg.ClusterSecret, "Secret shared by cluster nodes to sign forwarded requests")
+	flag.StringVar(&cfg.ReplicationRole, "replication-role", cfg.ReplicationRole, "Replication role of this node: primary or replica, empty to disable")
+	flag.StringVar(&cfg.ReplicationPrimary, "replication-primary", cfg.ReplicationPrimary, "Base URL of the primary for a replica (e.g., http://10.0.0.1:8080)")
+	flag.StringVar(&cfg.ReplicationAPIKey, "replication-api-key", cfg.ReplicationAPIKey, "API key with the admin scope that the replica presents to the primary")
+	flag.IntVar(&cfg.ReplicationBacklog, "replication-backlog", cfg.ReplicationBacklog, "Number of recent changes the primary keeps for replicas to resume without a full sync")
+	flag.StringVar(&cfg.TenantHeader, "tenant-header", cfg.TenantHeader, "Request header with the tenant name (e.g., X-Tenant-ID), empty to identify tenants by API key only")
+	flag.Int64Var(&cfg.MaxBodyBytes, "max-body-bytes", cfg.MaxBodyBytes, "Maximum request body size in bytes, 0 to disable")
+	flag.IntVar(&cfg.MaxKeyLength, "max-key-length", cfg.MaxKeyLength, "Maximum key length in bytes, 0 to disable")
+	flag.IntVar(&cfg.MaxValueSize, "max-value-size", cfg.MaxValueSize, "Maximum value size in bytes, 0 to disable")
+	flag.BoolVar(&cfg.DisallowUnknownFields, "disallow-unknown-fields", cfg.DisallowUnknownFields, "Reject request bodies with unknown fields")
+	flag.BoolVar(&cfg.CompressionEnabled, "compression", cfg.CompressionEnabled, "Compress responses with gzip or deflate")
+	flag.IntVar(&cfg.CompressionMinSize, "compression-min-size", cfg.CompressionMinSize, "Minimum response size in bytes to compress")
+	flag.IntVar(&cfg.CompressionLevel, "compression-level", cfg.CompressionLevel, "Compression level from 1 to 9, 0 for the default level")
+	flag.StringVar(&cfg.DocsAssetsURL, "docs-assets-url", cfg.DocsAssetsURL, "Base URL of the Swagger UI assets (swagger-ui.css, swagger-ui-bundle.js) for /docs")
 	flag.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Log level (e.g., DEBUG, INFO, WARN, ERROR)")
+	flag.StringVar(&cfg.AdminAddress, "admin-host-port", cfg.AdminAddress, "Address to run the admin server with pprof and expvar (e.g., localhost:6060), empty to disable")
+	flag.IntVar(&cfg.MutexProfileFraction, "mutex-profile-fraction", cfg.MutexProfileFraction, "Report 1/n of mutex contention events, 0 to disable")
+	flag.IntVar(&cfg.BlockProfileRate, "block-profile-rate", cfg.BlockProfileRate, "Sample one blocking event per n nanoseconds blocked, 0 to disable")
+	flag.StringVar(&cfg.APIKeys, "api-keys", cfg.APIKeys, "Static API keys as name:key,name:key")
+	flag.StringVar(&cfg.APIKeysFile, "api-keys-file", cfg.APIKeysFile, "File with static API keys, one name:key per line")
+	flag.StringVar(&cfg.JWTHS256Secret, "jwt-hs256-secret", cfg.JWTHS256Secret, "Secret to verify HS256 JWT bearer tokens")
+	flag.StringVar(&cfg.JWTRS256PublicKeyFile, "jwt-rs256-public-key-file", cfg.JWTRS256PublicKeyFile, "PEM file with the public key to verify RS256 JWT bearer tokens")
+	flag.StringVar(&cfg.JWTIssuer, "jwt-issuer", cfg.JWTIssuer, "Expected JWT iss claim, empty to skip the check")
+	flag.StringVar(&cfg.JWTAudience, "jwt-audience", cfg.JWTAudience, "Expected JWT aud claim, empty to skip the check")
+	flag.Func("auth-public-paths", "Comma-separated paths available without authentication (default \"/healthz,/readyz\")", func(s string) error {
+		cfg.AuthPublicPaths = splitList(s)
+		return nil
+	})
+	flag.StringVar(&cfg.AuthScopes, "auth-scopes", cfg.AuthScopes, "Principal scopes as name:scope,name:scope (read, write, admin)")
+	flag.Func("auth-default-scopes", "Comma-separated scopes of principals without configured scopes (default \"read\")", func(s string) error {
+		cfg.AuthDefaultScopes = splitList(s)
+		return nil
+	})
+	flag.Float64Var(&cfg.RateLimit, "rate-limit", cfg.RateLimit, "Requests per second per client (API key or IP), 0 to disable")
+	flag.IntVar(&cfg.RateLimitBurst, "rate-limit-burst", cfg.RateLimitBurst, "Maximum burst of requests per client, 0 to use the rate limit")
+	flag.StringVar(&cfg.RateLimitRoutes, "rate-limit-routes", cfg.RateLimitRoutes, "Per-route budgets as [METHOD ]route=rate:burst,... (e.g., DELETE /api/lru=1:1)")
+	flag.StringVar(&cfg.RateLimitAuthFailures, "rate-limit-auth-failures", cfg.RateLimitAuthFailures, "Failed authentication attempts per second per IP as rate[:burst], 0 to disable")
+	flag.IntVar(&cfg.MaxInFlightRequests, "max-in-flight-requests", cfg.MaxInFlightRequests, "Maximum number of requests processed concurrently, 0 to disable")
+	flag.StringVar(&cfg.TLSCertFile, "tls-cert-file", cfg.TLSCertFile, "PEM file with the server certificate, enables TLS")
+	flag.StringVar(&cfg.TLSKeyFile, "tls-key-file", cfg.TLSKeyFile, "PEM file with the server private key")
+	flag.StringVar(&cfg.TLSClientCAFile, "tls-client-ca-file", cfg.TLSClientCAFile, "PEM file with CA certificates to verify clients, enables mutual TLS")
+	flag.StringVar(&cfg.TLSClientAuth, "tls-client-auth", cfg.TLSClientAuth, "Client certificate policy: require or verify_if_given")
+	flag.StringVar(&cfg.TLSMinVersion, "tls-min-version", cfg.TLSMinVersion, "Minimum TLS version (1.0, 1.1, 1.2, 1.3)")
+	flag.DurationVar(&cfg.TLSReloadInterval, "tls-reload-interval", cfg.TLSReloadInterval, "Interval to check the certificate files for changes, 0 to disable reloading")
+	flag.DurationVar(&cfg.ShutdownDrainDelay, "shutdown-drain-delay", cfg.ShutdownDrainDelay, "Time to report not ready before shutting down the server")
 
 	flag.Parse()
 
 	return &cfg
 }
+
+// splitList разбирает список значений, разделенных запятыми, пропуская пустые значения.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
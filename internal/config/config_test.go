@@ -20,6 +20,15 @@ func TestMustLoad(t *testing.T) {
 		assert.Equal(t, 10, cfg.CacheSize)
 		assert.Equal(t, "WARN", cfg.LogLevel)
 		assert.Equal(t, time.Minute, cfg.DefaultCacheTTL)
+		assert.Equal(t, "lru", cfg.CachePolicy)
+		assert.Empty(t, cfg.Caches)
+		assert.Empty(t, cfg.Tenants)
+		assert.Empty(t, cfg.TenantHeader)
+		assert.Empty(t, cfg.ClusterSelf)
+		assert.Equal(t, 128, cfg.ClusterVirtualNodes)
+		assert.Equal(t, 5*time.Second, cfg.ClusterForwardTimeout)
+		assert.Equal(t, 5*time.Second, cfg.ShutdownDrainDelay)
+		assert.Equal(t, []string{"read"}, cfg.AuthDefaultScopes)
 	})
 
 }
@@ -0,0 +1,124 @@
+package grpcserver
+
+import (
+	"context"
+	cachev1 "github.com/instinctG/lru-cache/api/cache/v1"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// methodScopes задает права, которые требуются для вызова методов сервиса кэша, как в HTTP API.
+// Остальные методы, кроме publicMethods (например, reflection), доступны любому аутентифицированному клиенту.
+var methodScopes = map[string]string{
+	cachev1.CacheService_Get_FullMethodName:      mw_auth.ScopeRead,
+	cachev1.CacheService_GetMany_FullMethodName:  mw_auth.ScopeRead,
+	cachev1.CacheService_Scan_FullMethodName:     mw_auth.ScopeRead,
+	cachev1.CacheService_Watch_FullMethodName:    mw_auth.ScopeRead,
+	cachev1.CacheService_Put_FullMethodName:      mw_auth.ScopeWrite,
+	cachev1.CacheService_PutMany_FullMethodName:  mw_auth.ScopeWrite,
+	cachev1.CacheService_Evict_FullMethodName:    mw_auth.ScopeWrite,
+	cachev1.CacheService_EvictAll_FullMethodName: mw_auth.ScopeAdmin,
+}
+
+// publicMethods доступны без аутентификации, как /health в HTTP API.
+var publicMethods = map[string]bool{
+	healthpb.Health_Check_FullMethodName: true,
+	healthpb.Health_List_FullMethodName:  true,
+	healthpb.Health_Watch_FullMethodName: true,
+}
+
+// WithAuth включает аутентификацию вызовов теми же способами, что и в HTTP API.
+// Учетные данные берутся из метаданных x-api-key и authorization или из сертификата клиента TLS.
+// Методы сервиса кэша требуют прав read, write или admin (EvictAll), проверка состояния доступна без аутентификации.
+// Если не задан ни один способ аутентификации, вызовы не проверяются.
+func WithAuth(cfg mw_auth.Config) Option {
+	return func(s *Service) {
+		s.auth = cfg
+	}
+}
+
+// authUnaryInterceptor аутентифицирует вызов и проверяет права субъекта на метод.
+func authUnaryInterceptor(log *slog.Logger, cfg mw_auth.Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authorize(ctx, log, cfg, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authStreamInterceptor аутентифицирует потоковый вызов и проверяет права субъекта на метод.
+func authStreamInterceptor(log *slog.Logger, cfg mw_auth.Config) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), log, cfg, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authorize возвращает контекст с субъектом вызова или ошибку Unauthenticated или PermissionDenied.
+func authorize(ctx context.Context, log *slog.Logger, cfg mw_auth.Config, method string) (context.Context, error) {
+	if publicMethods[method] {
+		return ctx, nil
+	}
+
+	principal, err := cfg.Authenticate(credentialsRequest(ctx))
+	if err != nil {
+		log.Debug("authentication failed", sl.Err(err), slog.String("method", method))
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if scope, ok := methodScopes[method]; ok && !principal.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "%q scope is required", scope)
+	}
+
+	return mw_auth.NewContext(ctx, principal), nil
+}
+
+// credentialsRequest переносит учетные данные вызова в запрос, который понимают способы аутентификации mw_auth.
+func credentialsRequest(ctx context.Context) *http.Request {
+	r := &http.Request{Header: make(http.Header)}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(strings.ToLower(mw_auth.APIKeyHeader)); len(v) > 0 {
+			r.Header.Set(mw_auth.APIKeyHeader, v[0])
+		}
+		if v := md.Get("authorization"); len(v) > 0 {
+			r.Header.Set("Authorization", v[0])
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+		if p.Addr != nil {
+			r.RemoteAddr = p.Addr.String()
+		}
+	}
+
+	return r.WithContext(ctx)
+}
+
+// authStream подменяет контекст потока контекстом с субъектом вызова.
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,99 @@
+// Package grpcserver реализует gRPC API кэша (lrucache.v1.CacheService), повторяющий HTTP API.
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	cachev1 "github.com/instinctG/lru-cache/api/cache/v1"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+// NewServer создает gRPC-сервер с сервисом кэша, стандартной проверкой состояния grpc.health.v1
+// и reflection для grpcurl. Если tlsCfg не nil, сервер принимает только TLS-соединения.
+// Максимальный размер сообщения ограничивается limits.MaxBodyBytes.
+// Если задан WithAuth, вызовы проходят аутентификацию и проверку прав.
+func NewServer(svc *Service, tlsCfg *tls.Config) *grpc.Server {
+	unary := []grpc.UnaryServerInterceptor{unaryInterceptor(svc.Log)}
+	stream := []grpc.StreamServerInterceptor{streamInterceptor(svc.Log)}
+	if svc.auth.Enabled() {
+		unary = append(unary, authUnaryInterceptor(svc.Log, svc.auth))
+		stream = append(stream, authStreamInterceptor(svc.Log, svc.auth))
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+	if tlsCfg != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+	if svc.limits.MaxBodyBytes > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(int(svc.limits.MaxBodyBytes)))
+	}
+
+	srv := grpc.NewServer(opts...)
+	cachev1.RegisterCacheServiceServer(srv, svc)
+
+	hs := health.NewServer()
+	hs.SetServingStatus(cachev1.CacheService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+
+	reflection.Register(srv)
+
+	return srv
+}
+
+// unaryInterceptor логирует вызовы и преобразует панику обработчика в ошибку Internal.
+func unaryInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		t1 := time.Now()
+		defer func() {
+			if p := recover(); p != nil {
+				log.Error("panic in grpc handler", slog.Any("panic", p), slog.String("stack", string(debug.Stack())))
+				err = status.Error(codes.Internal, "internal error")
+			}
+			logCall(log, info.FullMethod, t1, err)
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// streamInterceptor логирует потоковые вызовы и преобразует панику обработчика в ошибку Internal.
+func streamInterceptor(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		t1 := time.Now()
+		defer func() {
+			if p := recover(); p != nil {
+				log.Error("panic in grpc handler", slog.Any("panic", p), slog.String("stack", string(debug.Stack())))
+				err = status.Error(codes.Internal, "internal error")
+			}
+			logCall(log, info.FullMethod, t1, err)
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+func logCall(log *slog.Logger, method string, start time.Time, err error) {
+	attrs := []any{
+		slog.String("method", method),
+		slog.String("code", status.Code(err).String()),
+		slog.String("duration", time.Since(start).String()),
+	}
+	if err != nil && status.Code(err) == codes.Internal {
+		log.Error("grpc call failed", append(attrs, sl.Err(err))...)
+		return
+	}
+	log.Debug("grpc call completed", attrs...)
+}
@@ -0,0 +1,330 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	cachev1 "github.com/instinctG/lru-cache/api/cache/v1"
+	"github.com/instinctG/lru-cache/internal/http-server/handler"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"github.com/instinctG/lru-cache/internal/replication"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log/slog"
+	"strings"
+)
+
+// watchBuffer - размер буфера событий подписчика Watch.
+const watchBuffer = 256
+
+// Watcher реализуется кэшем, который сообщает об изменениях элементов (*lru.Cache).
+// Если кэш его не реализует, Watch возвращает Unimplemented.
+type Watcher interface {
+	// Subscribe подписывает на изменения элементов кэша.
+	Subscribe(buffer int) (events <-chan lru.Event, cancel func())
+}
+
+// Service реализует cachev1.CacheServiceServer поверх handler.ILRUCache.
+type Service struct {
+	cachev1.UnimplementedCacheServiceServer
+
+	LRU handler.ILRUCache // Кэш, над которым выполняются вызовы.
+	Log *slog.Logger      // Логгер для записи событий сервиса.
+
+	limits handler.Limits // Ограничения на размер ключей и значений.
+	auth   mw_auth.Config // Параметры аутентификации вызовов (см. WithAuth).
+}
+
+// Option задает дополнительные параметры Service.
+type Option func(s *Service)
+
+// WithLimits задает ограничения на длину ключа, размер значения и размер сообщения.
+// По умолчанию используются handler.DefaultLimits, как в HTTP API.
+func WithLimits(l handler.Limits) Option {
+	return func(s *Service) {
+		s.limits = l
+	}
+}
+
+// NewService создает сервис gRPC API кэша.
+func NewService(cache handler.ILRUCache, log *slog.Logger, opts ...Option) *Service {
+	s := &Service{
+		LRU:    cache,
+		Log:    log.With(slog.String("component", "grpc")),
+		limits: handler.DefaultLimits,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Get возвращает элемент по ключу.
+func (s *Service) Get(ctx context.Context, req *cachev1.GetRequest) (*cachev1.GetResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	entry, err := s.get(ctx, req.GetKey())
+	if err != nil {
+		return nil, err
+	}
+	return &cachev1.GetResponse{Entry: entry}, nil
+}
+
+// Put добавляет или обновляет элемент.
+func (s *Service) Put(ctx context.Context, req *cachev1.PutRequest) (*cachev1.PutResponse, error) {
+	value, err := s.validatePut(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.LRU.Put(ctx, req.GetKey(), value, req.GetTtl().AsDuration()); err != nil {
+		return nil, s.internal("failed to put value", err)
+	}
+	return &cachev1.PutResponse{}, nil
+}
+
+// Evict удаляет элемент и возвращает его значение.
+func (s *Service) Evict(ctx context.Context, req *cachev1.EvictRequest) (*cachev1.EvictResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	value, err := s.LRU.Evict(ctx, req.GetKey())
+	if errors.Is(err, lru.ErrKeyNotFound) {
+		return nil, status.Errorf(codes.NotFound, "key %q not found", req.GetKey())
+	}
+	if err != nil {
+		return nil, s.internal("failed to evict value", err)
+	}
+	return &cachev1.EvictResponse{Value: toValue(value)}, nil
+}
+
+// EvictAll удаляет все элементы.
+func (s *Service) EvictAll(ctx context.Context, _ *cachev1.EvictAllRequest) (*cachev1.EvictAllResponse, error) {
+	if err := s.LRU.EvictAll(ctx); err != nil {
+		return nil, s.internal("failed to evict all values", err)
+	}
+	return &cachev1.EvictAllResponse{}, nil
+}
+
+// Scan передает элементы, ключи которых начинаются с prefix, в порядке от давно использованных к недавним.
+func (s *Service) Scan(req *cachev1.ScanRequest, stream cachev1.CacheService_ScanServer) error {
+	keys, values, err := s.LRU.GetAll(stream.Context())
+	if err != nil && !errors.Is(err, lru.ErrCacheIsEmpty) {
+		return s.internal("failed to scan values", err)
+	}
+
+	for i, key := range keys {
+		if !strings.HasPrefix(key, req.GetPrefix()) {
+			continue
+		}
+		if err = stream.Send(&cachev1.Entry{Key: key, Value: toValue(values[i])}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMany возвращает найденные элементы в порядке запроса и список отсутствующих ключей.
+func (s *Service) GetMany(ctx context.Context, req *cachev1.GetManyRequest) (*cachev1.GetManyResponse, error) {
+	resp := &cachev1.GetManyResponse{}
+	for _, key := range req.GetKeys() {
+		entry, err := s.get(ctx, key)
+		if status.Code(err) == codes.NotFound {
+			resp.MissingKeys = append(resp.MissingKeys, key)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		resp.Entries = append(resp.Entries, entry)
+	}
+	return resp, nil
+}
+
+// PutMany проверяет все элементы и только после этого записывает их.
+// Запись не атомарна: другие клиенты могут увидеть часть элементов до завершения вызова.
+func (s *Service) PutMany(ctx context.Context, req *cachev1.PutManyRequest) (*cachev1.PutManyResponse, error) {
+	values := make([]interface{}, len(req.GetEntries()))
+	for i, entry := range req.GetEntries() {
+		value, err := s.validatePut(entry)
+		if err != nil {
+			st := status.Convert(err)
+			return nil, status.Errorf(st.Code(), "entries[%d]: %s", i, st.Message())
+		}
+		values[i] = value
+	}
+
+	for i, entry := range req.GetEntries() {
+		if err := s.LRU.Put(ctx, entry.GetKey(), values[i], entry.GetTtl().AsDuration()); err != nil {
+			return nil, s.internal("failed to put value", err)
+		}
+	}
+	return &cachev1.PutManyResponse{}, nil
+}
+
+// Watch передает изменения элементов, ключи которых начинаются с prefix.
+// Заголовки ответа отправляются сразу после подписки на изменения.
+// Если клиент не успевает читать события, вызов завершается с ResourceExhausted,
+// и клиент должен вызвать Watch заново и при необходимости перечитать данные через Scan.
+func (s *Service) Watch(req *cachev1.WatchRequest, stream cachev1.CacheService_WatchServer) error {
+	watcher, ok := s.LRU.(Watcher)
+	if !ok {
+		return status.Error(codes.Unimplemented, "cache does not support watching")
+	}
+
+	events, cancel := watcher.Subscribe(watchBuffer)
+	defer cancel()
+
+	// Заголовки отправляются после подписки: получив их, клиент может быть уверен,
+	// что изменения, сделанные после этого, попадут в поток.
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher is too slow, events were dropped")
+			}
+			if event.Type != lru.EventFlush && !strings.HasPrefix(event.Key, req.GetPrefix()) {
+				continue
+			}
+			if err := stream.Send(toWatchEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// get возвращает элемент по ключу или ошибку NotFound.
+func (s *Service) get(ctx context.Context, key string) (*cachev1.Entry, error) {
+	value, expiresAt, err := s.LRU.Get(ctx, key)
+	if errors.Is(err, lru.ErrKeyNotFound) {
+		return nil, status.Errorf(codes.NotFound, "key %q not found", key)
+	}
+	if err != nil {
+		return nil, s.internal("failed to get value", err)
+	}
+
+	return &cachev1.Entry{Key: key, Value: toValue(value), ExpiresAt: timestamppb.New(expiresAt)}, nil
+}
+
+// validatePut проверяет запрос на запись и возвращает значение для записи в кэш.
+func (s *Service) validatePut(req *cachev1.PutRequest) (interface{}, error) {
+	key := req.GetKey()
+	if key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	if s.limits.MaxKeyLength > 0 && len(key) > s.limits.MaxKeyLength {
+		return nil, status.Errorf(codes.InvalidArgument, "key must be at most %d bytes", s.limits.MaxKeyLength)
+	}
+	if req.GetTtl() != nil {
+		if err := req.GetTtl().CheckValid(); err != nil || req.GetTtl().AsDuration() < 0 {
+			return nil, status.Error(codes.InvalidArgument, "ttl must be a non-negative duration")
+		}
+	}
+
+	value, ok := fromValue(req.GetValue())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "value is required")
+	}
+	if s.limits.MaxValueSize > 0 {
+		if size := valueSize(value); size > s.limits.MaxValueSize {
+			return nil, status.Errorf(codes.ResourceExhausted, "value is %d bytes, the limit is %d bytes", size, s.limits.MaxValueSize)
+		}
+	}
+
+	return value, nil
+}
+
+// internal логирует ошибку кэша и возвращает ошибку Internal без подробностей.
+// Запись в кэш реплики возвращает FailedPrecondition: изменять кэш нужно на ведущем узле.
+func (s *Service) internal(msg string, err error) error {
+	if errors.Is(err, replication.ErrReadOnly) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	s.Log.Error(msg, sl.Err(err))
+	return status.Error(codes.Internal, msg)
+}
+
+// fromValue возвращает значение кэша из сообщения Value. Байты хранятся как []byte.
+func fromValue(v *cachev1.Value) (interface{}, bool) {
+	switch kind := v.GetKind().(type) {
+	case *cachev1.Value_StringValue:
+		return kind.StringValue, true
+	case *cachev1.Value_NumberValue:
+		return kind.NumberValue, true
+	case *cachev1.Value_BoolValue:
+		return kind.BoolValue, true
+	case *cachev1.Value_BytesValue:
+		return kind.BytesValue, true
+	default:
+		return nil, false
+	}
+}
+
+// toValue преобразует значение кэша в сообщение Value. Значения других типов,
+// например записанные по протоколу memcached с флагами, передаются строкой в виде JSON.
+func toValue(value interface{}) *cachev1.Value {
+	switch v := value.(type) {
+	case string:
+		return &cachev1.Value{Kind: &cachev1.Value_StringValue{StringValue: v}}
+	case float64:
+		return &cachev1.Value{Kind: &cachev1.Value_NumberValue{NumberValue: v}}
+	case bool:
+		return &cachev1.Value{Kind: &cachev1.Value_BoolValue{BoolValue: v}}
+	case []byte:
+		return &cachev1.Value{Kind: &cachev1.Value_BytesValue{BytesValue: v}}
+	case nil:
+		return nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			data = []byte(fmt.Sprint(v))
+		}
+		return &cachev1.Value{Kind: &cachev1.Value_StringValue{StringValue: string(data)}}
+	}
+}
+
+// valueSize возвращает размер значения: длину строки или байтов и 8 байт для чисел.
+func valueSize(value interface{}) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	default:
+		return 8
+	}
+}
+
+// toWatchEvent преобразует событие кэша в сообщение WatchEvent.
+func toWatchEvent(event lru.Event) *cachev1.WatchEvent {
+	out := &cachev1.WatchEvent{Key: event.Key}
+	switch event.Type {
+	case lru.EventPut:
+		out.Type = cachev1.WatchEvent_TYPE_PUT
+		out.Value = toValue(event.Value)
+		out.ExpiresAt = timestamppb.New(event.ExpiresAt)
+	case lru.EventEvict:
+		out.Type = cachev1.WatchEvent_TYPE_EVICT
+		out.Reason = event.Reason.String()
+	case lru.EventFlush:
+		out.Type = cachev1.WatchEvent_TYPE_FLUSH
+	}
+	return out
+}
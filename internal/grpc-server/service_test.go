@@ -0,0 +1,266 @@
+package grpcserver_test
+
+import (
+	"context"
+	cachev1 "github.com/instinctG/lru-cache/api/cache/v1"
+	grpcserver "github.com/instinctG/lru-cache/internal/grpc-server"
+	"github.com/instinctG/lru-cache/internal/http-server/handler"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+)
+
+// newClient запускает gRPC-сервер в памяти и возвращает клиента и кэш сервера.
+func newClient(t *testing.T, opts ...grpcserver.Option) (cachev1.CacheServiceClient, *lru.Cache, *grpc.ClientConn) {
+	t.Helper()
+
+	cache := lru.NewLRUCache(10, time.Minute)
+	svc := grpcserver.NewService(cache, slog.New(slog.NewTextHandler(io.Discard, nil)), opts...)
+	srv := grpcserver.NewServer(svc, nil)
+
+	ln := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return cachev1.NewCacheServiceClient(conn), cache, conn
+}
+
+func stringValue(s string) *cachev1.Value {
+	return &cachev1.Value{Kind: &cachev1.Value_StringValue{StringValue: s}}
+}
+
+func TestService_PutGetEvict(t *testing.T) {
+	client, cache, _ := newClient(t)
+	ctx := context.Background()
+
+	values := []*cachev1.Value{
+		stringValue("text"),
+		{Kind: &cachev1.Value_NumberValue{NumberValue: 4.5}},
+		{Kind: &cachev1.Value_BoolValue{BoolValue: true}},
+		{Kind: &cachev1.Value_BytesValue{BytesValue: []byte{0, 1, 2, 255}}},
+	}
+
+	for _, value := range values {
+		_, err := client.Put(ctx, &cachev1.PutRequest{Key: "key", Value: value, Ttl: durationpb.New(time.Hour)})
+		require.NoError(t, err)
+
+		resp, err := client.Get(ctx, &cachev1.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, "key", resp.GetEntry().GetKey())
+		assert.Equal(t, value.GetKind(), resp.GetEntry().GetValue().GetKind())
+		assert.WithinDuration(t, time.Now().Add(time.Hour), resp.GetEntry().GetExpiresAt().AsTime(), time.Second)
+	}
+
+	// Байты хранятся в кэше без преобразования.
+	stored, _, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2, 255}, stored)
+
+	evicted, err := client.Evict(ctx, &cachev1.EvictRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2, 255}, evicted.GetValue().GetBytesValue())
+
+	_, err = client.Get(ctx, &cachev1.GetRequest{Key: "key"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Evict(ctx, &cachev1.EvictRequest{Key: "key"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestService_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *cachev1.PutRequest
+		code codes.Code
+	}{
+		{"empty key", &cachev1.PutRequest{Value: stringValue("v")}, codes.InvalidArgument},
+		{"key too long", &cachev1.PutRequest{Key: "12345678901", Value: stringValue("v")}, codes.InvalidArgument},
+		{"missing value", &cachev1.PutRequest{Key: "key"}, codes.InvalidArgument},
+		{"negative ttl", &cachev1.PutRequest{Key: "key", Value: stringValue("v"), Ttl: durationpb.New(-time.Second)}, codes.InvalidArgument},
+		{"value too large", &cachev1.PutRequest{Key: "key", Value: stringValue("123456")}, codes.ResourceExhausted},
+		{"valid", &cachev1.PutRequest{Key: "key", Value: stringValue("12345")}, codes.OK},
+	}
+
+	client, _, _ := newClient(t, grpcserver.WithLimits(handler.Limits{MaxKeyLength: 10, MaxValueSize: 5}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Put(context.Background(), tt.req)
+			assert.Equal(t, tt.code, status.Code(err), err)
+		})
+	}
+
+	_, err := client.Get(context.Background(), &cachev1.GetRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestService_ManyAndScan(t *testing.T) {
+	client, _, _ := newClient(t)
+	ctx := context.Background()
+
+	_, err := client.PutMany(ctx, &cachev1.PutManyRequest{Entries: []*cachev1.PutRequest{
+		{Key: "user:1", Value: stringValue("a")},
+		{Key: "user:2", Value: stringValue("b")},
+		{Key: "order:1", Value: stringValue("c")},
+	}})
+	require.NoError(t, err)
+
+	// Если хотя бы один элемент не проходит проверку, не записывается ни один.
+	_, err = client.PutMany(ctx, &cachev1.PutManyRequest{Entries: []*cachev1.PutRequest{
+		{Key: "user:3", Value: stringValue("d")},
+		{Key: "", Value: stringValue("e")},
+	}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "entries[1]")
+
+	many, err := client.GetMany(ctx, &cachev1.GetManyRequest{Keys: []string{"user:1", "user:3", "order:1"}})
+	require.NoError(t, err)
+	require.Len(t, many.GetEntries(), 2)
+	assert.Equal(t, "user:1", many.GetEntries()[0].GetKey())
+	assert.Equal(t, "order:1", many.GetEntries()[1].GetKey())
+	assert.Equal(t, []string{"user:3"}, many.GetMissingKeys())
+
+	stream, err := client.Scan(ctx, &cachev1.ScanRequest{Prefix: "user:"})
+	require.NoError(t, err)
+	var keys []string
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		keys = append(keys, entry.GetKey())
+	}
+	assert.ElementsMatch(t, []string{"user:1", "user:2"}, keys)
+
+	_, err = client.EvictAll(ctx, &cachev1.EvictAllRequest{})
+	require.NoError(t, err)
+
+	stream, err = client.Scan(ctx, &cachev1.ScanRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestService_Watch(t *testing.T) {
+	client, cache, _ := newClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &cachev1.WatchRequest{Prefix: "user:"})
+	require.NoError(t, err)
+
+	// Заголовки приходят после подписки, поэтому последующие изменения попадут в поток.
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Put(context.Background(), "user:probe", "x", 0))
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, cachev1.WatchEvent_TYPE_PUT, event.GetType())
+	assert.Equal(t, "user:probe", event.GetKey())
+	assert.Equal(t, "x", event.GetValue().GetStringValue())
+
+	_, err = cache.Evict(context.Background(), "user:probe")
+	require.NoError(t, err)
+	// Изменения ключей с другим префиксом не передаются.
+	require.NoError(t, cache.Put(context.Background(), "order:1", "x", 0))
+	_, err = client.Put(ctx, &cachev1.PutRequest{Key: "user:1", Value: stringValue("v")})
+	require.NoError(t, err)
+	require.NoError(t, cache.EvictAll(context.Background()))
+
+	want := []struct {
+		typ    cachev1.WatchEvent_Type
+		key    string
+		reason string
+	}{
+		{cachev1.WatchEvent_TYPE_EVICT, "user:probe", "explicit"},
+		{cachev1.WatchEvent_TYPE_PUT, "user:1", ""},
+		{cachev1.WatchEvent_TYPE_FLUSH, "", ""},
+	}
+	for _, w := range want {
+		event, err = stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, w.typ, event.GetType())
+		assert.Equal(t, w.key, event.GetKey())
+		assert.Equal(t, w.reason, event.GetReason())
+	}
+}
+
+func TestServer_Health(t *testing.T) {
+	_, _, conn := newClient(t)
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(),
+		&healthpb.HealthCheckRequest{Service: cachev1.CacheService_ServiceDesc.ServiceName})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
+
+func TestServer_Auth(t *testing.T) {
+	client, _, conn := newClient(t, grpcserver.WithAuth(mw_auth.Config{
+		Authenticators: []mw_auth.Authenticator{
+			mw_auth.NewAPIKeyAuthenticator(map[string]string{"reader": "read-key", "writer": "write-key"}),
+		},
+		Scopes: map[string][]string{
+			"reader": {mw_auth.ScopeRead},
+			"writer": {mw_auth.ScopeRead, mw_auth.ScopeWrite},
+		},
+	}))
+	ctx := context.Background()
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, "x-api-key", key)
+	}
+
+	_, err := client.Get(ctx, &cachev1.GetRequest{Key: "k"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Get(withKey("wrong"), &cachev1.GetRequest{Key: "k"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Put(withKey("read-key"), &cachev1.PutRequest{Key: "k", Value: stringValue("v")})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Put(withKey("write-key"), &cachev1.PutRequest{Key: "k", Value: stringValue("v")})
+	require.NoError(t, err)
+
+	resp, err := client.Get(withKey("read-key"), &cachev1.GetRequest{Key: "k"})
+	require.NoError(t, err)
+	assert.Equal(t, "v", resp.GetEntry().GetValue().GetStringValue())
+
+	_, err = client.EvictAll(withKey("write-key"), &cachev1.EvictAllRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	stream, err := client.Scan(ctx, &cachev1.ScanRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	stream, err = client.Scan(withKey("read-key"), &cachev1.ScanRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+}
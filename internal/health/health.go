@@ -0,0 +1,109 @@
+// Package health отслеживает состояние компонентов сервиса для проверок живости и готовности.
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Status описывает состояние компонента.
+type Status string
+
+// Возможные состояния компонентов.
+const (
+	StatusUp       Status = "up"       // StatusUp - компонент работает.
+	StatusDown     Status = "down"     // StatusDown - компонент не работает.
+	StatusStarting Status = "starting" // StatusStarting - компонент запускается, например восстанавливает снимок.
+	StatusDisabled Status = "disabled" // StatusDisabled - компонент отключен в конфигурации.
+)
+
+// Component представляет состояние отдельного компонента сервиса.
+type Component struct {
+	Status  Status                 `json:"status"`            // Состояние компонента.
+	Details map[string]interface{} `json:"details,omitempty"` // Дополнительные сведения о компоненте.
+}
+
+// CheckFunc возвращает текущее состояние компонента.
+type CheckFunc func(ctx context.Context) Component
+
+// Report представляет сводное состояние сервиса.
+type Report struct {
+	Status     Status               `json:"status"`               // Сводное состояние: up, если сервис готов принимать запросы.
+	Draining   bool                 `json:"draining,omitempty"`   // Признак завершения работы сервиса.
+	Components map[string]Component `json:"components,omitempty"` // Состояние компонентов по именам.
+}
+
+// Health хранит проверки состояния компонентов и признак завершения работы сервиса.
+type Health struct {
+	mu       sync.RWMutex
+	checks   map[string]CheckFunc
+	draining atomic.Bool
+}
+
+// New создает Health без зарегистрированных компонентов.
+func New() *Health {
+	return &Health{checks: make(map[string]CheckFunc)}
+}
+
+// Register регистрирует проверку компонента с именем name. Повторная регистрация заменяет проверку.
+func (h *Health) Register(name string, check CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks[name] = check
+}
+
+// Set задает постоянное состояние компонента с именем name.
+// Используется компонентами, которые сами сообщают о смене состояния, например при восстановлении снимка.
+func (h *Health) Set(name string, component Component) {
+	h.Register(name, func(context.Context) Component { return component })
+}
+
+// SetDraining помечает сервис как завершающий работу. После этого сервис перестает быть готовым.
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Draining возвращает true, если сервис завершает работу.
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+// Readiness выполняет проверки компонентов и возвращает сводное состояние.
+// Сервис готов, если он не завершает работу и ни один компонент не находится в состоянии down или starting.
+func (h *Health) Readiness(ctx context.Context) (report Report, ready bool) {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	checks := make([]CheckFunc, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		checks = append(checks, h.checks[name])
+	}
+	h.mu.RUnlock()
+
+	ready = !h.Draining()
+	report = Report{
+		Draining:   h.Draining(),
+		Components: make(map[string]Component, len(names)),
+	}
+
+	for i, name := range names {
+		component := checks[i](ctx)
+		if component.Status == StatusDown || component.Status == StatusStarting {
+			ready = false
+		}
+		report.Components[name] = component
+	}
+
+	report.Status = StatusUp
+	if !ready {
+		report.Status = StatusDown
+	}
+
+	return report, ready
+}
@@ -0,0 +1,43 @@
+package health_test
+
+import (
+	"context"
+	"github.com/instinctG/lru-cache/internal/health"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHealth_Readiness(t *testing.T) {
+	ctx := context.Background()
+	h := health.New()
+
+	// Без компонентов сервис готов
+	report, ready := h.Readiness(ctx)
+	assert.True(t, ready)
+	assert.Equal(t, health.StatusUp, report.Status)
+
+	// Компонент в состоянии starting делает сервис неготовым
+	h.Set("persistence", health.Component{Status: health.StatusStarting})
+	h.Register("cache", func(context.Context) health.Component {
+		return health.Component{Status: health.StatusUp, Details: map[string]interface{}{"size": 1}}
+	})
+
+	report, ready = h.Readiness(ctx)
+	assert.False(t, ready)
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Equal(t, health.StatusStarting, report.Components["persistence"].Status)
+	assert.Equal(t, 1, report.Components["cache"].Details["size"])
+
+	// Отключенный компонент не влияет на готовность
+	h.Set("persistence", health.Component{Status: health.StatusDisabled})
+
+	_, ready = h.Readiness(ctx)
+	assert.True(t, ready)
+
+	// После начала завершения работы сервис не готов
+	h.SetDraining()
+
+	report, ready = h.Readiness(ctx)
+	assert.False(t, ready)
+	assert.True(t, report.Draining)
+}
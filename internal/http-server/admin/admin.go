@@ -0,0 +1,162 @@
+// Package admin предоставляет отдельный HTTP-сервер с диагностическими эндпоинтами:
+// профилированием pprof, переменными expvar и сведениями о среде выполнения Go.
+// Сервер предназначен для запуска на внутреннем адресе, недоступном извне.
+package admin
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// VarFunc возвращает значение переменной, которая выводится в /debug/vars вместе с переменными expvar.
+type VarFunc func() interface{}
+
+// NewServer создает HTTP-сервер с диагностическими эндпоинтами.
+// Параметры:
+//   - address: адрес, на котором будет запущен сервер (например, "localhost:6060").
+//   - vars: дополнительные переменные для /debug/vars, например статистика кэша.
+//
+// Возвращает: HTTP-сервер, который нужно запустить и остановить вместе с основным сервером.
+func NewServer(address string, vars map[string]VarFunc) *http.Server {
+	return &http.Server{
+		Addr:    address,
+		Handler: NewRouter(vars),
+	}
+}
+
+// NewRouter создает роутер с диагностическими эндпоинтами:
+//   - /debug/pprof/ - профили net/http/pprof;
+//   - /debug/vars - переменные expvar и дополнительные переменные vars в формате JSON;
+//   - /debug/runtime - сведения о горутинах, памяти и сборщике мусора.
+func NewRouter(vars map[string]VarFunc) http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.Recoverer)
+
+	r.Get("/debug/pprof/", pprof.Index)
+	r.Get("/debug/pprof/cmdline", pprof.Cmdline)
+	r.Get("/debug/pprof/profile", pprof.Profile)
+	r.Get("/debug/pprof/symbol", pprof.Symbol)
+	r.Post("/debug/pprof/symbol", pprof.Symbol)
+	r.Get("/debug/pprof/trace", pprof.Trace)
+	r.Get("/debug/pprof/{profile}", pprof.Index)
+
+	r.Get("/debug/vars", varsHandler(vars))
+	r.Get("/debug/runtime", runtimeHandler)
+
+	return r
+}
+
+// varsHandler выводит переменные expvar и дополнительные переменные в том же формате, что и expvar.Handler.
+// Дополнительные переменные не публикуются в глобальном реестре expvar, поэтому сервер можно создавать многократно.
+func varsHandler(vars map[string]VarFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		fmt.Fprintf(w, "{\n")
+		first := true
+		expvar.Do(func(kv expvar.KeyValue) {
+			if _, overridden := vars[kv.Key]; overridden {
+				return
+			}
+			if !first {
+				fmt.Fprintf(w, ",\n")
+			}
+			first = false
+			fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+		})
+		for name, fn := range vars {
+			b, err := json.Marshal(fn())
+			if err != nil {
+				b, _ = json.Marshal(err.Error())
+			}
+			if !first {
+				fmt.Fprintf(w, ",\n")
+			}
+			first = false
+			fmt.Fprintf(w, "%q: %s", name, b)
+		}
+		fmt.Fprintf(w, "\n}\n")
+	}
+}
+
+// RuntimeInfo содержит сведения о среде выполнения Go.
+type RuntimeInfo struct {
+	GoVersion  string     `json:"go_version"` // Версия Go.
+	NumCPU     int        `json:"num_cpu"`    // Количество логических процессоров.
+	GOMAXPROCS int        `json:"gomaxprocs"` // Значение GOMAXPROCS.
+	Goroutines int        `json:"goroutines"` // Количество горутин.
+	Memory     MemoryInfo `json:"memory"`     // Сведения о памяти.
+	GC         GCInfo     `json:"gc"`         // Сведения о сборщике мусора.
+}
+
+// MemoryInfo содержит сведения о памяти процесса в байтах.
+type MemoryInfo struct {
+	Alloc       uint64 `json:"alloc"`        // Объем памяти, занятой объектами в куче.
+	TotalAlloc  uint64 `json:"total_alloc"`  // Суммарный объем выделенной памяти.
+	Sys         uint64 `json:"sys"`          // Объем памяти, полученной от ОС.
+	HeapInuse   uint64 `json:"heap_inuse"`   // Объем используемых спанов кучи.
+	HeapObjects uint64 `json:"heap_objects"` // Количество объектов в куче.
+	StackInuse  uint64 `json:"stack_inuse"`  // Объем памяти стеков.
+	Mallocs     uint64 `json:"mallocs"`      // Количество выделений памяти.
+	Frees       uint64 `json:"frees"`        // Количество освобождений памяти.
+}
+
+// GCInfo содержит сведения о сборщике мусора.
+type GCInfo struct {
+	NumGC       uint32     `json:"num_gc"`       // Количество завершенных циклов сборки мусора.
+	NextGC      uint64     `json:"next_gc"`      // Целевой размер кучи для следующего цикла.
+	LastGC      *time.Time `json:"last_gc"`      // Время завершения последнего цикла.
+	PauseTotal  string     `json:"pause_total"`  // Суммарное время пауз.
+	LastPause   string     `json:"last_pause"`   // Время последней паузы.
+	CPUFraction float64    `json:"cpu_fraction"` // Доля процессорного времени, затраченного на сборку мусора.
+}
+
+// ReadRuntimeInfo собирает сведения о среде выполнения Go.
+func ReadRuntimeInfo() RuntimeInfo {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	info := RuntimeInfo{
+		GoVersion:  runtime.Version(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		Memory: MemoryInfo{
+			Alloc:       m.Alloc,
+			TotalAlloc:  m.TotalAlloc,
+			Sys:         m.Sys,
+			HeapInuse:   m.HeapInuse,
+			HeapObjects: m.HeapObjects,
+			StackInuse:  m.StackInuse,
+			Mallocs:     m.Mallocs,
+			Frees:       m.Frees,
+		},
+		GC: GCInfo{
+			NumGC:       m.NumGC,
+			NextGC:      m.NextGC,
+			PauseTotal:  time.Duration(m.PauseTotalNs).String(),
+			CPUFraction: m.GCCPUFraction,
+		},
+	}
+
+	if m.NumGC > 0 {
+		lastGC := time.Unix(0, int64(m.LastGC)).UTC()
+		info.GC.LastGC = &lastGC
+		info.GC.LastPause = time.Duration(m.PauseNs[(m.NumGC+255)%256]).String()
+	}
+
+	return info
+}
+
+func runtimeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(ReadRuntimeInfo())
+}
@@ -0,0 +1,52 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"github.com/instinctG/lru-cache/internal/http-server/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	router := admin.NewRouter(map[string]admin.VarFunc{
+		"lru_cache": func() interface{} { return map[string]int{"size": 3} },
+	})
+
+	t.Run("Vars", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var vars map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &vars))
+		assert.Contains(t, vars, "memstats")
+		assert.Contains(t, vars, "cmdline")
+		assert.JSONEq(t, `{"size":3}`, string(vars["lru_cache"]))
+	})
+
+	t.Run("Runtime", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var info admin.RuntimeInfo
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&info))
+		assert.Positive(t, info.Goroutines)
+		assert.Positive(t, info.Memory.Sys)
+		assert.NotEmpty(t, info.GoVersion)
+	})
+
+	t.Run("Pprof", func(t *testing.T) {
+		for _, target := range []string{"/debug/pprof/", "/debug/pprof/heap?debug=1", "/debug/pprof/mutex?debug=1", "/debug/pprof/cmdline"} {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code, target)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// computeETag вычисляет сильный ETag по JSON-представлению ответа.
+// Представление включает ключ, значение и время истечения, поэтому ETag меняется при любой перезаписи элемента.
+func computeETag(data interface{}) (string, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write(b)
+
+	return `"` + hex.EncodeToString(hash.Sum(nil)) + `"`, nil
+}
+
+// etagMatches проверяет, совпадает ли ETag с одним из значений заголовка If-None-Match.
+// Для If-None-Match используется слабое сравнение (RFC 9110, раздел 13.1.2).
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setCacheHeaders устанавливает заголовки ETag, Cache-Control и Expires по времени истечения элемента.
+func setCacheHeaders(w http.ResponseWriter, etag string, expiresAt time.Time) {
+	maxAge := int64(time.Until(expiresAt) / time.Second)
+	if maxAge < 0 {
+		maxAge = 0
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "max-age="+strconv.FormatInt(maxAge, 10))
+	w.Header().Set("Expires", expiresAt.UTC().Format(http.TimeFormat))
+}
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/models"
+	"github.com/instinctG/lru-cache/internal/namespace"
+	"github.com/instinctG/lru-cache/pkg/lrucache"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// cacheCtxKey - ключ контекста запроса, в котором хранится именованный кэш.
+type cacheCtxKey struct{}
+
+// cache возвращает кэш, с которым работает запрос: именованный кэш из пути /api/caches/{name} или кэш по умолчанию.
+func (h *Handler) cache(r *http.Request) ILRUCache {
+	if cache, ok := r.Context().Value(cacheCtxKey{}).(ILRUCache); ok {
+		return cache
+	}
+	return h.LRU
+}
+
+// namespace - middleware, которое находит именованный кэш по параметру пути name и передает его обработчикам API v2.
+// Имя default соответствует кэшу по умолчанию, а для арендатора - кэшу арендатора.
+// Именованные кэши общие для всех клиентов, поэтому запросы арендаторов к ним отклоняются с кодом 403.
+// Именованные кэши не реплицируются, поэтому на реплике запросы к ним отклоняются с кодом 409.
+func (h *Handler) namespace(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		if name == namespace.DefaultName {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if t, ok := tenantName(r); ok {
+			h.Log.Debug("named cache requested by tenant", slog.String("cache", name), slog.String("tenant", t))
+
+			problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden,
+				"named caches are not available to tenant "+t))
+
+			return
+		}
+
+		if h.replica != nil {
+			h.notReplicated(w, r, "named caches")
+			return
+		}
+
+		ns, ok := h.caches.Get(name)
+		if !ok {
+			h.Log.Debug("cache not found", slog.String("cache", name))
+
+			problem.Write(w, r, cacheNotFoundProblem(name))
+
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), cacheCtxKey{}, ILRUCache(ns.Cache))))
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// ListCaches обрабатывает запрос на получение списка кэшей. Первым в списке идет кэш по умолчанию.
+// Реплика возвращает только кэш по умолчанию: именованные кэши не реплицируются.
+func (h *Handler) ListCaches(w http.ResponseWriter, r *http.Request) {
+	var named []*namespace.Namespace
+	if h.replica == nil {
+		named = h.caches.List()
+	}
+
+	resp := models.CacheList{Items: make([]models.CacheInfo, 0, len(named)+1)}
+	resp.Items = append(resp.Items, h.defaultCacheInfo())
+	for _, ns := range named {
+		resp.Items = append(resp.Items, cacheInfo(ns.Spec, ns.Cache.Stats()))
+	}
+	resp.Count = len(resp.Items)
+
+	jsonRespond(w, r, http.StatusOK, resp)
+}
+
+// GetCache обрабатывает запрос на получение параметров и статистики кэша по имени.
+func (h *Handler) GetCache(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if name == namespace.DefaultName {
+		jsonRespond(w, r, http.StatusOK, h.defaultCacheInfo())
+		return
+	}
+
+	if h.replica != nil {
+		h.notReplicated(w, r, "named caches")
+		return
+	}
+
+	ns, ok := h.caches.Get(name)
+	if !ok {
+		h.Log.Debug("cache not found", slog.String("cache", name))
+
+		problem.Write(w, r, cacheNotFoundProblem(name))
+
+		return
+	}
+
+	jsonRespond(w, r, http.StatusOK, cacheInfo(ns.Spec, ns.Cache.Stats()))
+}
+
+// CreateCache обрабатывает запрос на создание именованного кэша.
+func (h *Handler) CreateCache(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateCacheRequest
+
+	if err := h.decodeJSON(w, r, &req); err != nil {
+
+		h.Log.Debug("failed to decode request body", sl.Err(err))
+
+		problem.Write(w, r, decodeErrorProblem(err))
+
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+
+		validateErr := err.(validator.ValidationErrors)
+
+		h.Log.Debug("invalid request", sl.Err(validateErr))
+
+		problem.Write(w, r, ValidationError(validateErr))
+
+		return
+	}
+
+	spec := namespace.Spec{
+		Name:     req.Name,
+		Capacity: req.Capacity,
+		TTL:      time.Duration(req.TTLSeconds) * time.Second,
+		Policy:   h.caches.Default().Policy,
+	}
+	if req.Policy != "" {
+		spec.Policy, _ = lrucache.ParsePolicy(req.Policy)
+	}
+
+	ns, err := h.caches.Create(spec)
+	if err != nil {
+
+		h.Log.Debug("failed to create cache", sl.Err(err))
+
+		problem.Write(w, r, namespaceErrorProblem(err))
+
+		return
+	}
+
+	h.Log.Info("cache created", slog.String("cache", ns.Name), slog.Int("capacity", ns.Capacity),
+		slog.Duration("ttl", ns.TTL), slog.String("policy", ns.Policy.String()))
+
+	w.Header().Set("Location", "/api/caches/"+ns.Name)
+	jsonRespond(w, r, http.StatusCreated, cacheInfo(ns.Spec, ns.Cache.Stats()))
+}
+
+// DropCache обрабатывает запрос на удаление именованного кэша вместе с его элементами.
+func (h *Handler) DropCache(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	if err := h.caches.Drop(name); err != nil {
+
+		h.Log.Debug("failed to drop cache", sl.Err(err))
+
+		problem.Write(w, r, namespaceErrorProblem(err))
+
+		return
+	}
+
+	h.Log.Info("cache dropped", slog.String("cache", name))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// defaultCacheInfo возвращает параметры и статистику кэша по умолчанию.
+func (h *Handler) defaultCacheInfo() models.CacheInfo {
+	var stats lrucache.Stats
+	if h.cacheStats != nil {
+		stats = h.cacheStats.Stats()
+	}
+
+	spec := h.caches.Default()
+	if spec.Capacity == 0 {
+		spec.Capacity = stats.Capacity
+	}
+
+	info := cacheInfo(spec, stats)
+	info.Default = true
+
+	return info
+}
+
+// cacheInfo формирует описание кэша для ответа.
+func cacheInfo(spec namespace.Spec, stats lrucache.Stats) models.CacheInfo {
+	return models.CacheInfo{
+		Name:       spec.Name,
+		Capacity:   spec.Capacity,
+		TTLSeconds: int64(spec.TTL / time.Second),
+		Policy:     spec.Policy.String(),
+		Size:       stats.Size,
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+	}
+}
+
+// cacheNotFoundProblem формирует ответ об отсутствии именованного кэша.
+func cacheNotFoundProblem(name string) problem.Problem {
+	return problem.New(http.StatusNotFound, problem.CodeCacheNotFound, "cache "+name+" not found")
+}
+
+// namespaceErrorProblem сопоставляет ошибку реестра именованных кэшей с ответом об ошибке.
+func namespaceErrorProblem(err error) problem.Problem {
+	switch {
+	case errors.Is(err, namespace.ErrNotFound):
+		return problem.New(http.StatusNotFound, problem.CodeCacheNotFound, err.Error())
+	case errors.Is(err, namespace.ErrExists), errors.Is(err, namespace.ErrDefault):
+		return problem.New(http.StatusConflict, problem.CodeConflict, err.Error())
+	case errors.Is(err, namespace.ErrInvalidName):
+		p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, err.Error())
+		p.Errors = []problem.FieldError{{Field: "name", Rule: "pattern", Message: err.Error()}}
+		return p
+	default:
+		return problem.New(http.StatusBadRequest, problem.CodeValidationFailed, err.Error())
+	}
+}
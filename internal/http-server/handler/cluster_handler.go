@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/instinctG/lru-cache/internal/cluster"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/models"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// forward - middleware кластерного режима для маршрутов с ключом в пути. Запрос с ключом, который по кольцу
+// принадлежит другому узлу, пересылается владельцу, остальные запросы обрабатываются локально.
+func (h *Handler) forward(next http.Handler) http.Handler {
+	return h.forwardBy(next, func(w http.ResponseWriter, r *http.Request) (string, bool) {
+		return keyParam(r), true
+	})
+}
+
+// forwardBody работает как forward для запросов, в которых ключ передается в теле (POST /api/lru).
+// Тело читается с учетом MaxBodyBytes и восстанавливается для обработчика. Если ключ не удалось
+// прочитать, запрос обрабатывается локально и обработчик сообщает об ошибке сам.
+func (h *Handler) forwardBody(next http.Handler) http.Handler {
+	return h.forwardBy(next, func(w http.ResponseWriter, r *http.Request) (string, bool) {
+		body := r.Body
+		if h.limits.MaxBodyBytes > 0 {
+			body = http.MaxBytesReader(w, r.Body, h.limits.MaxBodyBytes)
+		}
+
+		data, err := io.ReadAll(body)
+		if err != nil {
+
+			h.Log.Debug("failed to read request body", sl.Err(err))
+
+			problem.Write(w, r, decodeErrorProblem(err))
+
+			return "", false
+		}
+		r.Body = io.NopCloser(bytes.NewReader(data))
+
+		var req struct {
+			Key string `json:"key"`
+		}
+		_ = json.Unmarshal(data, &req)
+
+		return req.Key, true
+	})
+}
+
+// forwardBy пересылает запрос владельцу ключа, который возвращает key. Если key вернул false,
+// ответ уже записан. Запросы, уже пересланные другим узлом, обрабатываются локально. Заголовок пересылки
+// без действительной подписи участника кластера удаляется, и запрос обрабатывается как запрос клиента.
+func (h *Handler) forwardBy(next http.Handler, key func(w http.ResponseWriter, r *http.Request) (string, bool)) http.Handler {
+	if h.cluster == nil {
+		return next
+	}
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if h.cluster.Forwarded(r) {
+			w.Header().Set(cluster.OwnerHeader, h.cluster.Self())
+			next.ServeHTTP(w, r)
+			return
+		}
+		if by := r.Header.Get(cluster.ForwardedHeader); by != "" {
+			h.Log.Debug("ignoring unsigned forwarded request", slog.String("forwarded_by", by))
+
+			r.Header.Del(cluster.ForwardedHeader)
+			r.Header.Del(cluster.SignatureHeader)
+		}
+
+		k, ok := key(w, r)
+		if !ok {
+			return
+		}
+
+		owner := h.cluster.Owner(k)
+		if k == "" || owner == h.cluster.Self() {
+			w.Header().Set(cluster.OwnerHeader, h.cluster.Self())
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.Log.Debug("forwarding request", slog.String("key", k), slog.String("owner", owner))
+
+		h.cluster.Forward(w, r, owner)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// fanOut - middleware кластерного режима для маршрутов без ключа: списков и очистки кэша. Запрос выполняется
+// на этом узле и рассылается остальным участникам, которые выполняют его локально. Если все узлы ответили
+// успешно, тела ответов объединяются функцией merge, а при merge = nil возвращается ответ этого узла.
+// Иначе возвращается первая ошибка: ответ узла с кодом не 2xx или 502, если участник недоступен.
+// Запрос, разосланный другим участником, выполняется только локально, даже без подписи (см. cluster.FromPeer).
+func (h *Handler) fanOut(merge func(bodies [][]byte) (interface{}, error)) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if h.cluster == nil {
+			return next
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if h.cluster.FromPeer(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			r.Header.Del(cluster.ForwardedHeader)
+			r.Header.Del(cluster.SignatureHeader)
+
+			var local bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&local)
+			ww.Discard()
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status < 200 || status > 299 {
+				w.WriteHeader(status)
+				_, _ = w.Write(local.Bytes())
+				return
+			}
+
+			bodies := [][]byte{local.Bytes()}
+			for _, resp := range h.cluster.Broadcast(r) {
+				if resp.Err != nil {
+					problem.Write(w, r, problem.New(http.StatusBadGateway, problem.CodePeerUnavailable,
+						"peer "+resp.Peer+" is unavailable"))
+					return
+				}
+				if resp.Status < 200 || resp.Status > 299 {
+					w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+					w.WriteHeader(resp.Status)
+					_, _ = w.Write(resp.Body)
+					return
+				}
+				bodies = append(bodies, resp.Body)
+			}
+
+			if merge == nil {
+				w.WriteHeader(status)
+				_, _ = w.Write(local.Bytes())
+				return
+			}
+
+			merged, err := merge(bodies)
+			if err != nil {
+
+				h.Log.Error("failed to merge peer responses", sl.Err(err))
+
+				problem.Write(w, r, problem.New(http.StatusBadGateway, problem.CodePeerUnavailable,
+					"invalid peer response"))
+
+				return
+			}
+
+			jsonRespond(w, r, status, merged)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// mergeEntryLists объединяет списки элементов API v2 всех узлов.
+func mergeEntryLists(bodies [][]byte) (interface{}, error) {
+	merged := models.EntryList{Items: []models.Entry{}}
+	for _, body := range bodies {
+		var list models.EntryList
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, err
+		}
+		merged.Items = append(merged.Items, list.Items...)
+	}
+	merged.Count = len(merged.Items)
+
+	return merged, nil
+}
+
+// mergeLRU объединяет ответы GET /api/lru всех узлов.
+func mergeLRU(bodies [][]byte) (interface{}, error) {
+	merged := models.GetLRU{Keys: []string{}, Values: []interface{}{}}
+	for _, body := range bodies {
+		var resp models.GetLRU
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		merged.Keys = append(merged.Keys, resp.Keys...)
+		merged.Values = append(merged.Values, resp.Values...)
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"net/http"
+)
+
+// DefaultDocsAssets - адрес ресурсов Swagger UI по умолчанию. Версия закреплена,
+// чтобы страница документации не менялась при выходе новых версий Swagger UI.
+const DefaultDocsAssets = "https://unpkg.com/swagger-ui-dist@5.17.14"
+
+// openAPISpec - спецификация OpenAPI 3, встроенная в бинарный файл.
+//
+//go:embed api/openapi.json
+var openAPISpec []byte
+
+// docsTemplate - шаблон страницы документации Swagger UI, встроенный в бинарный файл.
+// Параметр шаблона - адрес каталога с swagger-ui.css и swagger-ui-bundle.js.
+//
+//go:embed api/docs.html
+var docsTemplate string
+
+// docsPage - шаблон страницы документации.
+var docsPage = template.Must(template.New("docs").Parse(docsTemplate))
+
+// renderDocs строит страницу документации с ресурсами Swagger UI по адресу assets.
+func renderDocs(assets string) []byte {
+	var buf bytes.Buffer
+	if err := docsPage.Execute(&buf, assets); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// OpenAPI возвращает спецификацию OpenAPI 3 для всех маршрутов сервиса.
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPISpec)
+}
+
+// Docs возвращает страницу документации Swagger UI, построенную по спецификации OpenAPI.
+func (h *Handler) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(h.docs)
+}
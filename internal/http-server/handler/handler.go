@@ -3,79 +3,491 @@ package handler
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/instinctG/lru-cache/internal/cluster"
+	"github.com/instinctG/lru-cache/internal/health"
+	"github.com/instinctG/lru-cache/internal/http-server/admin"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	mw_compress "github.com/instinctG/lru-cache/internal/http-server/middleware/compress"
 	logger "github.com/instinctG/lru-cache/internal/http-server/middleware/logger"
+	mw_metrics "github.com/instinctG/lru-cache/internal/http-server/middleware/metrics"
+	mw_ratelimit "github.com/instinctG/lru-cache/internal/http-server/middleware/ratelimit"
 	sl "github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/metrics"
+	"github.com/instinctG/lru-cache/internal/namespace"
+	"github.com/instinctG/lru-cache/internal/replication"
+	"github.com/instinctG/lru-cache/internal/tenant"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
 
 // Handler представляет структуру для обработки HTTP-запросов и управления сервером.
 type Handler struct {
-	LRU    ILRUCache    // Интерфейс LRU-кэша для обработки запросов.
-	Log    *slog.Logger // Логгер для записи событий сервера.
-	Router *chi.Mux     // Роутер для маршрутизации запросов.
-	Server *http.Server // HTTP-сервер.
+	LRU     ILRUCache         // Интерфейс LRU-кэша для обработки запросов.
+	Log     *slog.Logger      // Логгер для записи событий сервера.
+	Router  *chi.Mux          // Роутер для маршрутизации запросов.
+	Server  *http.Server      // HTTP-сервер.
+	Metrics *metrics.Registry // Реестр метрик, отдаваемых по /metrics.
+	Health  *health.Health    // Состояние компонентов для проверок /healthz и /readyz.
+
+	AdminServer *http.Server // Диагностический сервер с pprof и expvar, nil если отключен.
+	GRPCServer  *grpc.Server // gRPC-сервер API кэша, nil если отключен.
+
+	caches     *namespace.Registry // Именованные кэши, доступные по /api/caches/{name}.
+	cacheStats statsProvider       // Статистика кэша по умолчанию, nil если кэш ее не ведет.
+	snapshots  snapshotter         // Снимки кэша по умолчанию со временем истечения, nil если кэш их не отдает.
+
+	tenants      *tenant.Registry // Арендаторы со своими кэшами и квотами.
+	tenantHeader string           // Заголовок запроса с именем арендатора, пустой - арендатор определяется только по API-ключу.
+
+	cluster *cluster.Cluster // Кластер, которому принадлежит узел, nil - кластерный режим отключен.
+
+	primary *replication.Primary // Журнал изменений для реплик, nil - узел не является ведущим.
+	replica *replication.Replica // Реплика ведущего узла, nil - узел не является репликой.
+
+	limits         Limits              // Ограничения на размер запросов на запись.
+	compression    *mw_compress.Config // Параметры сжатия ответов, nil - ответы не сжимаются.
+	auth           mw_auth.Config      // Параметры аутентификации запросов.
+	rateLimit      mw_ratelimit.Config // Бюджеты частоты запросов клиентов.
+	maxInFlight    int                 // Максимальное число одновременно обрабатываемых запросов, 0 - без ограничения.
+	tlsConfig      *tls.Config         // Настройки TLS основного сервера, nil - сервер работает по HTTP.
+	h2c            bool                // Принимать HTTP/2 без TLS (h2c).
+	unixSocketMode os.FileMode         // Права файла unix-сокета, 0 - по умолчанию.
+	adminAddress   string              // Адрес диагностического сервера.
+	grpcAddress    string              // Адрес gRPC-сервера.
+	drainDelay     time.Duration       // Задержка между переходом в состояние "не готов" и остановкой сервера.
+	docsAssets     string              // Адрес каталога с ресурсами Swagger UI.
+	docs           []byte              // Страница документации /docs.
+}
+
+// Option задает дополнительные параметры Handler.
+type Option func(h *Handler)
+
+// WithMetrics задает реестр метрик, в котором Handler регистрирует метрики кэша и HTTP-запросов.
+// Позволяет отдавать по /metrics метрики других компонентов сервиса. По умолчанию создается новый реестр.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(h *Handler) {
+		h.Metrics = reg
+	}
+}
+
+// WithHealth задает общий объект состояния компонентов, в котором другие компоненты сервиса
+// (восстановление снимка, фоновые задачи) сообщают о своем состоянии. По умолчанию создается новый.
+func WithHealth(hc *health.Health) Option {
+	return func(h *Handler) {
+		h.Health = hc
+	}
+}
+
+// WithDrainDelay задает время, в течение которого сервер продолжает обрабатывать запросы
+// после получения сигнала завершения, сообщая о неготовности через /readyz.
+// За это время балансировщики нагрузки успевают исключить экземпляр до остановки сервера.
+func WithDrainDelay(d time.Duration) Option {
+	return func(h *Handler) {
+		h.drainDelay = d
+	}
+}
+
+// WithAdmin включает диагностический сервер с pprof, expvar и сведениями о среде выполнения на отдельном адресе.
+// Пустой адрес оставляет сервер отключенным.
+func WithAdmin(address string) Option {
+	return func(h *Handler) {
+		h.adminAddress = address
+	}
+}
+
+// WithGRPC задает gRPC-сервер, который запускается на адресе address и останавливается
+// вместе с основным сервером при graceful shutdown.
+func WithGRPC(address string, srv *grpc.Server) Option {
+	return func(h *Handler) {
+		h.grpcAddress = address
+		h.GRPCServer = srv
+	}
+}
+
+// WithCaches задает реестр именованных кэшей. По умолчанию создается пустой реестр,
+// и по /api/caches доступен только кэш по умолчанию.
+func WithCaches(reg *namespace.Registry) Option {
+	return func(h *Handler) {
+		h.caches = reg
+	}
+}
+
+// WithTenants задает реестр арендаторов и заголовок запроса, по которому определяется арендатор.
+// Арендатор определяется по имени аутентифицированного субъекта (например, имени API-ключа), а при отключенной
+// аутентификации - по заголовку header. Пустой header отключает определение арендатора по заголовку.
+func WithTenants(reg *tenant.Registry, header string) Option {
+	return func(h *Handler) {
+		h.tenants = reg
+		h.tenantHeader = header
+	}
+}
+
+// WithCluster включает кластерный режим: запросы с ключом, принадлежащим другому узлу, пересылаются владельцу.
+// Списки и очистка кэша (GET и DELETE без ключа) выполняются на всех узлах, списки объединяются.
+// При включенной аутентификации узел принимает субъекта, переданного участником кластера (см. cluster.Cluster.Authenticate).
+func WithCluster(c *cluster.Cluster) Option {
+	return func(h *Handler) {
+		h.cluster = c
+	}
+}
+
+// WithReplicationPrimary делает узел ведущим: реплики получают изменения кэша по умолчанию
+// по /api/replication/stream. Журнал primary должен быть запущен вызовом Run.
+func WithReplicationPrimary(p *replication.Primary) Option {
+	return func(h *Handler) {
+		h.primary = p
+	}
+}
+
+// WithReplica делает узел репликой: запросы на запись перенаправляются ведущему узлу r.Primary().
+// Изменения ведущего узла применяются к кэшу по умолчанию вызовом r.Run.
+func WithReplica(r *replication.Replica) Option {
+	return func(h *Handler) {
+		h.replica = r
+	}
+}
+
+// WithLimits задает ограничения на размер запросов на запись и строгость разбора JSON. По умолчанию используются DefaultLimits.
+func WithLimits(l Limits) Option {
+	return func(h *Handler) {
+		h.limits = l
+	}
+}
+
+// WithCompression включает сжатие ответов gzip и deflate по заголовку Accept-Encoding.
+func WithCompression(cfg mw_compress.Config) Option {
+	return func(h *Handler) {
+		h.compression = &cfg
+	}
+}
+
+// WithAuth включает аутентификацию запросов. Если не задан ни один способ аутентификации, запросы не проверяются.
+func WithAuth(cfg mw_auth.Config) Option {
+	return func(h *Handler) {
+		h.auth = cfg
+	}
+}
+
+// WithRateLimit включает ограничение частоты запросов клиентов. Если не задан ни один бюджет, частота не ограничивается.
+func WithRateLimit(cfg mw_ratelimit.Config) Option {
+	return func(h *Handler) {
+		h.rateLimit = cfg
+	}
+}
+
+// WithMaxInFlight ограничивает число одновременно обрабатываемых запросов. 0 отключает ограничение.
+// Проверки состояния и метрики обрабатываются без учета ограничения.
+func WithMaxInFlight(n int) Option {
+	return func(h *Handler) {
+		h.maxInFlight = n
+	}
+}
+
+// WithTLS включает TLS на основном сервере. Сертификат сервера должен задаваться в cfg
+// (Certificates или GetCertificate), проверка клиентских сертификатов - через ClientCAs и ClientAuth.
+func WithTLS(cfg *tls.Config) Option {
+	return func(h *Handler) {
+		h.tlsConfig = cfg
+	}
+}
+
+// WithH2C включает HTTP/2 без TLS (h2c) наряду с HTTP/1.1: клиенты могут мультиплексировать запросы в одном соединении.
+// При включенном TLS HTTP/2 согласуется через ALPN, и параметр не используется.
+func WithH2C() Option {
+	return func(h *Handler) {
+		h.h2c = true
+	}
+}
+
+// WithUnixSocketMode задает права файла unix-сокета, если адрес сервера имеет вид "unix:///path.sock".
+func WithUnixSocketMode(mode os.FileMode) Option {
+	return func(h *Handler) {
+		h.unixSocketMode = mode
+	}
+}
+
+// WithDocsAssets задает адрес каталога с ресурсами Swagger UI (swagger-ui.css и swagger-ui-bundle.js)
+// для страницы /docs. В изолированных от интернета окружениях ресурсы размещаются на собственном сервере.
+// По умолчанию DefaultDocsAssets.
+func WithDocsAssets(url string) Option {
+	return func(h *Handler) {
+		h.docsAssets = strings.TrimSuffix(url, "/")
+	}
 }
 
 // NewHandler создает новый экземпляр Handler.
 // Параметры:
 //   - lru: реализация интерфейса LRU-кэша.
-//   - address: адрес для запуска HTTP-сервера (например, "localhost:8080").
+//   - address: адрес для запуска HTTP-сервера (например, "localhost:8080" или "unix:///run/lru-cache.sock").
 //   - log: логгер для обработки событий.
+//   - opts: дополнительные параметры.
 //
 // Возвращает: указатель на созданный Handler.
-func NewHandler(lru ILRUCache, address string, log *slog.Logger) *Handler {
+func NewHandler(lru ILRUCache, address string, log *slog.Logger, opts ...Option) *Handler {
 	h := &Handler{
-		LRU:    lru,
-		Log:    log,
-		Router: chi.NewRouter(),
+		LRU:     lru,
+		Log:     log,
+		Router:  chi.NewRouter(),
+		Metrics: metrics.NewRegistry(),
+		Health:  health.New(),
+		limits:  DefaultLimits,
+
+		docsAssets: DefaultDocsAssets,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.docs = renderDocs(h.docsAssets)
+
+	if h.caches == nil {
+		h.caches = namespace.NewRegistry(namespace.Spec{})
+	}
+	h.cacheStats, _ = h.LRU.(statsProvider)
+	h.snapshots, _ = h.LRU.(snapshotter)
+	if h.tenants == nil {
+		h.tenants = tenant.NewRegistry(h.caches.Default().TTL)
+	}
+	registerTenantStats(h.Metrics, h.tenants)
+	h.registerReplicationStats(h.Metrics)
+
+	h.Health.Register("cache", cacheHealthCheck(h.LRU))
+	if h.replica != nil {
+		h.Health.Register("replication", replicationHealthCheck(h.replica))
+	}
+
+	if h.adminAddress != "" {
+		h.AdminServer = admin.NewServer(h.adminAddress, adminVars(h.LRU))
 	}
 
+	// Измерение времени выполнения операций кэша.
+	h.LRU = newInstrumentedCache(h.LRU, h.Metrics)
+
 	// Настройка middleware
+	h.Router.Use(middleware.GetHead)   // HEAD обрабатывается маршрутами GET с правом read.
 	h.Router.Use(middleware.RequestID) // Генерация идентификаторов запросов.
 	//h.Router.Use(middleware.Logger)  //Можно использовать логгер от chi, но решил написать свой для удобства логов
-	h.Router.Use(logger.New(log))      // Логирование запросов.
-	h.Router.Use(middleware.Recoverer) // Восстановление после паники.
-	h.Router.Use(middleware.URLFormat) // Поддержка форматов URL.
+	h.Router.Use(logger.New(log))           // Логирование запросов.
+	h.Router.Use(middleware.Recoverer)      // Восстановление после паники.
+	h.Router.Use(mw_metrics.New(h.Metrics)) // Метрики HTTP-запросов.
+	if h.compression != nil {
+		h.Router.Use(mw_compress.New(log, *h.compression)) // Сжатие ответов.
+	}
+	if h.maxInFlight > 0 {
+		h.Router.Use(mw_ratelimit.NewInFlight(log, h.maxInFlight, "/healthz", "/readyz", "/metrics", replication.StreamPath)) // Ограничение одновременных запросов.
+	}
+	if len(h.auth.Authenticators) > 0 && h.cluster != nil {
+		// Субъект запроса, пересланного участником кластера, уже аутентифицирован отправителем.
+		h.auth.Authenticators = append([]mw_auth.Authenticator{h.cluster}, h.auth.Authenticators...)
+	}
+	if len(h.auth.Authenticators) > 0 && h.rateLimit.AuthFailures.Rate > 0 {
+		h.Router.Use(mw_ratelimit.NewAuthFailures(log, h.rateLimit.AuthFailures)) // Ограничение подбора учетных данных по IP-адресу.
+	}
+	if len(h.auth.Authenticators) > 0 {
+		h.Router.Use(mw_auth.New(log, h.auth)) // Аутентификация запросов.
+	}
+	if h.rateLimit.Enabled() {
+		if h.cluster != nil {
+			// Запрос, пересланный участником кластера, уже списан из бюджета клиента на узле, который его получил,
+			// а по IP-адресу все такие запросы выглядели бы запросами одного клиента - узла-отправителя.
+			h.rateLimit.Exempt = h.cluster.Forwarded
+		}
+		h.Router.Use(mw_ratelimit.New(log, h.rateLimit, h.Router)) // Ограничение частоты запросов клиентов.
+	}
 
 	h.mapRoutes()
 
 	h.Server = &http.Server{
-		Addr:    address,
-		Handler: h.Router,
+		Addr:      address,
+		Handler:   h.Router,
+		TLSConfig: h.tlsConfig,
+	}
+
+	// Потоки репликации не завершаются сами, поэтому закрываются в начале graceful shutdown.
+	if h.primary != nil {
+		h.Server.RegisterOnShutdown(h.primary.Close)
+	}
+
+	if h.h2c && h.tlsConfig == nil {
+		h2s := &http2.Server{}
+		h.Server.Handler = h2c.NewHandler(h.Router, h2s)
+
+		// Регистрирует h2s в сервере, чтобы при graceful shutdown соединения HTTP/2 получали GOAWAY.
+		if err := http2.ConfigureServer(h.Server, h2s); err != nil {
+			h.Log.Error("failed to configure HTTP/2", sl.Err(err))
+		}
 	}
 
 	return h
 }
 
 func (h *Handler) mapRoutes() {
-	h.Router.Post("/api/lru", h.Put)
+	// Права на операции с кэшем: чтение - read, изменение отдельных элементов - write, очистка кэша - admin.
+	// Запросы арендаторов работают с кэшем арендатора вместо кэша по умолчанию.
+	// На реплике запросы на изменение перенаправляются ведущему узлу.
+	read := h.Router.With(h.authorize(mw_auth.ScopeRead), h.tenant)
+	write := h.Router.With(h.authorize(mw_auth.ScopeWrite), h.readOnly, h.tenant)
+	admin := h.Router.With(h.authorize(mw_auth.ScopeAdmin), h.readOnly, h.tenant)
+
+	// API v1 сохраняется для обратной совместимости и помечается как устаревшее.
+	v1 := deprecated("/api/v2/cache/entries")
+
+	// В кластерном режиме запросы с ключом пересылаются узлу-владельцу ключа,
+	// а списки и очистка кэша выполняются на всех узлах.
+	write.With(v1, h.forwardBody).Post("/api/lru", h.Put)
+
+	read.With(v1, h.forward).Get("/api/lru/{key}", h.Get)
+	read.With(v1, h.fanOut(mergeLRU)).Get("/api/lru", h.GetAll)
+
+	write.With(v1, h.forward).Delete("/api/lru/{key}", h.Evict)
+	admin.With(v1, h.fanOut(nil)).Delete("/api/lru", h.EvictAll)
+
+	write.With(h.forward).Put("/api/v2/cache/entries/{key}", h.PutEntry)
+
+	read.With(h.forward).Get("/api/v2/cache/entries/{key}", h.GetEntry)
+	read.With(h.fanOut(mergeEntryLists)).Get("/api/v2/cache/entries", h.ListEntries)
+
+	write.With(h.forward).Delete("/api/v2/cache/entries/{key}", h.DeleteEntry)
+	admin.With(h.fanOut(nil)).Delete("/api/v2/cache/entries", h.DeleteEntries)
+
+	// Именованные кэши: создание и удаление требуют права admin, элементы - тех же прав, что и API v2.
+	read.Get("/api/caches", h.ListCaches)
+	admin.Post("/api/caches", h.CreateCache)
+	read.Get("/api/caches/{name}", h.GetCache)
+	admin.Delete("/api/caches/{name}", h.DropCache)
+
+	write.With(h.forward, h.namespace).Put("/api/caches/{name}/entries/{key}", h.PutEntry)
+
+	read.With(h.forward, h.namespace).Get("/api/caches/{name}/entries/{key}", h.GetEntry)
+	read.With(h.fanOut(mergeEntryLists), h.namespace).Get("/api/caches/{name}/entries", h.ListEntries)
+
+	write.With(h.forward, h.namespace).Delete("/api/caches/{name}/entries/{key}", h.DeleteEntry)
+	admin.With(h.fanOut(nil), h.namespace).Delete("/api/caches/{name}/entries", h.DeleteEntries)
+
+	// Арендаторы: квоты меняются только с правом admin.
+	read.With(h.primaryOnly("tenants")).Get("/api/tenants", h.ListTenants)
+	read.With(h.primaryOnly("tenants")).Get("/api/tenants/{name}", h.GetTenant)
+	admin.Put("/api/tenants/{name}", h.SetTenantQuota)
+
+	// Репликация: поток содержит все элементы кэша, поэтому требует права admin.
+	read.Get("/api/replication", h.ReplicationStatus)
+	h.Router.With(h.authorize(mw_auth.ScopeAdmin)).Get("/api/replication/stream", h.ReplicationStream)
+
+	// Каждый маршрут должен быть описан в api/openapi.json, это проверяется тестами.
+	h.Router.Get("/openapi.json", h.OpenAPI)
+	h.Router.Get("/docs", h.Docs)
+
+	read.Method(http.MethodGet, "/metrics", h.Metrics.Handler())
+
+	h.Router.Get("/healthz", h.Healthz)
+	h.Router.Get("/readyz", h.Readyz)
+}
+
+// authorize возвращает middleware проверки права scope.
+// Если аутентификация отключена, права не проверяются.
+func (h *Handler) authorize(scope string) func(next http.Handler) http.Handler {
+	if len(h.auth.Authenticators) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
 
-	h.Router.Get("/api/lru/{key}", h.Get)
-	h.Router.Get("/api/lru", h.GetAll)
+	return mw_auth.Authorize(h.Log, scope)
+}
+
+// deprecated создает middleware, которое помечает ответы устаревшего API заголовками Deprecation и Link.
+// Параметры:
+//   - successor: путь к версии API, которая заменяет устаревшую.
+func deprecated(successor string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+
+			next.ServeHTTP(w, r)
+		}
 
-	h.Router.Delete("/api/lru/{key}", h.Evict)
-	h.Router.Delete("/api/lru", h.EvictAll)
+		return http.HandlerFunc(fn)
+	}
 }
 
 // Serve запускает HTTP-сервер и обрабатывает сигналы завершения работы(graceful-shutdown).
 // Возвращает: ошибку в случае, если сервер не может быть запущен.
 func (h *Handler) Serve() error {
-	h.Log.Info("starting server on port: " + h.Server.Addr)
+	h.Log.Info("starting server on port: "+h.Server.Addr, slog.Bool("tls", h.Server.TLSConfig != nil), slog.Bool("h2c", h.h2c))
+
+	// Все сокеты открываются до запуска серверов: если порт занят, Serve возвращает ошибку,
+	// а не оставляет работать часть серверов.
+	ln, err := h.Listen()
+	if err != nil {
+		return err
+	}
+
+	var adminListener net.Listener
+	if h.AdminServer != nil {
+		if adminListener, err = net.Listen("tcp", h.AdminServer.Addr); err != nil {
+			_ = ln.Close()
+			return err
+		}
+	}
+
+	var grpcListener net.Listener
+	if h.GRPCServer != nil {
+		if grpcListener, err = net.Listen("tcp", h.grpcAddress); err != nil {
+			_ = ln.Close()
+			if adminListener != nil {
+				_ = adminListener.Close()
+			}
+			return err
+		}
+	}
 
 	go func() {
-		if err := h.Server.ListenAndServe(); err != nil {
+		var err error
+		if h.Server.TLSConfig != nil {
+			// Сертификат задан в TLSConfig, поэтому пути к файлам не передаются.
+			err = h.Server.ServeTLS(ln, "", "")
+		} else {
+			err = h.Server.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatal(err)
 		}
 	}()
 
+	if h.AdminServer != nil {
+		h.Log.Info("starting admin server on port: " + h.AdminServer.Addr)
+
+		go func() {
+			if err := h.AdminServer.Serve(adminListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	if h.GRPCServer != nil {
+		h.Log.Info("starting grpc server on port: " + h.grpcAddress)
+
+		go func() {
+			if err := h.GRPCServer.Serve(grpcListener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				log.Fatal(err)
+			}
+		}()
+	}
+
 	// Ожидание сигнала завершения (graceful shutdown)
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -84,6 +496,13 @@ func (h *Handler) Serve() error {
 
 	h.Log.Info("Received shutdown signal")
 
+	// Сообщаем о неготовности и даем балансировщикам время исключить экземпляр до остановки сервера.
+	h.Health.SetDraining()
+	if h.drainDelay > 0 {
+		h.Log.Info("draining connections", slog.String("drain_delay", h.drainDelay.String()))
+		time.Sleep(h.drainDelay)
+	}
+
 	// Завершение работы сервера с таймаутом
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -93,6 +512,16 @@ func (h *Handler) Serve() error {
 		h.Log.Error("Server Shutdown:", sl.Err(err))
 	}
 
+	if h.AdminServer != nil {
+		if err := h.AdminServer.Shutdown(ctx); err != nil {
+			h.Log.Error("Admin Server Shutdown:", sl.Err(err))
+		}
+	}
+
+	if h.GRPCServer != nil {
+		h.stopGRPC(ctx)
+	}
+
 	// Обработка завершения контекста
 	select {
 	case <-ctx.Done():
@@ -102,3 +531,20 @@ func (h *Handler) Serve() error {
 
 	return nil
 }
+
+// stopGRPC дожидается завершения текущих вызовов gRPC. Если ctx завершается раньше,
+// например из-за открытых потоков Watch, оставшиеся соединения закрываются принудительно.
+func (h *Handler) stopGRPC(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		h.GRPCServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		h.Log.Error("GRPC Server Shutdown:", sl.Err(ctx.Err()))
+		h.GRPCServer.Stop()
+	}
+}
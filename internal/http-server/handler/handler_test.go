@@ -2,20 +2,41 @@ package handler_test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/instinctG/lru-cache/internal/cluster"
+	"github.com/instinctG/lru-cache/internal/health"
 	"github.com/instinctG/lru-cache/internal/http-server/handler"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	mw_compress "github.com/instinctG/lru-cache/internal/http-server/middleware/compress"
+	mw_ratelimit "github.com/instinctG/lru-cache/internal/http-server/middleware/ratelimit"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
 	"github.com/instinctG/lru-cache/internal/logger"
 	"github.com/instinctG/lru-cache/internal/lru"
 	"github.com/instinctG/lru-cache/internal/models"
+	"github.com/instinctG/lru-cache/internal/namespace"
+	"github.com/instinctG/lru-cache/internal/replication"
+	"github.com/instinctG/lru-cache/internal/tenant"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -226,13 +247,20 @@ func TestGetAllHandler(t *testing.T) {
 			name:          "Empty cache",
 			mockKeys:      []string{},
 			mockValues:    []interface{}{},
-			mockReturnErr: errors.New("no elements found"),
-			expectedCode:  http.StatusNoContent,
+			mockReturnErr: lru.ErrCacheIsEmpty,
+			expectedCode:  http.StatusOK,
 			expectedResp: models.GetLRU{
 				Keys:   []string{},
 				Values: []interface{}{},
 			},
 		},
+		{
+			name:          "Cache error during get all",
+			mockKeys:      []string{},
+			mockValues:    []interface{}{},
+			mockReturnErr: errors.New("cache error"),
+			expectedCode:  http.StatusInternalServerError,
+		},
 	}
 
 	for _, tt := range tests {
@@ -350,3 +378,1555 @@ func TestEvictAllHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestGetHandlerConditional(t *testing.T) {
+	mockCache := new(MockCache)
+	h := &handler.Handler{
+		LRU: mockCache,
+		Log: logger.NewDiscardLogger(),
+	}
+	router := setupRouter(h)
+
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	mockCache.On("Get", mock.Anything, "test-key").Return("test-value", expiresAt, nil)
+
+	// Первый запрос возвращает значение и заголовки кэширования
+	req := httptest.NewRequest(http.MethodGet, "/api/lru/test-key", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Regexp(t, `^max-age=(59|60)$`, rec.Header().Get("Cache-Control"))
+	assert.Equal(t, expiresAt.UTC().Format(http.TimeFormat), rec.Header().Get("Expires"))
+
+	tests := []struct {
+		name         string
+		ifNoneMatch  string
+		expectedCode int
+	}{
+		{name: "Matching etag", ifNoneMatch: etag, expectedCode: http.StatusNotModified},
+		{name: "Weak matching etag in list", ifNoneMatch: `"other", W/` + etag, expectedCode: http.StatusNotModified},
+		{name: "Wildcard", ifNoneMatch: "*", expectedCode: http.StatusNotModified},
+		{name: "Different etag", ifNoneMatch: `"other"`, expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/lru/test-key", nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			assert.Equal(t, etag, rec.Header().Get("ETag"))
+			if tt.expectedCode == http.StatusNotModified {
+				assert.Empty(t, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		target          string
+		body            string
+		setupMock       func(m *MockCache)
+		expectedCode    int
+		expectedProblem string
+		expectedFields  []string
+	}{
+		{
+			name:            "Empty body",
+			method:          http.MethodPost,
+			target:          "/api/lru",
+			expectedCode:    http.StatusBadRequest,
+			expectedProblem: problem.CodeEmptyBody,
+		},
+		{
+			name:            "Invalid JSON",
+			method:          http.MethodPost,
+			target:          "/api/lru",
+			body:            `{"key":`,
+			expectedCode:    http.StatusBadRequest,
+			expectedProblem: problem.CodeInvalidJSON,
+		},
+		{
+			name:            "Validation failed",
+			method:          http.MethodPost,
+			target:          "/api/lru",
+			body:            `{"ttl_seconds":-1}`,
+			expectedCode:    http.StatusBadRequest,
+			expectedProblem: problem.CodeValidationFailed,
+			expectedFields:  []string{"key", "value", "ttl_seconds"},
+		},
+		{
+			name:            "Value is not a simple type",
+			method:          http.MethodPost,
+			target:          "/api/lru",
+			body:            `{"key":"k","value":{"a":1}}`,
+			expectedCode:    http.StatusBadRequest,
+			expectedProblem: problem.CodeValidationFailed,
+			expectedFields:  []string{"value"},
+		},
+		{
+			name:   "Key not found",
+			method: http.MethodGet,
+			target: "/api/lru/missing",
+			setupMock: func(m *MockCache) {
+				m.On("Get", mock.Anything, "missing").Return(nil, time.Time{}, lru.ErrKeyNotFound)
+			},
+			expectedCode:    http.StatusNotFound,
+			expectedProblem: problem.CodeKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCache := new(MockCache)
+			if tt.setupMock != nil {
+				tt.setupMock(mockCache)
+			}
+
+			h := &handler.Handler{
+				LRU: mockCache,
+				Log: logger.NewDiscardLogger(),
+			}
+			router := setupRouter(h)
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewReader([]byte(tt.body)))
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedCode, rec.Code)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+			var p problem.Problem
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+			assert.Equal(t, tt.expectedCode, p.Status)
+			assert.Equal(t, tt.expectedProblem, p.Code)
+			assert.Equal(t, tt.target, p.Instance)
+
+			var fields []string
+			for _, f := range p.Errors {
+				fields = append(fields, f.Field)
+			}
+			assert.ElementsMatch(t, tt.expectedFields, fields)
+		})
+	}
+}
+
+func TestV2Handlers(t *testing.T) {
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		setupMock    func(m *MockCache)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:   "Put entry",
+			method: http.MethodPut,
+			target: "/api/v2/cache/entries/test-key",
+			body:   `{"value":"test-value","ttl_seconds":60}`,
+			setupMock: func(m *MockCache) {
+				m.On("Put", mock.Anything, "test-key", "test-value", time.Minute).Return(nil)
+			},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Put entry without value",
+			method:       http.MethodPut,
+			target:       "/api/v2/cache/entries/test-key",
+			body:         `{"ttl_seconds":60}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "Get entry",
+			method: http.MethodGet,
+			target: "/api/v2/cache/entries/test-key",
+			setupMock: func(m *MockCache) {
+				m.On("Get", mock.Anything, "test-key").Return("test-value", expiresAt, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"key":"test-key","value":"test-value","expires_at":"` + expiresAt.UTC().Format(time.RFC3339) + `"}`,
+		},
+		{
+			name:   "Get missing entry",
+			method: http.MethodGet,
+			target: "/api/v2/cache/entries/missing",
+			setupMock: func(m *MockCache) {
+				m.On("Get", mock.Anything, "missing").Return(nil, time.Time{}, lru.ErrKeyNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "List entries",
+			method: http.MethodGet,
+			target: "/api/v2/cache/entries",
+			setupMock: func(m *MockCache) {
+				m.On("GetAll", mock.Anything).Return([]string{"key1", "key2"}, []interface{}{"value1", 2.0}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"items":[{"key":"key1","value":"value1"},{"key":"key2","value":2}],"count":2}`,
+		},
+		{
+			name:   "List empty cache",
+			method: http.MethodGet,
+			target: "/api/v2/cache/entries",
+			setupMock: func(m *MockCache) {
+				m.On("GetAll", mock.Anything).Return([]string(nil), []interface{}(nil), lru.ErrCacheIsEmpty)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"items":[],"count":0}`,
+		},
+		{
+			name:   "Delete entry",
+			method: http.MethodDelete,
+			target: "/api/v2/cache/entries/test-key",
+			setupMock: func(m *MockCache) {
+				m.On("Evict", mock.Anything, "test-key").Return("test-value", nil)
+			},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:   "Delete all entries",
+			method: http.MethodDelete,
+			target: "/api/v2/cache/entries",
+			setupMock: func(m *MockCache) {
+				m.On("EvictAll", mock.Anything).Return(nil)
+			},
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCache := new(MockCache)
+			if tt.setupMock != nil {
+				tt.setupMock(mockCache)
+			}
+
+			h := handler.NewHandler(mockCache, ":0", logger.NewDiscardLogger())
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewReader([]byte(tt.body)))
+			rec := httptest.NewRecorder()
+
+			h.Router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			assert.Empty(t, rec.Header().Get("Deprecation"))
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+			}
+			mockCache.AssertExpectations(t)
+		})
+	}
+}
+
+func TestV1DeprecationHeaders(t *testing.T) {
+	mockCache := new(MockCache)
+	mockCache.On("GetAll", mock.Anything).Return([]string{"key1"}, []interface{}{"value1"}, nil)
+
+	h := handler.NewHandler(mockCache, ":0", logger.NewDiscardLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/lru", nil)
+	rec := httptest.NewRecorder()
+
+	h.Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Equal(t, `</api/v2/cache/entries>; rel="successor-version"`, rec.Header().Get("Link"))
+}
+
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	h := handler.NewHandler(new(MockCache), ":0", logger.NewDiscardLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&spec))
+	assert.True(t, strings.HasPrefix(spec.OpenAPI, "3."))
+
+	var specRoutes []string
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			specRoutes = append(specRoutes, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	var routerRoutes []string
+	err := chi.Walk(h.Router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routerRoutes = append(routerRoutes, method+" "+route)
+		return nil
+	})
+	require.NoError(t, err)
+
+	sort.Strings(specRoutes)
+	sort.Strings(routerRoutes)
+	assert.Equal(t, routerRoutes, specRoutes, "routes in mapRoutes and api/openapi.json are out of sync")
+}
+
+func TestDocsPage(t *testing.T) {
+	h := handler.NewHandler(new(MockCache), ":0", logger.NewDiscardLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	rec := httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rec.Body.String(), "/openapi.json")
+	assert.Contains(t, rec.Body.String(), handler.DefaultDocsAssets+"/swagger-ui-bundle.js")
+}
+
+func TestDocsPage_SelfHostedAssets(t *testing.T) {
+	h := handler.NewHandler(new(MockCache), ":0", logger.NewDiscardLogger(), handler.WithDocsAssets("/static/swagger-ui/"))
+
+	req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	rec := httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `href="/static/swagger-ui/swagger-ui.css"`)
+	assert.Contains(t, rec.Body.String(), `src="/static/swagger-ui/swagger-ui-bundle.js"`)
+	assert.NotContains(t, rec.Body.String(), "unpkg.com")
+}
+
+func TestGetKeyWithDots(t *testing.T) {
+	mockCache := new(MockCache)
+	mockCache.On("Get", mock.Anything, "config.json").Return("value", time.Now().Add(time.Minute), nil)
+
+	h := handler.NewHandler(mockCache, ":0", logger.NewDiscardLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/cache/entries/config.json", nil)
+	rec := httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockCache.AssertExpectations(t)
+}
+
+func TestEscapedKey(t *testing.T) {
+	mockCache := new(MockCache)
+	mockCache.On("Get", mock.Anything, "users/1 a").Return("value", time.Now().Add(time.Minute), nil)
+
+	h := handler.NewHandler(mockCache, ":0", logger.NewDiscardLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/cache/entries/users%2F1%20a", nil)
+	rec := httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `"users/1 a"`, mustJSONField(t, rec.Body.Bytes(), "key"))
+	mockCache.AssertExpectations(t)
+}
+
+func TestDeleteEntryPreferRepresentation(t *testing.T) {
+	mockCache := new(MockCache)
+	mockCache.On("Evict", mock.Anything, "test-key").Return("test-value", nil)
+
+	h := handler.NewHandler(mockCache, ":0", logger.NewDiscardLogger())
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v2/cache/entries/test-key", nil)
+	req.Header.Set("Prefer", "respond-async, return=representation")
+	rec := httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "return=representation", rec.Header().Get("Preference-Applied"))
+	assert.JSONEq(t, `{"key":"test-key","value":"test-value"}`, rec.Body.String())
+	mockCache.AssertExpectations(t)
+}
+
+// mustJSONField возвращает поле field JSON-объекта data в виде JSON.
+func mustJSONField(t *testing.T, data []byte, field string) string {
+	t.Helper()
+
+	var obj map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &obj))
+	return string(obj[field])
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	cache := lru.NewLRUCache(1, time.Minute)
+	h := handler.NewHandler(cache, ":0", logger.NewDiscardLogger())
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodPut, "/api/v2/cache/entries/key1", bytes.NewReader([]byte(`{"value":"v"}`))),
+		httptest.NewRequest(http.MethodPut, "/api/v2/cache/entries/key2", bytes.NewReader([]byte(`{"value":"v"}`))),
+		httptest.NewRequest(http.MethodGet, "/api/v2/cache/entries/key2", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v2/cache/entries/key1", nil),
+	}
+	for _, req := range requests {
+		h.Router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+
+	assert.Contains(t, body, "lru_cache_size 1\n")
+	assert.Contains(t, body, "lru_cache_capacity 1\n")
+	assert.Contains(t, body, "lru_cache_hits_total 1\n")
+	assert.Contains(t, body, "lru_cache_misses_total 1\n")
+	assert.Contains(t, body, `lru_cache_evictions_total{reason="capacity"} 1`+"\n")
+	assert.Contains(t, body, `lru_cache_operation_duration_seconds_count{method="Put"} 2`+"\n")
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/v2/cache/entries/{key}",status="404"} 1`+"\n")
+	assert.Contains(t, body, `http_requests_total{method="PUT",route="/api/v2/cache/entries/{key}",status="204"} 2`+"\n")
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/api/v2/cache/entries/{key}"} 2`+"\n")
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	hc := health.New()
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger(), handler.WithHealth(hc))
+
+	get := func(target string) (int, health.Report) {
+		rec := httptest.NewRecorder()
+		h.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+		var report health.Report
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+		return rec.Code, report
+	}
+
+	code, report := get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusUp, report.Status)
+
+	code, report = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusUp, report.Components["cache"].Status)
+	assert.EqualValues(t, 10, report.Components["cache"].Details["capacity"])
+
+	// Пока снимок восстанавливается, сервис не готов
+	hc.Set("persistence", health.Component{Status: health.StatusStarting})
+
+	code, report = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusStarting, report.Components["persistence"].Status)
+
+	hc.Set("persistence", health.Component{Status: health.StatusUp})
+
+	code, _ = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	// После сигнала завершения сервис не готов, но жив
+	hc.SetDraining()
+
+	code, report = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.True(t, report.Draining)
+
+	code, _ = get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestAdminServer(t *testing.T) {
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger())
+	assert.Nil(t, h.AdminServer)
+
+	h = handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger(), handler.WithAdmin("localhost:0"))
+	require.NotNil(t, h.AdminServer)
+
+	// Диагностические эндпоинты доступны только на отдельном сервере
+	rec := httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.AdminServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var vars struct {
+		Cache lru.Stats `json:"lru_cache"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&vars))
+	assert.Equal(t, 10, vars.Cache.Capacity)
+}
+
+func TestAuth(t *testing.T) {
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger(), handler.WithAuth(mw_auth.Config{
+		Authenticators: []mw_auth.Authenticator{mw_auth.NewAPIKeyAuthenticator(map[string]string{
+			"consumer": "consumer-key",
+			"service":  "service-key",
+			"ops":      "ops-key",
+			"nobody":   "nobody-key",
+		})},
+		PublicPaths: []string{"/healthz"},
+		Scopes: map[string][]string{
+			"consumer": {mw_auth.ScopeRead},
+			"service":  {mw_auth.ScopeWrite},
+			"ops":      {mw_auth.ScopeAdmin},
+		},
+	}))
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		key          string
+		expectedCode int
+	}{
+		{name: "Public path", method: http.MethodGet, target: "/healthz", expectedCode: http.StatusOK},
+		{name: "No key", method: http.MethodGet, target: "/api/v2/cache/entries", expectedCode: http.StatusUnauthorized},
+		{name: "Wrong key", method: http.MethodGet, target: "/api/v2/cache/entries", key: "wrong", expectedCode: http.StatusUnauthorized},
+		{name: "No scopes", method: http.MethodGet, target: "/api/v2/cache/entries", key: "nobody-key", expectedCode: http.StatusForbidden},
+		{name: "Read", method: http.MethodGet, target: "/api/v2/cache/entries", key: "consumer-key", expectedCode: http.StatusOK},
+		{name: "Read metrics", method: http.MethodGet, target: "/metrics", key: "consumer-key", expectedCode: http.StatusOK},
+		{name: "Write without scope", method: http.MethodPut, target: "/api/v2/cache/entries/a", body: `{"value":1}`, key: "consumer-key", expectedCode: http.StatusForbidden},
+		{name: "Write", method: http.MethodPut, target: "/api/v2/cache/entries/a", body: `{"value":1}`, key: "service-key", expectedCode: http.StatusNoContent},
+		{name: "Write implies read", method: http.MethodGet, target: "/api/v2/cache/entries/a", key: "service-key", expectedCode: http.StatusOK},
+		{name: "Head", method: http.MethodHead, target: "/api/v2/cache/entries/a", key: "consumer-key", expectedCode: http.StatusOK},
+		{name: "Head list", method: http.MethodHead, target: "/api/v2/cache/entries", key: "consumer-key", expectedCode: http.StatusOK},
+		{name: "Head without key", method: http.MethodHead, target: "/api/v2/cache/entries/a", expectedCode: http.StatusUnauthorized},
+		{name: "Head without scope", method: http.MethodHead, target: "/api/v2/cache/entries/a", key: "nobody-key", expectedCode: http.StatusForbidden},
+		{name: "Write v1", method: http.MethodPost, target: "/api/lru", body: `{"key":"b","value":1}`, key: "service-key", expectedCode: http.StatusCreated},
+		{name: "Delete entry", method: http.MethodDelete, target: "/api/v2/cache/entries/b", key: "service-key", expectedCode: http.StatusNoContent},
+		{name: "Bulk delete without scope", method: http.MethodDelete, target: "/api/v2/cache/entries", key: "service-key", expectedCode: http.StatusForbidden},
+		{name: "Bulk delete v1 without scope", method: http.MethodDelete, target: "/api/lru", key: "service-key", expectedCode: http.StatusForbidden},
+		{name: "Bulk delete", method: http.MethodDelete, target: "/api/v2/cache/entries", key: "ops-key", expectedCode: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.key != "" {
+				req.Header.Set(mw_auth.APIKeyHeader, tt.key)
+			}
+			rec := httptest.NewRecorder()
+
+			h.Router.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedCode, rec.Code, rec.Body.String())
+			switch tt.expectedCode {
+			case http.StatusUnauthorized, http.StatusForbidden:
+				var p problem.Problem
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+				if tt.expectedCode == http.StatusUnauthorized {
+					assert.Equal(t, problem.CodeUnauthorized, p.Code)
+				} else {
+					assert.Equal(t, problem.CodeForbidden, p.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger(),
+		handler.WithRateLimit(mw_ratelimit.Config{
+			Default: mw_ratelimit.Limit{Rate: 1, Burst: 1},
+			Routes:  map[string]mw_ratelimit.Limit{"DELETE /api/v2/cache/entries": {Rate: 1, Burst: 1}},
+		}),
+		handler.WithMaxInFlight(10),
+	)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.Router.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/v2/cache/entries").Code)
+
+	rec := serve(http.MethodGet, "/api/v2/cache/entries/a")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	// Бюджет очистки кэша не расходуется на чтение
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/api/v2/cache/entries").Code)
+}
+
+func TestRateLimit_AuthFailures(t *testing.T) {
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger(),
+		handler.WithAuth(mw_auth.Config{
+			Authenticators: []mw_auth.Authenticator{mw_auth.NewAPIKeyAuthenticator(map[string]string{"consumer": "consumer-key"})},
+			DefaultScopes:  []string{mw_auth.ScopeRead},
+		}),
+		handler.WithRateLimit(mw_ratelimit.Config{AuthFailures: mw_ratelimit.Limit{Rate: 0.01, Burst: 1}}),
+	)
+
+	serve := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v2/cache/entries", nil)
+		req.Header.Set(mw_auth.APIKeyHeader, key)
+		rec := httptest.NewRecorder()
+		h.Router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("consumer-key"))
+	assert.Equal(t, http.StatusUnauthorized, serve("guess-1"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("guess-2"))
+}
+
+func TestUnixSocketH2C(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lru.sock")
+
+	// Файл, оставшийся после аварийного завершения, не мешает запуску
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), "unix://"+path, logger.NewDiscardLogger(),
+		handler.WithUnixSocketMode(0o600), handler.WithH2C())
+
+	ln, err := h.Listen()
+	require.NoError(t, err)
+	go func() { _ = h.Server.Serve(ln) }()
+	t.Cleanup(func() { _ = h.Server.Close() })
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Занятый сокет не удаляется
+	_, err = handler.NewHandler(lru.NewLRUCache(10, time.Minute), "unix://"+path, logger.NewDiscardLogger()).Listen()
+	assert.Error(t, err)
+
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", path)
+	}
+
+	tests := []struct {
+		name          string
+		transport     http.RoundTripper
+		expectedProto int
+	}{
+		{name: "HTTP/1.1", transport: &http.Transport{DialContext: dial}, expectedProto: 1},
+		{name: "h2c", transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		}, expectedProto: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: tt.transport}
+
+			req, err := http.NewRequest(http.MethodPut, "http://lru/api/v2/cache/entries/a", strings.NewReader(`{"value":1}`))
+			require.NoError(t, err)
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+			resp, err = client.Get("http://lru/api/v2/cache/entries/a")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, tt.expectedProto, resp.ProtoMajor)
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger(), handler.WithLimits(handler.Limits{
+		MaxBodyBytes:          64,
+		MaxKeyLength:          4,
+		MaxValueSize:          8,
+		DisallowUnknownFields: true,
+	}))
+
+	tests := []struct {
+		name            string
+		method          string
+		target          string
+		body            string
+		expectedCode    int
+		expectedProblem string
+		expectedFields  []string
+	}{
+		{name: "Within limits", method: http.MethodPost, target: "/api/lru", body: `{"key":"abcd","value":"12345678"}`, expectedCode: http.StatusCreated},
+		{name: "Body too large", method: http.MethodPost, target: "/api/lru", body: `{"key":"a","value":"` + strings.Repeat("x", 64) + `"}`, expectedCode: http.StatusRequestEntityTooLarge, expectedProblem: problem.CodeBodyTooLarge},
+		{name: "Body too large after object", method: http.MethodPost, target: "/api/lru", body: `{"key":"a","value":1}` + strings.Repeat(" ", 64), expectedCode: http.StatusRequestEntityTooLarge, expectedProblem: problem.CodeBodyTooLarge},
+		{name: "Trailing data", method: http.MethodPost, target: "/api/lru", body: `{"key":"a","value":1}{}`, expectedCode: http.StatusBadRequest, expectedProblem: problem.CodeInvalidJSON},
+		{name: "Unknown field", method: http.MethodPost, target: "/api/lru", body: `{"key":"a","value":1,"ttl":5}`, expectedCode: http.StatusBadRequest, expectedProblem: problem.CodeValidationFailed, expectedFields: []string{"ttl"}},
+		{name: "Key too long", method: http.MethodPost, target: "/api/lru", body: `{"key":"abcde","value":1}`, expectedCode: http.StatusBadRequest, expectedProblem: problem.CodeValidationFailed, expectedFields: []string{"key"}},
+		{name: "Value too large", method: http.MethodPost, target: "/api/lru", body: `{"key":"a","value":"123456789"}`, expectedCode: http.StatusRequestEntityTooLarge, expectedProblem: problem.CodeValueTooLarge},
+		{name: "V2 key too long", method: http.MethodPut, target: "/api/v2/cache/entries/abcde", body: `{"value":1}`, expectedCode: http.StatusBadRequest, expectedProblem: problem.CodeValidationFailed, expectedFields: []string{"key"}},
+		{name: "V2 value too large", method: http.MethodPut, target: "/api/v2/cache/entries/a", body: `{"value":1234567890}`, expectedCode: http.StatusRequestEntityTooLarge, expectedProblem: problem.CodeValueTooLarge},
+		{name: "V2 unknown field", method: http.MethodPut, target: "/api/v2/cache/entries/a", body: `{"value":1,"key":"a"}`, expectedCode: http.StatusBadRequest, expectedProblem: problem.CodeValidationFailed, expectedFields: []string{"key"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.Router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.expectedCode, rec.Code, rec.Body.String())
+			if tt.expectedProblem == "" {
+				return
+			}
+
+			var p problem.Problem
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+			assert.Equal(t, tt.expectedProblem, p.Code)
+
+			var fields []string
+			for _, f := range p.Errors {
+				fields = append(fields, f.Field)
+			}
+			assert.ElementsMatch(t, tt.expectedFields, fields)
+		})
+	}
+}
+
+func TestCompression(t *testing.T) {
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger(),
+		handler.WithCompression(mw_compress.Config{MinSize: 64}))
+
+	value := strings.Repeat("v", 100)
+	for _, key := range []string{"a", "b"} {
+		rec := httptest.NewRecorder()
+		h.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v2/cache/entries/"+key, strings.NewReader(`{"value":"`+value+`"}`)))
+		require.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	get := func(target, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.Router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/api/lru", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+
+	zr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	var all models.GetLRU
+	require.NoError(t, json.NewDecoder(zr).Decode(&all))
+	assert.ElementsMatch(t, []string{"a", "b"}, all.Keys)
+
+	// ETag сжатого ответа слабый и подходит для условного запроса
+	rec = get("/api/v2/cache/entries/a", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	etag := rec.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, "W/"))
+
+	rec = get("/api/v2/cache/entries/a", etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+
+	// Маленькие ответы не сжимаются
+	rec = get("/healthz", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+}
+
+func TestNamedCaches(t *testing.T) {
+	defaultSpec := namespace.Spec{Capacity: 10, TTL: time.Minute}
+	caches := namespace.NewRegistry(defaultSpec)
+	_, err := caches.Create(namespace.Spec{Name: "sessions", Capacity: 2, TTL: time.Hour})
+	require.NoError(t, err)
+
+	h := handler.NewHandler(namespace.NewCache(defaultSpec), ":0", logger.NewDiscardLogger(), handler.WithCaches(caches))
+
+	// Шаги выполняются по порядку над одним сервером.
+	steps := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		expectedCode int
+		expectedBody string
+		expectedKeys []string
+	}{
+		{
+			name:         "List configured caches",
+			method:       http.MethodGet,
+			target:       "/api/caches",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"items":[
+				{"name":"default","default":true,"capacity":10,"ttl_seconds":60,"policy":"lru","size":0,"hits":0,"misses":0},
+				{"name":"sessions","capacity":2,"ttl_seconds":3600,"policy":"lru","size":0,"hits":0,"misses":0}
+			],"count":2}`,
+		},
+		{
+			name:         "Create cache",
+			method:       http.MethodPost,
+			target:       "/api/caches",
+			body:         `{"name":"thumbs","capacity":1,"policy":"fifo"}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"name":"thumbs","capacity":1,"ttl_seconds":60,"policy":"fifo","size":0,"hits":0,"misses":0}`,
+		},
+		{
+			name:         "Create duplicate cache",
+			method:       http.MethodPost,
+			target:       "/api/caches",
+			body:         `{"name":"thumbs","capacity":1}`,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Create default cache",
+			method:       http.MethodPost,
+			target:       "/api/caches",
+			body:         `{"name":"default","capacity":1}`,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Create cache with invalid name",
+			method:       http.MethodPost,
+			target:       "/api/caches",
+			body:         `{"name":"a/b","capacity":1}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Create cache with unknown policy",
+			method:       http.MethodPost,
+			target:       "/api/caches",
+			body:         `{"name":"other","capacity":1,"policy":"lfu"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Put to named cache",
+			method:       http.MethodPut,
+			target:       "/api/caches/thumbs/entries/a",
+			body:         `{"value":"thumb-a"}`,
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Put evicts from named cache by capacity",
+			method:       http.MethodPut,
+			target:       "/api/caches/thumbs/entries/b",
+			body:         `{"value":"thumb-b"}`,
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "List named cache entries",
+			method:       http.MethodGet,
+			target:       "/api/caches/thumbs/entries",
+			expectedCode: http.StatusOK,
+			expectedKeys: []string{"b"},
+		},
+		{
+			name:         "Named caches do not share keys",
+			method:       http.MethodGet,
+			target:       "/api/caches/sessions/entries/b",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Put to default cache by name",
+			method:       http.MethodPut,
+			target:       "/api/caches/default/entries/shared",
+			body:         `{"value":"default-value"}`,
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Default cache is served by /api/lru",
+			method:       http.MethodGet,
+			target:       "/api/lru/shared",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Get cache",
+			method:       http.MethodGet,
+			target:       "/api/caches/thumbs",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"name":"thumbs","capacity":1,"ttl_seconds":60,"policy":"fifo","size":1,"hits":0,"misses":0}`,
+		},
+		{
+			name:         "Get unknown cache",
+			method:       http.MethodGet,
+			target:       "/api/caches/missing",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Put to unknown cache",
+			method:       http.MethodPut,
+			target:       "/api/caches/missing/entries/a",
+			body:         `{"value":1}`,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Drop default cache",
+			method:       http.MethodDelete,
+			target:       "/api/caches/default",
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Drop cache",
+			method:       http.MethodDelete,
+			target:       "/api/caches/thumbs",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Get dropped cache entries",
+			method:       http.MethodGet,
+			target:       "/api/caches/thumbs/entries",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Drop unknown cache",
+			method:       http.MethodDelete,
+			target:       "/api/caches/thumbs",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.target, strings.NewReader(step.body))
+		rec := httptest.NewRecorder()
+
+		h.Router.ServeHTTP(rec, req)
+
+		require.Equal(t, step.expectedCode, rec.Code, step.name+": "+rec.Body.String())
+		if step.expectedBody != "" {
+			assert.JSONEq(t, step.expectedBody, rec.Body.String(), step.name)
+		}
+		if step.expectedKeys != nil {
+			assert.Equal(t, step.expectedKeys, entryKeys(t, rec.Body), step.name)
+		}
+		if rec.Code >= http.StatusBadRequest {
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"), step.name)
+		}
+	}
+}
+
+func TestTenants(t *testing.T) {
+	tenants := tenant.NewRegistry(time.Minute)
+	_, _, err := tenants.Set("team-a", tenant.Quota{MaxEntries: 2})
+	require.NoError(t, err)
+	_, _, err = tenants.Set("team-b", tenant.Quota{MaxEntries: 10, MaxBytes: 20})
+	require.NoError(t, err)
+
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger(),
+		handler.WithTenants(tenants, "X-Tenant-ID"),
+		handler.WithAuth(mw_auth.Config{
+			Authenticators: []mw_auth.Authenticator{mw_auth.NewAPIKeyAuthenticator(map[string]string{
+				"team-a": "team-a-key",
+				"team-b": "team-b-key",
+				"ops":    "ops-key",
+			})},
+			Scopes: map[string][]string{
+				"team-a": {mw_auth.ScopeWrite},
+				"team-b": {mw_auth.ScopeWrite},
+				"ops":    {mw_auth.ScopeAdmin},
+			},
+		}),
+	)
+
+	// Шаги выполняются по порядку над одним сервером.
+	steps := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		key          string
+		tenant       string
+		expectedCode int
+		expectedBody string
+		expectedKeys []string
+	}{
+		{name: "Tenant by API key", method: http.MethodPut, target: "/api/v2/cache/entries/k1", body: `{"value":"a1"}`, key: "team-a-key", expectedCode: http.StatusNoContent},
+		{name: "Tenant by API key 2", method: http.MethodPut, target: "/api/v2/cache/entries/k2", body: `{"value":"a2"}`, key: "team-a-key", expectedCode: http.StatusNoContent},
+		{name: "Tenant over entry quota", method: http.MethodPost, target: "/api/lru", body: `{"key":"k3","value":"a3"}`, key: "team-a-key", expectedCode: http.StatusCreated},
+		{name: "Default cache", method: http.MethodPut, target: "/api/v2/cache/entries/shared", body: `{"value":"s"}`, key: "ops-key", expectedCode: http.StatusNoContent},
+		{name: "Tenant header from non-tenant principal", method: http.MethodPut, target: "/api/v2/cache/entries/k1", body: `{"value":"0123456789"}`, key: "ops-key", tenant: "team-b", expectedCode: http.StatusForbidden},
+		{name: "Second tenant by API key", method: http.MethodPut, target: "/api/v2/cache/entries/k1", body: `{"value":"0123456789"}`, key: "team-b-key", expectedCode: http.StatusNoContent},
+		{name: "Tenant over byte quota", method: http.MethodPut, target: "/api/v2/cache/entries/big", body: `{"value":"0123456789012345678"}`, key: "team-b-key", expectedCode: http.StatusRequestEntityTooLarge},
+		{name: "Unknown tenant", method: http.MethodGet, target: "/api/v2/cache/entries", key: "ops-key", tenant: "team-x", expectedCode: http.StatusForbidden},
+		{
+			name:         "Tenant evicts only own entries",
+			method:       http.MethodGet,
+			target:       "/api/v2/cache/entries",
+			key:          "team-a-key",
+			expectedCode: http.StatusOK,
+			expectedKeys: []string{"k2", "k3"},
+		},
+		{name: "API key tenant ignores header", method: http.MethodGet, target: "/api/v2/cache/entries/k1", key: "team-a-key", tenant: "team-b", expectedCode: http.StatusNotFound},
+		{name: "Default cache is not shared with tenants", method: http.MethodGet, target: "/api/v2/cache/entries/shared", key: "team-a-key", expectedCode: http.StatusNotFound},
+		{name: "Default cache survives tenant pressure", method: http.MethodGet, target: "/api/v2/cache/entries/shared", key: "ops-key", expectedCode: http.StatusOK},
+		{name: "Default cache by name is tenant cache", method: http.MethodGet, target: "/api/caches/default/entries", key: "team-a-key", expectedCode: http.StatusOK, expectedKeys: []string{"k2", "k3"}},
+		{name: "Named cache is not available to tenant", method: http.MethodPut, target: "/api/caches/thumbs/entries/k", body: `{"value":"v"}`, key: "team-a-key", expectedCode: http.StatusForbidden},
+		{
+			name:         "List tenants",
+			method:       http.MethodGet,
+			target:       "/api/tenants",
+			key:          "ops-key",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"items":[
+				{"name":"team-a","max_entries":2,"max_bytes":0,"ttl_seconds":60,"size":2,"bytes":0,"hits":0,"misses":2,
+				 "evictions":{"capacity":1,"expired":0,"explicit":0,"flush":0}},
+				{"name":"team-b","max_entries":10,"max_bytes":20,"ttl_seconds":60,"size":1,"bytes":12,"hits":0,"misses":0,
+				 "evictions":{"capacity":0,"expired":0,"explicit":0,"flush":0}}
+			],"count":2}`,
+		},
+		{
+			name:         "Tenant sees only own stats",
+			method:       http.MethodGet,
+			target:       "/api/tenants",
+			key:          "team-b-key",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"items":[
+				{"name":"team-b","max_entries":10,"max_bytes":20,"ttl_seconds":60,"size":1,"bytes":12,"hits":0,"misses":0,
+				 "evictions":{"capacity":0,"expired":0,"explicit":0,"flush":0}}
+			],"count":1}`,
+		},
+		{name: "Tenant cannot get other tenant", method: http.MethodGet, target: "/api/tenants/team-a", key: "team-b-key", expectedCode: http.StatusNotFound},
+		{name: "Tenant gets own tenant", method: http.MethodGet, target: "/api/tenants/team-b", key: "team-b-key", expectedCode: http.StatusOK},
+		{name: "Set quota without admin scope", method: http.MethodPut, target: "/api/tenants/team-a", body: `{"max_entries":1}`, key: "team-a-key", expectedCode: http.StatusForbidden},
+		{name: "Set invalid quota", method: http.MethodPut, target: "/api/tenants/team-a", body: `{"max_entries":0}`, key: "ops-key", expectedCode: http.StatusBadRequest},
+		{
+			name:         "Shrink quota",
+			method:       http.MethodPut,
+			target:       "/api/tenants/team-a",
+			body:         `{"max_entries":1,"ttl_seconds":3600}`,
+			key:          "ops-key",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"name":"team-a","max_entries":1,"max_bytes":0,"ttl_seconds":3600,"size":1,"bytes":0,"hits":0,"misses":2,
+				"evictions":{"capacity":2,"expired":0,"explicit":0,"flush":0}}`,
+		},
+		{name: "Create tenant", method: http.MethodPut, target: "/api/tenants/team-c", body: `{"max_entries":5}`, key: "ops-key", expectedCode: http.StatusCreated},
+		{name: "Get tenant", method: http.MethodGet, target: "/api/tenants/team-c", key: "ops-key", expectedCode: http.StatusOK},
+		{name: "Get unknown tenant", method: http.MethodGet, target: "/api/tenants/team-x", key: "ops-key", expectedCode: http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.target, strings.NewReader(step.body))
+		req.Header.Set(mw_auth.APIKeyHeader, step.key)
+		if step.tenant != "" {
+			req.Header.Set("X-Tenant-ID", step.tenant)
+		}
+		rec := httptest.NewRecorder()
+
+		h.Router.ServeHTTP(rec, req)
+
+		require.Equal(t, step.expectedCode, rec.Code, step.name+": "+rec.Body.String())
+		if step.expectedBody != "" {
+			assert.JSONEq(t, step.expectedBody, rec.Body.String(), step.name)
+		}
+		if step.expectedKeys != nil {
+			assert.Equal(t, step.expectedKeys, entryKeys(t, rec.Body), step.name)
+		}
+		if step.expectedCode == http.StatusRequestEntityTooLarge {
+			var p problem.Problem
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+			assert.Equal(t, problem.CodeQuotaExceeded, p.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set(mw_auth.APIKeyHeader, "ops-key")
+	rec := httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, req)
+
+	assert.Contains(t, rec.Body.String(), `lru_cache_tenant_size{tenant="team-a"} 1`)
+	assert.Contains(t, rec.Body.String(), `lru_cache_tenant_bytes{tenant="team-b"} 12`)
+	assert.Contains(t, rec.Body.String(), `lru_cache_tenant_evictions_total{tenant="team-a",reason="capacity"} 2`)
+}
+
+func TestTenants_HeaderWithoutAuth(t *testing.T) {
+	tenants := tenant.NewRegistry(time.Minute)
+	_, _, err := tenants.Set("team-a", tenant.Quota{MaxEntries: 10})
+	require.NoError(t, err)
+
+	cache := lru.NewLRUCache(10, time.Minute)
+	h := handler.NewHandler(cache, ":0", logger.NewDiscardLogger(), handler.WithTenants(tenants, "X-Tenant-ID"))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v2/cache/entries/k", strings.NewReader(`{"value":"v"}`))
+	req.Header.Set("X-Tenant-ID", "team-a")
+	rec := httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	team, _ := tenants.Get("team-a")
+	assert.Equal(t, 1, team.Cache.Stats().Size)
+	assert.Equal(t, 0, cache.Stats().Size)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v2/cache/entries", nil)
+	req.Header.Set("X-Tenant-ID", "team-x")
+	rec = httptest.NewRecorder()
+	h.Router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+// clusterNode - узел кластера, запущенный в httptest-сервере.
+type clusterNode struct {
+	srv      *httptest.Server
+	cache    *lru.Cache
+	requests atomic.Int64 // Количество запросов, полученных узлом.
+}
+
+// newCluster запускает n узлов кластера с общим секретом secret в одном процессе. Адреса серверов известны
+// только после запуска, поэтому обработчики создаются после серверов.
+func newCluster(t *testing.T, n int, secret string, opts ...handler.Option) ([]*clusterNode, []string) {
+	t.Helper()
+
+	nodes := make([]*clusterNode, n)
+	handlers := make([]http.Handler, n)
+	peers := make([]string, n)
+	for i := range nodes {
+		i := i
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nodes[i].requests.Add(1)
+			handlers[i].ServeHTTP(w, r)
+		}))
+		t.Cleanup(srv.Close)
+
+		nodes[i] = &clusterNode{srv: srv, cache: lru.NewLRUCache(100, time.Minute)}
+		peers[i] = srv.URL
+	}
+
+	for i, node := range nodes {
+		c, err := cluster.New(node.srv.URL, peers, logger.NewDiscardLogger(), cluster.WithSecret(secret))
+		require.NoError(t, err)
+
+		handlers[i] = handler.NewHandler(node.cache, ":0", logger.NewDiscardLogger(), append(opts, handler.WithCluster(c))...).Router
+	}
+
+	return nodes, peers
+}
+
+func TestClusterForwarding(t *testing.T) {
+	ctx := context.Background()
+	nodes, peers := newCluster(t, 3, "cluster-secret")
+	ring := cluster.NewRing(0, peers...)
+
+	do := func(method, url, body string) *http.Response {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+
+		return resp
+	}
+
+	// Запись через любой узел попадает к владельцу ключа.
+	keys := make([]string, 30)
+	for i := range keys {
+		keys[i] = "key/" + strconv.Itoa(i)
+		entry := nodes[i%3].srv.URL + "/api/v2/cache/entries/" + url.PathEscape(keys[i])
+
+		resp := do(http.MethodPut, entry, `{"value":`+strconv.Itoa(i)+`}`)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, ring.Get(keys[i]), resp.Header.Get(cluster.OwnerHeader))
+	}
+
+	// API v1 с ключом в теле тоже пересылается.
+	resp := do(http.MethodPost, nodes[0].srv.URL+"/api/lru", `{"key":"v1-key","value":"v1"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, ring.Get("v1-key"), resp.Header.Get(cluster.OwnerHeader))
+	keys = append(keys, "v1-key")
+
+	// Каждый узел хранит только свои ключи.
+	total := 0
+	for _, node := range nodes {
+		local, _, err := node.cache.GetAll(ctx)
+		if err != nil {
+			require.ErrorIs(t, err, lru.ErrCacheIsEmpty)
+		}
+		for _, key := range local {
+			assert.Equal(t, node.srv.URL, ring.Get(key), key)
+		}
+		total += len(local)
+	}
+	assert.Equal(t, len(keys), total)
+
+	// Заголовок пересылки от клиента без подписи не мешает пересылке владельцу.
+	for i, key := range keys[:30] {
+		if owner := ring.Get(key); owner != nodes[0].srv.URL {
+			req, err := http.NewRequest(http.MethodGet, nodes[0].srv.URL+"/api/v2/cache/entries/"+url.PathEscape(key), nil)
+			require.NoError(t, err)
+			req.Header.Set(cluster.ForwardedHeader, peers[(i+1)%3])
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode, key)
+			assert.Equal(t, owner, resp.Header.Get(cluster.OwnerHeader), key)
+			break
+		}
+	}
+
+	// Чтение и удаление через любой узел.
+	for i, key := range keys[:30] {
+		resp := do(http.MethodGet, nodes[(i+1)%3].srv.URL+"/api/v2/cache/entries/"+url.PathEscape(key), "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, key)
+
+		var entry models.Entry
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&entry))
+		assert.Equal(t, key, entry.Key)
+		assert.Equal(t, float64(i), entry.Value)
+	}
+
+	resp = do(http.MethodDelete, nodes[1].srv.URL+"/api/lru/v1-key", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = do(http.MethodGet, nodes[2].srv.URL+"/api/v2/cache/entries/v1-key", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Списки объединяют элементы всех узлов, очистка выполняется на всех узлах.
+	resp = do(http.MethodGet, nodes[1].srv.URL+"/api/v2/cache/entries", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.ElementsMatch(t, keys[:30], entryKeys(t, resp.Body))
+
+	resp = do(http.MethodGet, nodes[2].srv.URL+"/api/lru", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var all models.GetLRU
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&all))
+	assert.ElementsMatch(t, keys[:30], all.Keys)
+
+	resp = do(http.MethodDelete, nodes[0].srv.URL+"/api/v2/cache/entries", "")
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	for _, node := range nodes {
+		assert.Zero(t, node.cache.Stats().Size, node.srv.URL)
+	}
+
+	// Ключ недоступного владельца возвращает 502.
+	down := nodes[2]
+	down.srv.Close()
+
+	var key string
+	for i := 0; key == ""; i++ {
+		if k := "down-" + strconv.Itoa(i); ring.Get(k) == down.srv.URL {
+			key = k
+		}
+	}
+	resp = do(http.MethodGet, nodes[0].srv.URL+"/api/v2/cache/entries/"+key, "")
+	require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+
+	var p problem.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+	assert.Equal(t, problem.CodePeerUnavailable, p.Code)
+
+	// Список не собирается, если узел недоступен.
+	resp = do(http.MethodGet, nodes[0].srv.URL+"/api/v2/cache/entries", "")
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
+
+func TestClusterFanOutWithoutSecret(t *testing.T) {
+	ctx := context.Background()
+	nodes, _ := newCluster(t, 3, "")
+
+	var keys []string
+	for i, node := range nodes {
+		key := "node-" + strconv.Itoa(i)
+		require.NoError(t, node.cache.Put(ctx, key, "v", 0))
+		keys = append(keys, key)
+	}
+
+	// Узлы выполняют разосланную копию локально и не рассылают ее снова, хотя она не подписана.
+	resp, err := http.Get(nodes[0].srv.URL + "/api/v2/cache/entries")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.ElementsMatch(t, keys, entryKeys(t, resp.Body))
+	for _, node := range nodes {
+		assert.EqualValues(t, 1, node.requests.Load(), node.srv.URL)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, nodes[1].srv.URL+"/api/lru", nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	for _, node := range nodes {
+		assert.EqualValues(t, 2, node.requests.Load(), node.srv.URL)
+		assert.Zero(t, node.cache.Stats().Size, node.srv.URL)
+	}
+}
+
+func TestClusterRateLimitForwarded(t *testing.T) {
+	nodes, peers := newCluster(t, 2, "cluster-secret",
+		handler.WithRateLimit(mw_ratelimit.Config{Default: mw_ratelimit.Limit{Rate: 0.01, Burst: 2}}))
+	ring := cluster.NewRing(0, peers...)
+
+	var remote []string
+	for i := 0; len(remote) < 2; i++ {
+		if k := "key-" + strconv.Itoa(i); ring.Get(k) == nodes[1].srv.URL {
+			remote = append(remote, k)
+		}
+	}
+
+	get := func(node *clusterNode, key string) int {
+		resp, err := http.Get(node.srv.URL + "/api/v2/cache/entries/" + key)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Запросы, пересланные узлом 0, списываются только из бюджета клиента на узле 0.
+	for _, key := range remote {
+		assert.Equal(t, http.StatusNotFound, get(nodes[0], key))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, get(nodes[0], remote[0]))
+
+	// Бюджет того же клиента на узле-владельце не израсходован пересланными запросами.
+	assert.Equal(t, http.StatusNotFound, get(nodes[1], remote[0]))
+}
+
+// connAuthenticator аутентифицирует клиента по заголовку X-Test-User, только если запрос пришел напрямую
+// от клиента: как и сертификат клиента, такие учетные данные не доходят до узла-владельца при пересылке.
+type connAuthenticator struct{}
+
+func (connAuthenticator) Authenticate(r *http.Request) (*mw_auth.Principal, error) {
+	user := r.Header.Get("X-Test-User")
+	if user == "" || r.Header.Get(cluster.ForwardedHeader) != "" {
+		return nil, mw_auth.ErrNoCredentials
+	}
+	return &mw_auth.Principal{Name: user, Method: mw_auth.MethodClientCert}, nil
+}
+
+func TestClusterForwardsPrincipal(t *testing.T) {
+	nodes, peers := newCluster(t, 2, "cluster-secret", handler.WithAuth(mw_auth.Config{
+		Authenticators: []mw_auth.Authenticator{connAuthenticator{}},
+		Scopes:         map[string][]string{"writer": {mw_auth.ScopeWrite}, "reader": {mw_auth.ScopeRead}},
+	}))
+	ring := cluster.NewRing(0, peers...)
+
+	var key string
+	for i := 0; key == ""; i++ {
+		if k := "key-" + strconv.Itoa(i); ring.Get(k) == nodes[1].srv.URL {
+			key = k
+		}
+	}
+
+	do := func(method, user string) *http.Response {
+		req, err := http.NewRequest(method, nodes[0].srv.URL+"/api/v2/cache/entries/"+key, strings.NewReader(`{"value":"v"}`))
+		require.NoError(t, err)
+		req.Header.Set("X-Test-User", user)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		return resp
+	}
+
+	// Владелец не видит учетных данных клиента и принимает субъекта, которого аутентифицировал узел, получивший запрос.
+	resp := do(http.MethodPut, "reader")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = do(http.MethodPut, "writer")
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, nodes[1].srv.URL, resp.Header.Get(cluster.OwnerHeader))
+
+	_, _, err := nodes[1].cache.Get(context.Background(), key)
+	assert.NoError(t, err)
+}
+
+func TestReplication(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	primaryCache, replicaCache := lru.NewLRUCache(100, time.Minute), lru.NewLRUCache(100, time.Minute)
+
+	primary := replication.NewPrimary(primaryCache, logger.NewDiscardLogger(), replication.WithHeartbeat(20*time.Millisecond))
+	go primary.Run(ctx)
+	primarySrv := httptest.NewServer(handler.NewHandler(primaryCache, ":0", logger.NewDiscardLogger(),
+		handler.WithReplicationPrimary(primary)).Router)
+	defer primarySrv.Close()
+	defer primary.Close()
+
+	caches := namespace.NewRegistry(namespace.Spec{Capacity: 10, TTL: time.Minute})
+	_, err := caches.Create(namespace.Spec{Name: "sessions", Capacity: 2, TTL: time.Hour})
+	require.NoError(t, err)
+	tenants := tenant.NewRegistry(time.Minute)
+	_, _, err = tenants.Set("team-a", tenant.Quota{MaxEntries: 10})
+	require.NoError(t, err)
+
+	replica, err := replication.NewReplica(primarySrv.URL, replicaCache, logger.NewDiscardLogger())
+	require.NoError(t, err)
+	go replica.Run(ctx)
+	replicaSrv := httptest.NewServer(handler.NewHandler(replicaCache, ":0", logger.NewDiscardLogger(),
+		handler.WithReplica(replica), handler.WithCaches(caches), handler.WithTenants(tenants, "X-Tenant-ID")).Router)
+	defer replicaSrv.Close()
+
+	do := func(client *http.Client, method, url, body string) *http.Response {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+
+		return resp
+	}
+	noRedirect := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	// Запись на реплике перенаправляется ведущему узлу с сохранением метода и тела.
+	resp := do(noRedirect, http.MethodPut, replicaSrv.URL+"/api/v2/cache/entries/k?ttl=60", `{"value":"v"}`)
+	require.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	assert.Equal(t, primarySrv.URL+"/api/v2/cache/entries/k?ttl=60", resp.Header.Get("Location"))
+
+	resp = do(http.DefaultClient, http.MethodPut, replicaSrv.URL+"/api/v2/cache/entries/k", `{"value":"v"}`)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = do(noRedirect, http.MethodDelete, replicaSrv.URL+"/api/lru", "")
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	// Изменение доходит до реплики и читается с нее.
+	require.Eventually(t, func() bool {
+		_, _, err := replicaCache.Get(ctx, "k")
+		return err == nil
+	}, 2*time.Second, 5*time.Millisecond)
+
+	resp = do(noRedirect, http.MethodGet, replicaSrv.URL+"/api/v2/cache/entries/k", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var entry models.Entry
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&entry))
+	assert.Equal(t, "v", entry.Value)
+
+	// Состояние репликации и отставание реплики.
+	status := func(srv *httptest.Server) replication.Status {
+		resp := do(http.DefaultClient, http.MethodGet, srv.URL+"/api/replication", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var s replication.Status
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&s))
+		return s
+	}
+
+	primaryStatus := status(primarySrv)
+	assert.Equal(t, replication.RolePrimary, primaryStatus.Role)
+	assert.Len(t, primaryStatus.Replicas, 1)
+
+	replicaStatus := status(replicaSrv)
+	assert.Equal(t, replication.RoleReplica, replicaStatus.Role)
+	assert.Equal(t, primarySrv.URL, replicaStatus.Primary)
+	assert.True(t, replicaStatus.Connected)
+	assert.Equal(t, primaryStatus.Offset, replicaStatus.Offset)
+	assert.Zero(t, replicaStatus.LagEntries)
+
+	resp = do(http.DefaultClient, http.MethodGet, replicaSrv.URL+"/metrics", "")
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "lru_replication_lag_seconds 0\n")
+	assert.Contains(t, string(body), "lru_replication_connected 1\n")
+
+	// Поток репликации доступен только на ведущем узле.
+	resp = do(http.DefaultClient, http.MethodGet, replicaSrv.URL+"/api/replication/stream", "")
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	var p problem.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+	assert.Equal(t, problem.CodeConflict, p.Code)
+
+	// Именованные кэши и кэши арендаторов не реплицируются: чтение на реплике отклоняется, запись перенаправляется.
+	for _, path := range []string{"/api/caches/sessions", "/api/caches/sessions/entries", "/api/caches/sessions/entries/k", "/api/tenants", "/api/tenants/team-a"} {
+		resp = do(noRedirect, http.MethodGet, replicaSrv.URL+path, "")
+		assert.Equal(t, http.StatusConflict, resp.StatusCode, path)
+	}
+
+	resp = do(noRedirect, http.MethodPut, replicaSrv.URL+"/api/caches/sessions/entries/k", `{"value":"v"}`)
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	resp = do(noRedirect, http.MethodGet, replicaSrv.URL+"/api/caches/default/entries/k", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = do(noRedirect, http.MethodGet, replicaSrv.URL+"/api/caches", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list models.CacheList
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, namespace.DefaultName, list.Items[0].Name)
+
+	req, err := http.NewRequest(http.MethodGet, replicaSrv.URL+"/api/v2/cache/entries/k", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Tenant-ID", "team-a")
+	resp, err = noRedirect.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestReplicationReadiness(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	primaryCache := lru.NewLRUCache(100, time.Minute)
+	require.NoError(t, primaryCache.Put(ctx, "k", "v", 0))
+
+	primary := replication.NewPrimary(primaryCache, logger.NewDiscardLogger(), replication.WithHeartbeat(20*time.Millisecond))
+	go primary.Run(ctx)
+	primarySrv := httptest.NewServer(primary)
+	defer primarySrv.Close()
+
+	replica, err := replication.NewReplica(primarySrv.URL, lru.NewLRUCache(100, time.Minute), logger.NewDiscardLogger(),
+		replication.WithReadTimeout(200*time.Millisecond))
+	require.NoError(t, err)
+	h := handler.NewHandler(lru.NewLRUCache(100, time.Minute), ":0", logger.NewDiscardLogger(), handler.WithReplica(replica))
+
+	readyz := func() (int, health.Status) {
+		rec := httptest.NewRecorder()
+		h.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var report health.Report
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+		return rec.Code, report.Components["replication"].Status
+	}
+
+	// Пока снимок ведущего узла не загружен, реплика не готова.
+	code, status := readyz()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusStarting, status)
+
+	go replica.Run(ctx)
+	require.Eventually(t, func() bool {
+		code, status := readyz()
+		return code == http.StatusOK && status == health.StatusUp
+	}, 2*time.Second, 5*time.Millisecond)
+
+	// Реплика, потерявшая связь с ведущим узлом, тоже не готова.
+	primary.Close()
+	primarySrv.Close()
+	require.Eventually(t, func() bool {
+		code, status := readyz()
+		return code == http.StatusServiceUnavailable && status == health.StatusDown
+	}, 2*time.Second, 5*time.Millisecond)
+}
+
+// entryKeys возвращает ключи списка элементов API v2 и проверяет, что у каждого элемента есть время истечения.
+func entryKeys(t *testing.T, body io.Reader) []string {
+	t.Helper()
+
+	var list models.EntryList
+	require.NoError(t, json.NewDecoder(body).Decode(&list))
+
+	keys := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		keys = append(keys, item.Key)
+		assert.NotNil(t, item.ExpiresAt, item.Key)
+	}
+	return keys
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"github.com/instinctG/lru-cache/internal/health"
+	"github.com/instinctG/lru-cache/internal/replication"
+	"net/http"
+)
+
+// Healthz обрабатывает проверку живости: процесс запущен и обрабатывает запросы.
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	jsonRespond(w, r, http.StatusOK, health.Report{Status: health.StatusUp})
+}
+
+// Readyz обрабатывает проверку готовности принимать трафик.
+// Возвращает 503, пока какой-либо компонент запускается или не работает, а также после получения сигнала завершения.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	report, ready := h.Health.Readiness(r.Context())
+	if !ready {
+		jsonRespond(w, r, http.StatusServiceUnavailable, report)
+		return
+	}
+
+	jsonRespond(w, r, http.StatusOK, report)
+}
+
+// cacheHealthCheck возвращает проверку состояния кэша с его размером и емкостью, если кэш ведет статистику.
+func cacheHealthCheck(cache ILRUCache) health.CheckFunc {
+	return func(context.Context) health.Component {
+		component := health.Component{Status: health.StatusUp}
+
+		if sp, ok := cache.(statsProvider); ok {
+			stats := sp.Stats()
+			component.Details = map[string]interface{}{
+				"size":     stats.Size,
+				"capacity": stats.Capacity,
+			}
+		}
+
+		return component
+	}
+}
+
+// replicationHealthCheck возвращает проверку состояния реплики: starting, пока не загружен снимок ведущего узла,
+// и down, пока реплика не подключена к ведущему узлу. Так балансировщик не направляет чтение
+// на пустую или отставшую реплику.
+func replicationHealthCheck(replica *replication.Replica) health.CheckFunc {
+	return func(context.Context) health.Component {
+		status := replica.Status()
+		component := health.Component{
+			Status: health.StatusUp,
+			Details: map[string]interface{}{
+				"primary":     status.Primary,
+				"offset":      status.Offset,
+				"lag_entries": status.LagEntries,
+			},
+		}
+
+		switch {
+		case !status.Synced:
+			component.Status = health.StatusStarting
+		case !status.Connected:
+			component.Status = health.StatusDown
+		}
+
+		return component
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Limits задает ограничения на размер запросов на запись и строгость разбора JSON.
+type Limits struct {
+	MaxBodyBytes          int64 // Максимальный размер тела запроса в байтах, 0 - без ограничения.
+	MaxKeyLength          int   // Максимальная длина ключа в байтах, 0 - без ограничения.
+	MaxValueSize          int   // Максимальный размер значения в байтах (длина строки или JSON-представления), 0 - без ограничения.
+	DisallowUnknownFields bool  // Отклонять тела запросов с полями, которых нет в модели запроса.
+}
+
+// DefaultLimits - ограничения, которые используются, если не задан WithLimits.
+var DefaultLimits = Limits{
+	MaxBodyBytes: 1 << 20,
+	MaxKeyLength: 250,
+	MaxValueSize: 512 << 10,
+}
+
+// errTrailingData возвращается, если после JSON-объекта в теле запроса есть другие данные.
+var errTrailingData = errors.New("request body must contain a single JSON object")
+
+// decodeJSON декодирует тело запроса в v с учетом ограничений h.limits.
+// Тело, превышающее MaxBodyBytes, не дочитывается: возвращается *http.MaxBytesError.
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	body := r.Body
+	if h.limits.MaxBodyBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, h.limits.MaxBodyBytes)
+	}
+
+	dec := json.NewDecoder(body)
+	if h.limits.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+
+	// Данные после JSON-объекта не отбрасываются молча, а считаются ошибкой.
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return err
+		}
+		return errTrailingData
+	}
+
+	return nil
+}
+
+// checkEntryLimits проверяет длину ключа и размер значения элемента.
+// Возвращает: ответ об ошибке и false, если элемент превышает ограничения.
+func (h *Handler) checkEntryLimits(key string, value interface{}) (problem.Problem, bool) {
+	if h.limits.MaxKeyLength > 0 && len(key) > h.limits.MaxKeyLength {
+		msg := fmt.Sprintf("key must be at most %d bytes", h.limits.MaxKeyLength)
+
+		p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, msg)
+		p.Errors = []problem.FieldError{{Field: "key", Rule: "max", Message: msg}}
+
+		return p, false
+	}
+
+	if h.limits.MaxValueSize > 0 {
+		if size := valueSize(value); size > h.limits.MaxValueSize {
+			return problem.New(http.StatusRequestEntityTooLarge, problem.CodeValueTooLarge,
+				fmt.Sprintf("value is %d bytes, the limit is %d bytes", size, h.limits.MaxValueSize)), false
+		}
+	}
+
+	return problem.Problem{}, true
+}
+
+// valueSize возвращает размер значения: длину строки или длину JSON-представления для остальных типов.
+func valueSize(value interface{}) int {
+	if s, ok := value.(string); ok {
+		return len(s)
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// unknownFieldProblem формирует ошибку валидации для поля, которого нет в модели запроса.
+// Возвращает: ответ об ошибке и false, если err не является ошибкой неизвестного поля.
+func unknownFieldProblem(err error) (problem.Problem, bool) {
+	field, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return problem.Problem{}, false
+	}
+	field = strings.Trim(field, `"`)
+
+	msg := "field " + field + " is not allowed"
+
+	p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, msg)
+	p.Errors = []problem.FieldError{{Field: field, Rule: "unknown", Message: msg}}
+
+	return p, true
+}
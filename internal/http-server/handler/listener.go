@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// unixScheme - префикс адреса сервера, который задает путь к unix-сокету, например "unix:///run/lru-cache.sock".
+const unixScheme = "unix://"
+
+// Listen открывает сокет для адреса основного сервера.
+// Адрес вида "unix:///path.sock" открывает unix-сокет с правами, заданными WithUnixSocketMode,
+// остальные адреса открывают TCP-сокет.
+// Возвращает: открытый сокет и ошибку, если сокет не удалось открыть.
+func (h *Handler) Listen() (net.Listener, error) {
+	path, ok := strings.CutPrefix(h.Server.Addr, unixScheme)
+	if !ok {
+		return net.Listen("tcp", h.Server.Addr)
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.unixSocketMode != 0 {
+		if err = os.Chmod(path, h.unixSocketMode); err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("chmod unix socket: %w", err)
+		}
+	}
+
+	return ln, nil
+}
+
+// removeStaleSocket удаляет файл unix-сокета, оставшийся после аварийного завершения процесса.
+// Сокет, к которому удается подключиться, не удаляется: его использует другой процесс.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a unix socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("unix socket %s is in use", path)
+	}
+
+	return os.Remove(path)
+}
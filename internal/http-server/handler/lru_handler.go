@@ -3,9 +3,9 @@ package handler
 import (
 	"context"
 	"errors"
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/render"
+	"fmt"
 	"github.com/go-playground/validator/v10"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
 	sl "github.com/instinctG/lru-cache/internal/logger"
 	"github.com/instinctG/lru-cache/internal/lru"
 	"github.com/instinctG/lru-cache/internal/models"
@@ -34,45 +34,52 @@ func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
 	var req models.PutRequest
 
 	// Декодируем тело запроса.
-	err := render.DecodeJSON(r.Body, &req)
-	if errors.Is(err, io.EOF) {
+	if err := h.decodeJSON(w, r, &req); err != nil {
 
-		h.Log.Debug("request body is empty")
+		h.Log.Debug("failed to decode request body", sl.Err(err))
 
-		render.JSON(w, r, Response{Error: err.Error()})
+		problem.Write(w, r, decodeErrorProblem(err))
 
 		return
 	}
 
 	h.Log.Debug("request body decoded", slog.Any("request", req))
 
-	if !isSimpleType(req.Value) {
-		h.Log.Debug("value must be a simple type")
+	// Проверяем валидность данных.
+	if err := validate.Struct(req); err != nil {
+
+		validateErr := err.(validator.ValidationErrors)
+
+		h.Log.Debug("invalid request", sl.Err(validateErr))
 
-		jsonRespond(w, r, http.StatusBadRequest, Response{Error: "field value is invalid"})
+		problem.Write(w, r, ValidationError(validateErr))
 
 		return
 	}
 
-	// Проверяем валидность данных.
-	if err = validator.New().Struct(req); err != nil {
+	if !isSimpleType(req.Value) {
+		h.Log.Debug("value must be a simple type")
 
-		validateErr := err.(validator.ValidationErrors)
+		problem.Write(w, r, valueTypeProblem())
 
-		h.Log.Debug("invalid request", sl.Err(validateErr))
+		return
+	}
+
+	if p, ok := h.checkEntryLimits(req.Key, req.Value); !ok {
+		h.Log.Debug("entry exceeds limits", slog.String("detail", p.Detail))
 
-		jsonRespond(w, r, http.StatusBadRequest, ValidationError(validateErr))
+		problem.Write(w, r, p)
 
 		return
 	}
 
 	// Добавляем элемент в кэш.
-	err = h.LRU.Put(r.Context(), req.Key, req.Value, time.Duration(req.TTLSeconds)*time.Second)
+	err := h.cache(r).Put(r.Context(), req.Key, req.Value, time.Duration(req.TTLSeconds)*time.Second)
 	if err != nil {
 
 		h.Log.Debug("failed to put lru cache", sl.Err(err))
 
-		jsonRespond(w, r, http.StatusInternalServerError, Response{Error: err.Error()})
+		problem.Write(w, r, cacheErrorProblem(err))
 
 		return
 	}
@@ -83,23 +90,24 @@ func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get обрабатывает запрос на получение элемента из кэша.
+// Ответ содержит заголовки ETag, Cache-Control и Expires; при совпадении If-None-Match возвращается 304 Not Modified.
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
-	key := chi.URLParam(r, "key")
+	key := keyParam(r)
 	if key == "" {
 
 		h.Log.Debug("key is empty")
 
-		jsonRespond(w, r, http.StatusBadRequest, Response{Error: "key is empty"})
+		problem.Write(w, r, emptyKeyProblem())
 
 		return
 	}
 
-	val, exp, err := h.LRU.Get(r.Context(), key)
-	if errors.Is(err, lru.ErrKeyNotFound) {
+	val, exp, err := h.cache(r).Get(r.Context(), key)
+	if err != nil {
 
-		h.Log.Debug("key not found", sl.Err(err))
+		h.Log.Debug("failed to get lru cache", sl.Err(err))
 
-		jsonRespond(w, r, http.StatusNotFound, Response{Error: "key not found"})
+		problem.Write(w, r, cacheErrorProblem(err))
 
 		return
 	}
@@ -110,17 +118,48 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		ExpiresAt: exp.Unix(),
 	}
 
-	jsonRespond(w, r, http.StatusOK, resp)
+	h.respondCacheable(w, r, resp, exp)
+}
+
+// respondCacheable отправляет ответ с заголовками кэширования.
+// Если ETag совпадает с If-None-Match, возвращается 304 Not Modified без тела.
+func (h *Handler) respondCacheable(w http.ResponseWriter, r *http.Request, data interface{}, expiresAt time.Time) {
+	etag, err := computeETag(data)
+	if err != nil {
+
+		h.Log.Debug("failed to compute etag", sl.Err(err))
+
+		problem.Write(w, r, problem.New(http.StatusInternalServerError, problem.CodeInternal, err.Error()))
+
+		return
+	}
+
+	setCacheHeaders(w, etag, expiresAt)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+
+		h.Log.Debug("value is not modified", slog.String("etag", etag))
+
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	jsonRespond(w, r, http.StatusOK, data)
 }
 
 // GetAll обрабатывает запрос на получение всех элементов из кэша.
+// Для пустого кэша возвращаются пустые списки ключей и значений.
 func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
-	keys, vals, err := h.LRU.GetAll(r.Context())
+	keys, vals, err := h.cache(r).GetAll(r.Context())
+	if errors.Is(err, lru.ErrCacheIsEmpty) {
+		keys, vals, err = []string{}, []interface{}{}, nil
+	}
 	if err != nil {
 
 		h.Log.Debug("failed to get lru cache", sl.Err(err))
 
-		jsonRespond(w, r, http.StatusNoContent, nil)
+		problem.Write(w, r, cacheErrorProblem(err))
 
 		return
 	}
@@ -135,22 +174,22 @@ func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
 
 // Evict обрабатывает запрос на удаление элемента из кэша по ключу.
 func (h *Handler) Evict(w http.ResponseWriter, r *http.Request) {
-	key := chi.URLParam(r, "key")
+	key := keyParam(r)
 	if key == "" {
 
 		h.Log.Debug("key is empty")
 
-		jsonRespond(w, r, http.StatusBadRequest, Response{Error: "key is empty"})
+		problem.Write(w, r, emptyKeyProblem())
 
 		return
 	}
 
-	_, err := h.LRU.Evict(r.Context(), key)
-	if errors.Is(err, lru.ErrKeyNotFound) {
+	_, err := h.cache(r).Evict(r.Context(), key)
+	if err != nil {
 
-		h.Log.Debug("key not found", sl.Err(err))
+		h.Log.Debug("failed to evict lru cache", sl.Err(err))
 
-		jsonRespond(w, r, http.StatusNotFound, Response{Error: "key not found"})
+		problem.Write(w, r, cacheErrorProblem(err))
 
 		return
 	}
@@ -163,11 +202,11 @@ func (h *Handler) Evict(w http.ResponseWriter, r *http.Request) {
 // EvictAll обрабатывает запрос на удаление всех элементов из кэша.
 func (h *Handler) EvictAll(w http.ResponseWriter, r *http.Request) {
 
-	if err := h.LRU.EvictAll(r.Context()); err != nil {
+	if err := h.cache(r).EvictAll(r.Context()); err != nil {
 
 		h.Log.Debug("failed to evict lru cache", sl.Err(err))
 
-		jsonRespond(w, r, http.StatusInternalServerError, Response{Error: err.Error()})
+		problem.Write(w, r, cacheErrorProblem(err))
 
 		return
 	}
@@ -176,3 +215,50 @@ func (h *Handler) EvictAll(w http.ResponseWriter, r *http.Request) {
 
 	jsonRespond(w, r, http.StatusNoContent, nil)
 }
+
+// emptyKeyProblem формирует ошибку валидации для пустого ключа в пути запроса.
+func emptyKeyProblem() problem.Problem {
+	p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "key is empty")
+	p.Errors = []problem.FieldError{{Field: "key", Rule: "required", Message: "key is empty"}}
+
+	return p
+}
+
+// decodeErrorProblem сопоставляет ошибку декодирования тела запроса с ответом об ошибке.
+func decodeErrorProblem(err error) problem.Problem {
+	var maxBytesErr *http.MaxBytesError
+
+	if p, ok := unknownFieldProblem(err); ok {
+		return p
+	}
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return problem.New(http.StatusBadRequest, problem.CodeEmptyBody, "request body is empty")
+	case errors.As(err, &maxBytesErr):
+		return problem.New(http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge,
+			fmt.Sprintf("request body must be at most %d bytes", maxBytesErr.Limit))
+	default:
+		return problem.New(http.StatusBadRequest, problem.CodeInvalidJSON, err.Error())
+	}
+}
+
+// valueTypeProblem формирует ошибку валидации для значения неподдерживаемого типа.
+func valueTypeProblem() problem.Problem {
+	p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "field value is invalid")
+	p.Errors = []problem.FieldError{{Field: "value", Rule: "type", Message: "value must be a string, number or boolean"}}
+
+	return p
+}
+
+// cacheErrorProblem сопоставляет ошибку кэша с ответом об ошибке.
+func cacheErrorProblem(err error) problem.Problem {
+	switch {
+	case errors.Is(err, lru.ErrKeyNotFound):
+		return problem.New(http.StatusNotFound, problem.CodeKeyNotFound, "key not found")
+	case errors.Is(err, lru.ErrEntryTooLarge):
+		return problem.New(http.StatusRequestEntityTooLarge, problem.CodeQuotaExceeded, err.Error())
+	default:
+		return problem.New(http.StatusInternalServerError, problem.CodeInternal, err.Error())
+	}
+}
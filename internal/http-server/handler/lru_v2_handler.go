@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"github.com/instinctG/lru-cache/internal/models"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PutEntry обрабатывает запрос API v2 на запись элемента по ключу из пути запроса.
+func (h *Handler) PutEntry(w http.ResponseWriter, r *http.Request) {
+	key := keyParam(r)
+	if key == "" {
+
+		h.Log.Debug("key is empty")
+
+		problem.Write(w, r, emptyKeyProblem())
+
+		return
+	}
+
+	var req models.PutEntryRequest
+
+	if err := h.decodeJSON(w, r, &req); err != nil {
+
+		h.Log.Debug("failed to decode request body", sl.Err(err))
+
+		problem.Write(w, r, decodeErrorProblem(err))
+
+		return
+	}
+
+	h.Log.Debug("request body decoded", slog.String("key", key), slog.Any("request", req))
+
+	if err := validate.Struct(req); err != nil {
+
+		validateErr := err.(validator.ValidationErrors)
+
+		h.Log.Debug("invalid request", sl.Err(validateErr))
+
+		problem.Write(w, r, ValidationError(validateErr))
+
+		return
+	}
+
+	if !isSimpleType(req.Value) {
+		h.Log.Debug("value must be a simple type")
+
+		problem.Write(w, r, valueTypeProblem())
+
+		return
+	}
+
+	if p, ok := h.checkEntryLimits(key, req.Value); !ok {
+		h.Log.Debug("entry exceeds limits", slog.String("detail", p.Detail))
+
+		problem.Write(w, r, p)
+
+		return
+	}
+
+	if err := h.cache(r).Put(r.Context(), key, req.Value, time.Duration(req.TTLSeconds)*time.Second); err != nil {
+
+		h.Log.Debug("failed to put lru cache", sl.Err(err))
+
+		problem.Write(w, r, cacheErrorProblem(err))
+
+		return
+	}
+
+	h.Log.Debug("entry stored successfully")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetEntry обрабатывает запрос API v2 на получение элемента по ключу.
+// Как и в v1, ответ поддерживает ETag и условные запросы.
+func (h *Handler) GetEntry(w http.ResponseWriter, r *http.Request) {
+	key := keyParam(r)
+	if key == "" {
+
+		h.Log.Debug("key is empty")
+
+		problem.Write(w, r, emptyKeyProblem())
+
+		return
+	}
+
+	val, exp, err := h.cache(r).Get(r.Context(), key)
+	if err != nil {
+
+		h.Log.Debug("failed to get lru cache", sl.Err(err))
+
+		problem.Write(w, r, cacheErrorProblem(err))
+
+		return
+	}
+
+	h.respondCacheable(w, r, newEntry(key, val, exp), exp)
+}
+
+// snapshotter реализуется кэшем, который возвращает элементы вместе со временем истечения (*lru.Cache).
+type snapshotter interface {
+	// Snapshot возвращает неистекшие элементы кэша в порядке вытеснения.
+	Snapshot() (entries []lru.Entry, seq uint64)
+}
+
+// snapshotter возвращает снимки кэша, с которым работает запрос, или nil, если кэш их не отдает.
+func (h *Handler) snapshotter(r *http.Request) snapshotter {
+	if cache, ok := r.Context().Value(cacheCtxKey{}).(ILRUCache); ok {
+		s, _ := cache.(snapshotter)
+		return s
+	}
+	return h.snapshots
+}
+
+// ListEntries обрабатывает запрос API v2 на получение всех элементов кэша.
+// Если кэш реализует snapshotter, элементы возвращаются со временем истечения, и чтение не меняет порядок вытеснения.
+func (h *Handler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	if s := h.snapshotter(r); s != nil {
+		entries, _ := s.Snapshot()
+
+		resp := models.EntryList{
+			Items: make([]models.Entry, 0, len(entries)),
+			Count: len(entries),
+		}
+		for _, e := range entries {
+			resp.Items = append(resp.Items, newEntry(e.Key, e.Value, e.ExpiresAt))
+		}
+
+		jsonRespond(w, r, http.StatusOK, resp)
+
+		return
+	}
+
+	keys, vals, err := h.cache(r).GetAll(r.Context())
+	if err != nil && !errors.Is(err, lru.ErrCacheIsEmpty) {
+
+		h.Log.Debug("failed to get lru cache", sl.Err(err))
+
+		problem.Write(w, r, cacheErrorProblem(err))
+
+		return
+	}
+
+	resp := models.EntryList{
+		Items: make([]models.Entry, 0, len(keys)),
+		Count: len(keys),
+	}
+	for i, key := range keys {
+		resp.Items = append(resp.Items, models.Entry{Key: key, Value: vals[i]})
+	}
+
+	jsonRespond(w, r, http.StatusOK, resp)
+}
+
+// DeleteEntry обрабатывает запрос API v2 на удаление элемента по ключу.
+// С заголовком Prefer: return=representation удаленный элемент возвращается в теле ответа.
+func (h *Handler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	key := keyParam(r)
+	if key == "" {
+
+		h.Log.Debug("key is empty")
+
+		problem.Write(w, r, emptyKeyProblem())
+
+		return
+	}
+
+	val, err := h.cache(r).Evict(r.Context(), key)
+	if err != nil {
+
+		h.Log.Debug("failed to evict lru cache", sl.Err(err))
+
+		problem.Write(w, r, cacheErrorProblem(err))
+
+		return
+	}
+
+	h.Log.Debug("key is evicted")
+
+	if preferRepresentation(r) {
+		w.Header().Set("Preference-Applied", "return=representation")
+		jsonRespond(w, r, http.StatusOK, models.Entry{Key: key, Value: val})
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteEntries обрабатывает запрос API v2 на удаление всех элементов кэша.
+func (h *Handler) DeleteEntries(w http.ResponseWriter, r *http.Request) {
+	h.EvictAll(w, r)
+}
+
+// newEntry формирует модель элемента API v2 со временем истечения. Нулевое время истечения не передается.
+// Оставшееся время жизни передается в заголовке Cache-Control, чтобы тело ответа и ETag не менялись каждую секунду.
+func newEntry(key string, value interface{}, expiresAt time.Time) models.Entry {
+	entry := models.Entry{Key: key, Value: value}
+	if !expiresAt.IsZero() {
+		expiresAt = expiresAt.UTC()
+		entry.ExpiresAt = &expiresAt
+	}
+
+	return entry
+}
+
+// keyParam возвращает ключ из пути запроса. Если путь содержит экранированные символы,
+// которые chi сохраняет при маршрутизации (например, %2F), ключ раскодируется.
+func keyParam(r *http.Request) string {
+	key := chi.URLParam(r, "key")
+	if r.URL.RawPath == "" {
+		return key
+	}
+	if unescaped, err := url.PathUnescape(key); err == nil {
+		return unescaped
+	}
+	return key
+}
+
+// preferRepresentation сообщает, запросил ли клиент тело ответа заголовком Prefer: return=representation (RFC 7240).
+func preferRepresentation(r *http.Request) bool {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			if strings.EqualFold(strings.TrimSpace(pref), "return=representation") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"github.com/instinctG/lru-cache/internal/http-server/admin"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"github.com/instinctG/lru-cache/internal/metrics"
+	"time"
+)
+
+// cacheOperationBuckets - границы корзин гистограммы времени операций кэша в секундах.
+var cacheOperationBuckets = []float64{.000001, .0000025, .000005, .00001, .000025, .00005, .0001, .00025, .0005, .001, .01}
+
+// statsProvider реализуется кэшем, который ведет собственную статистику (например, lru.Cache).
+type statsProvider interface {
+	Stats() lru.Stats
+}
+
+// instrumentedCache измеряет время выполнения каждого метода ILRUCache.
+type instrumentedCache struct {
+	next     ILRUCache
+	duration *metrics.HistogramVec
+}
+
+// newInstrumentedCache оборачивает кэш сбором метрик и регистрирует метрики статистики кэша, если она доступна.
+func newInstrumentedCache(cache ILRUCache, reg *metrics.Registry) ILRUCache {
+	if sp, ok := cache.(statsProvider); ok {
+		registerCacheStats(reg, sp)
+	}
+
+	return &instrumentedCache{
+		next: cache,
+		duration: reg.NewHistogramVec("lru_cache_operation_duration_seconds",
+			"Cache operation latency in seconds by ILRUCache method.", cacheOperationBuckets, "method"),
+	}
+}
+
+func registerCacheStats(reg *metrics.Registry, sp statsProvider) {
+	reg.NewGaugeFunc("lru_cache_size", "Current number of entries in the cache.", func() float64 {
+		return float64(sp.Stats().Size)
+	})
+	reg.NewGaugeFunc("lru_cache_capacity", "Maximum number of entries in the cache.", func() float64 {
+		return float64(sp.Stats().Capacity)
+	})
+	reg.NewCounterFunc("lru_cache_hits_total", "Total number of cache hits.", nil, func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(sp.Stats().Hits)}}
+	})
+	reg.NewCounterFunc("lru_cache_misses_total", "Total number of cache misses.", nil, func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(sp.Stats().Misses)}}
+	})
+	reg.NewCounterFunc("lru_cache_evictions_total", "Total number of evicted entries by reason.", []string{"reason"}, func() []metrics.Sample {
+		evictions := sp.Stats().Evictions
+
+		samples := make([]metrics.Sample, 0, len(evictions))
+		for reason, count := range evictions {
+			samples = append(samples, metrics.Sample{LabelValues: []string{reason.String()}, Value: float64(count)})
+		}
+		return samples
+	})
+}
+
+// adminVars возвращает переменные диагностического сервера со статистикой кэша, если кэш ее ведет.
+func adminVars(cache ILRUCache) map[string]admin.VarFunc {
+	sp, ok := cache.(statsProvider)
+	if !ok {
+		return nil
+	}
+
+	return map[string]admin.VarFunc{
+		"lru_cache": func() interface{} { return sp.Stats() },
+	}
+}
+
+// Put добавляет или обновляет элемент в кэше.
+func (c *instrumentedCache) Put(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	defer c.duration.ObserveDuration(time.Now(), "Put")
+	return c.next.Put(ctx, key, value, ttl)
+}
+
+// Get возвращает значение и время истечения элемента по ключу.
+func (c *instrumentedCache) Get(ctx context.Context, key string) (interface{}, time.Time, error) {
+	defer c.duration.ObserveDuration(time.Now(), "Get")
+	return c.next.Get(ctx, key)
+}
+
+// GetAll возвращает все ключи и значения кэша.
+func (c *instrumentedCache) GetAll(ctx context.Context) ([]string, []interface{}, error) {
+	defer c.duration.ObserveDuration(time.Now(), "GetAll")
+	return c.next.GetAll(ctx)
+}
+
+// Evict удаляет элемент из кэша по ключу.
+func (c *instrumentedCache) Evict(ctx context.Context, key string) (interface{}, error) {
+	defer c.duration.ObserveDuration(time.Now(), "Evict")
+	return c.next.Evict(ctx, key)
+}
+
+// EvictAll удаляет все элементы из кэша.
+func (c *instrumentedCache) EvictAll(ctx context.Context) error {
+	defer c.duration.ObserveDuration(time.Now(), "EvictAll")
+	return c.next.EvictAll(ctx)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+	"github.com/instinctG/lru-cache/internal/metrics"
+	"github.com/instinctG/lru-cache/internal/replication"
+	"log/slog"
+	"net/http"
+)
+
+// readOnly - middleware маршрутов записи на реплике: запрос перенаправляется ведущему узлу с кодом 307,
+// при котором клиент повторяет запрос с тем же методом и телом. На остальных узлах запросы обрабатываются локально.
+func (h *Handler) readOnly(next http.Handler) http.Handler {
+	if h.replica == nil {
+		return next
+	}
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		location := h.replica.Primary() + r.URL.RequestURI()
+
+		h.Log.Debug("redirecting write to the primary", slog.String("location", location))
+
+		http.Redirect(w, r, location, http.StatusTemporaryRedirect)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// primaryOnly возвращает middleware маршрутов с данными what, которые не реплицируются: реплика получает
+// только кэш по умолчанию, поэтому на ней такие запросы отклоняются с кодом 409. На остальных узлах
+// запросы обрабатываются локально.
+func (h *Handler) primaryOnly(what string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if h.replica == nil {
+			return next
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			h.notReplicated(w, r, what)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// notReplicated отклоняет на реплике запрос к данным, которые не реплицируются (what), с кодом 409.
+func (h *Handler) notReplicated(w http.ResponseWriter, r *http.Request, what string) {
+	h.Log.Debug("request to non-replicated data on a replica", slog.String("data", what))
+
+	problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeConflict,
+		what+" are not replicated, send the request to the primary "+h.replica.Primary()))
+}
+
+// ReplicationStatus обрабатывает запрос на получение состояния репликации узла.
+func (h *Handler) ReplicationStatus(w http.ResponseWriter, r *http.Request) {
+	jsonRespond(w, r, http.StatusOK, h.replicationStatus())
+}
+
+// ReplicationStream передает реплике поток изменений кэша по умолчанию. Доступен только на ведущем узле.
+func (h *Handler) ReplicationStream(w http.ResponseWriter, r *http.Request) {
+	if h.primary == nil {
+
+		h.Log.Debug("replication stream requested from a non-primary node")
+
+		problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeConflict, "node is not a replication primary"))
+
+		return
+	}
+
+	h.primary.ServeHTTP(w, r)
+}
+
+// replicationStatus возвращает состояние репликации узла.
+func (h *Handler) replicationStatus() replication.Status {
+	switch {
+	case h.primary != nil:
+		return h.primary.Status()
+	case h.replica != nil:
+		return h.replica.Status()
+	default:
+		return replication.Status{Role: replication.RoleStandalone}
+	}
+}
+
+// registerReplicationStats регистрирует метрики репликации, если узел является ведущим или репликой.
+func (h *Handler) registerReplicationStats(reg *metrics.Registry) {
+	switch {
+	case h.primary != nil:
+		reg.NewGaugeFunc("lru_replication_offset", "Last offset of the replication log.", func() float64 {
+			return float64(h.primary.Status().Offset)
+		})
+		reg.NewGaugeFunc("lru_replication_connected_replicas", "Number of replicas streaming the replication log.", func() float64 {
+			return float64(len(h.primary.Status().Replicas))
+		})
+		reg.NewGaugeVecFunc("lru_replication_replica_lag_entries", "Number of log changes not yet sent to a replica.", []string{"replica"}, func() []metrics.Sample {
+			replicas := h.primary.Status().Replicas
+
+			samples := make([]metrics.Sample, 0, len(replicas))
+			for _, replica := range replicas {
+				samples = append(samples, metrics.Sample{LabelValues: []string{replica.Address}, Value: float64(replica.LagEntries)})
+			}
+			return samples
+		})
+	case h.replica != nil:
+		reg.NewGaugeFunc("lru_replication_offset", "Last replication offset applied by the replica.", func() float64 {
+			return float64(h.replica.Status().Offset)
+		})
+		reg.NewGaugeFunc("lru_replication_connected", "Whether the replica is streaming changes from the primary (1 or 0).", func() float64 {
+			if h.replica.Status().Connected {
+				return 1
+			}
+			return 0
+		})
+		reg.NewGaugeFunc("lru_replication_lag_entries", "Number of primary changes known to the replica but not yet applied.", func() float64 {
+			return float64(h.replica.Status().LagEntries)
+		})
+		reg.NewGaugeFunc("lru_replication_lag_seconds", "Seconds since the replica last applied all known primary changes, 0 when in sync.", func() float64 {
+			return h.replica.Status().LagSeconds
+		})
+		reg.NewCounterFunc("lru_replication_full_syncs_total", "Total number of full syncs from a primary snapshot.", nil, func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(h.replica.Status().FullSyncs)}}
+		})
+	}
+}
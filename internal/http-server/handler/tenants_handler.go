@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/metrics"
+	"github.com/instinctG/lru-cache/internal/models"
+	"github.com/instinctG/lru-cache/internal/tenant"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// tenant - middleware, которое направляет запросы арендатора в его кэш. Арендатор определяется по имени
+// аутентифицированного субъекта. Заголовок h.tenantHeader учитывается только при отключенной аутентификации:
+// иначе любой клиент мог бы работать с кэшем чужого арендатора. Запросы с неизвестным арендатором в заголовке
+// и запросы аутентифицированного субъекта с заголовком другого арендатора отклоняются с кодом 403,
+// запросы без арендатора работают с кэшем по умолчанию.
+// Кэши арендаторов не реплицируются, поэтому на реплике запросы арендаторов отклоняются с кодом 409.
+func (h *Handler) tenant(next http.Handler) http.Handler {
+	serve := func(w http.ResponseWriter, r *http.Request, t *tenant.Tenant) {
+		if h.replica != nil {
+			h.notReplicated(w, r, "tenant caches")
+			return
+		}
+		next.ServeHTTP(w, withTenant(r, t))
+	}
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := mw_auth.FromContext(r.Context()); ok {
+			if t, ok := h.tenants.Get(p.Name); ok {
+				serve(w, r, t)
+				return
+			}
+		}
+
+		if h.tenantHeader == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name := r.Header.Get(h.tenantHeader)
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(h.auth.Authenticators) > 0 {
+			h.Log.Debug("tenant header from non-tenant principal", slog.String("tenant", name))
+
+			problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden,
+				"tenant header is not allowed for an authenticated principal that is not tenant "+name))
+
+			return
+		}
+
+		t, ok := h.tenants.Get(name)
+		if !ok {
+			h.Log.Debug("unknown tenant", slog.String("tenant", name))
+
+			problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden, "unknown tenant "+name))
+
+			return
+		}
+
+		serve(w, r, t)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// tenantCtxKey - ключ контекста запроса, в котором хранится имя арендатора.
+type tenantCtxKey struct{}
+
+// withTenant возвращает запрос, который работает с кэшем арендатора t.
+func withTenant(r *http.Request, t *tenant.Tenant) *http.Request {
+	ctx := context.WithValue(r.Context(), cacheCtxKey{}, ILRUCache(t.Cache))
+	return r.WithContext(context.WithValue(ctx, tenantCtxKey{}, t.Name))
+}
+
+// tenantName возвращает имя арендатора запроса, если запрос работает с кэшем арендатора.
+func tenantName(r *http.Request) (string, bool) {
+	name, ok := r.Context().Value(tenantCtxKey{}).(string)
+	return name, ok
+}
+
+// tenantVisible сообщает, может ли клиент запроса видеть квоту и статистику арендатора name.
+// Без аутентификации и с правом admin доступны все арендаторы, иначе - только собственный арендатор субъекта.
+func (h *Handler) tenantVisible(r *http.Request, name string) bool {
+	if len(h.auth.Authenticators) == 0 {
+		return true
+	}
+
+	if p, ok := mw_auth.FromContext(r.Context()); ok && p.HasScope(mw_auth.ScopeAdmin) {
+		return true
+	}
+
+	own, ok := tenantName(r)
+	return ok && own == name
+}
+
+// ListTenants обрабатывает запрос на получение квот и статистики арендаторов, видимых клиенту (см. tenantVisible).
+func (h *Handler) ListTenants(w http.ResponseWriter, r *http.Request) {
+	tenants := h.tenants.List()
+
+	resp := models.TenantList{Items: make([]models.TenantInfo, 0, len(tenants))}
+	for _, t := range tenants {
+		if h.tenantVisible(r, t.Name) {
+			resp.Items = append(resp.Items, tenantInfo(t))
+		}
+	}
+	resp.Count = len(resp.Items)
+
+	jsonRespond(w, r, http.StatusOK, resp)
+}
+
+// GetTenant обрабатывает запрос на получение квоты и статистики арендатора.
+// Арендатор, невидимый клиенту (см. tenantVisible), не отличается от отсутствующего.
+func (h *Handler) GetTenant(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	t, ok := h.tenants.Get(name)
+	if !ok || !h.tenantVisible(r, name) {
+		h.Log.Debug("tenant not found", slog.String("tenant", name))
+
+		problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeTenantNotFound, "tenant "+name+" not found"))
+
+		return
+	}
+
+	jsonRespond(w, r, http.StatusOK, tenantInfo(t))
+}
+
+// SetTenantQuota обрабатывает запрос на изменение квоты арендатора. Если арендатора нет, он создается.
+// Если кэш арендатора превышает новую квоту, лишние элементы арендатора вытесняются.
+func (h *Handler) SetTenantQuota(w http.ResponseWriter, r *http.Request) {
+	var req models.SetTenantQuotaRequest
+
+	if err := h.decodeJSON(w, r, &req); err != nil {
+
+		h.Log.Debug("failed to decode request body", sl.Err(err))
+
+		problem.Write(w, r, decodeErrorProblem(err))
+
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+
+		validateErr := err.(validator.ValidationErrors)
+
+		h.Log.Debug("invalid request", sl.Err(validateErr))
+
+		problem.Write(w, r, ValidationError(validateErr))
+
+		return
+	}
+
+	quota := tenant.Quota{
+		MaxEntries: req.MaxEntries,
+		MaxBytes:   req.MaxBytes,
+		TTL:        time.Duration(req.TTLSeconds) * time.Second,
+	}
+
+	t, created, err := h.tenants.Set(chi.URLParam(r, "name"), quota)
+	if err != nil {
+
+		h.Log.Debug("failed to set tenant quota", sl.Err(err))
+
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeValidationFailed, err.Error()))
+
+		return
+	}
+
+	quota = t.Quota()
+	h.Log.Info("tenant quota changed", slog.String("tenant", t.Name), slog.Int("max_entries", quota.MaxEntries),
+		slog.Int64("max_bytes", quota.MaxBytes), slog.Duration("ttl", quota.TTL))
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	jsonRespond(w, r, status, tenantInfo(t))
+}
+
+// tenantInfo формирует описание арендатора для ответа.
+func tenantInfo(t *tenant.Tenant) models.TenantInfo {
+	quota, stats := t.Quota(), t.Cache.Stats()
+
+	info := models.TenantInfo{
+		Name:       t.Name,
+		MaxEntries: quota.MaxEntries,
+		MaxBytes:   quota.MaxBytes,
+		TTLSeconds: int64(quota.TTL / time.Second),
+		Size:       stats.Size,
+		Bytes:      stats.Bytes,
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+		Evictions:  make(map[string]uint64, len(stats.Evictions)),
+	}
+	for reason, count := range stats.Evictions {
+		info.Evictions[reason.String()] = count
+	}
+
+	return info
+}
+
+// registerTenantStats регистрирует метрики кэшей арендаторов с меткой tenant.
+func registerTenantStats(reg *metrics.Registry, tenants *tenant.Registry) {
+	// samples возвращает по одному значению value на арендатора.
+	samples := func(value func(t *tenant.Tenant) float64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			list := tenants.List()
+
+			samples := make([]metrics.Sample, 0, len(list))
+			for _, t := range list {
+				samples = append(samples, metrics.Sample{LabelValues: []string{t.Name}, Value: value(t)})
+			}
+			return samples
+		}
+	}
+
+	reg.NewGaugeVecFunc("lru_cache_tenant_size", "Current number of entries in the cache by tenant.", []string{"tenant"},
+		samples(func(t *tenant.Tenant) float64 { return float64(t.Cache.Stats().Size) }))
+	reg.NewGaugeVecFunc("lru_cache_tenant_bytes", "Current size of entries in bytes by tenant with a byte quota.", []string{"tenant"},
+		samples(func(t *tenant.Tenant) float64 { return float64(t.Cache.Stats().Bytes) }))
+	reg.NewCounterFunc("lru_cache_tenant_hits_total", "Total number of cache hits by tenant.", []string{"tenant"},
+		samples(func(t *tenant.Tenant) float64 { return float64(t.Cache.Stats().Hits) }))
+	reg.NewCounterFunc("lru_cache_tenant_misses_total", "Total number of cache misses by tenant.", []string{"tenant"},
+		samples(func(t *tenant.Tenant) float64 { return float64(t.Cache.Stats().Misses) }))
+	reg.NewCounterFunc("lru_cache_tenant_evictions_total", "Total number of evicted entries by tenant and reason.", []string{"tenant", "reason"}, func() []metrics.Sample {
+		var samples []metrics.Sample
+		for _, t := range tenants.List() {
+			for reason, count := range t.Cache.Stats().Evictions {
+				samples = append(samples, metrics.Sample{LabelValues: []string{t.Name, reason.String()}, Value: float64(count)})
+			}
+		}
+		return samples
+	})
+}
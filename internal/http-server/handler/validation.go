@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// validate - валидатор запросов, использующий имена полей из JSON-тегов.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// ValidationError формирует ответ об ошибках валидации со списком невалидных полей.
+func ValidationError(errs validator.ValidationErrors) problem.Problem {
+	fields := make([]problem.FieldError, 0, len(errs))
+	messages := make([]string, 0, len(errs))
+
+	for _, err := range errs {
+		fieldErr := problem.FieldError{
+			Field: err.Field(),
+			Rule:  err.ActualTag(),
+		}
+
+		switch err.ActualTag() {
+		case "required":
+			fieldErr.Message = "field " + err.Field() + " is a required field and not valid"
+		default:
+			fieldErr.Message = "field " + err.Field() + " is not valid"
+		}
+
+		fields = append(fields, fieldErr)
+		messages = append(messages, fieldErr.Message)
+	}
+
+	p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, strings.Join(messages, ", "))
+	p.Errors = fields
+
+	return p
+}
@@ -0,0 +1,102 @@
+package mw_auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// APIKeyHeader - заголовок, в котором передается API-ключ.
+// Ключ также можно передать в заголовке Authorization со схемой ApiKey.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator аутентифицирует запросы по статическим API-ключам.
+type APIKeyAuthenticator struct {
+	// keys сопоставляет SHA-256 ключа с именем субъекта.
+	// Поиск по хэшу не раскрывает через время ответа, какая часть ключа совпала.
+	keys map[[sha256.Size]byte]string
+}
+
+// NewAPIKeyAuthenticator создает APIKeyAuthenticator по набору "имя субъекта -> ключ".
+func NewAPIKeyAuthenticator(keys map[string]string) *APIKeyAuthenticator {
+	a := &APIKeyAuthenticator{keys: make(map[[sha256.Size]byte]string, len(keys))}
+	for name, key := range keys {
+		a.keys[sha256.Sum256([]byte(key))] = name
+	}
+	return a
+}
+
+// Authenticate проверяет API-ключ из заголовка X-API-Key или Authorization: ApiKey <ключ>.
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		var ok bool
+		if key, ok = bearerToken(r, "ApiKey"); !ok {
+			return nil, ErrNoCredentials
+		}
+	}
+
+	name, ok := a.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown api key", ErrInvalidCredentials)
+	}
+
+	return &Principal{Name: name, Method: MethodAPIKey}, nil
+}
+
+// ParseAPIKeys разбирает список ключей в формате "имя:ключ,имя:ключ".
+func ParseAPIKeys(spec string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		if err := addAPIKey(keys, entry); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// LoadAPIKeys загружает ключи из файла, в котором каждая строка имеет формат "имя:ключ".
+// Пустые строки и строки, начинающиеся с #, пропускаются.
+func LoadAPIKeys(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keys := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if err = addAPIKey(keys, entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+
+	return keys, scanner.Err()
+}
+
+func addAPIKey(keys map[string]string, entry string) error {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return nil
+	}
+
+	name, key, ok := strings.Cut(entry, ":")
+	name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+	if !ok || name == "" || key == "" {
+		return fmt.Errorf("invalid api key entry, expected name:key")
+	}
+	if _, exists := keys[name]; exists {
+		return fmt.Errorf("duplicate api key name %q", name)
+	}
+
+	keys[name] = key
+	return nil
+}
@@ -0,0 +1,171 @@
+// Package mw_auth предоставляет middleware для аутентификации HTTP-запросов
+// по статическим API-ключам, JWT-токенам и клиентским сертификатам TLS.
+package mw_auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+)
+
+var (
+	ErrNoCredentials      = errors.New("no credentials")      // ErrNoCredentials возвращается, если запрос не содержит учетных данных.
+	ErrInvalidCredentials = errors.New("invalid credentials") // ErrInvalidCredentials возвращается, если учетные данные не прошли проверку.
+)
+
+// Способы аутентификации субъекта.
+const (
+	MethodAPIKey     = "api_key"     // MethodAPIKey - статический API-ключ.
+	MethodJWT        = "jwt"         // MethodJWT - JWT-токен в заголовке Authorization.
+	MethodClientCert = "client_cert" // MethodClientCert - клиентский сертификат TLS.
+)
+
+// Principal представляет аутентифицированного субъекта запроса.
+type Principal struct {
+	Name   string   // Имя субъекта (имя ключа или claim sub токена).
+	Method string   // Способ аутентификации.
+	Scopes []string // Права субъекта, заданные при выдаче учетных данных.
+}
+
+// Authenticator проверяет учетные данные запроса.
+type Authenticator interface {
+	// Authenticate возвращает субъекта запроса.
+	// Если запрос не содержит учетных данных, поддерживаемых Authenticator, возвращается ErrNoCredentials.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Config содержит параметры middleware аутентификации.
+type Config struct {
+	Authenticators []Authenticator // Способы аутентификации, проверяемые по порядку.
+	PublicPaths    []string        // Пути, доступные без аутентификации (например, /healthz).
+
+	Scopes        map[string][]string // Права, выданные субъектам по имени, дополняют права из учетных данных.
+	DefaultScopes []string            // Права субъектов, для которых права не заданы ни в учетных данных, ни в Scopes.
+}
+
+type principalKey struct{}
+
+// slot хранит субъекта запроса для middleware, которые выполняются до аутентификации.
+type slot struct {
+	principal *Principal
+}
+
+type slotKey struct{}
+
+// FromContext возвращает субъекта запроса, если запрос аутентифицирован.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// NewContext возвращает контекст с субъектом запроса.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	if s, ok := ctx.Value(slotKey{}).(*slot); ok {
+		s.principal = p
+	}
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// Track добавляет в контекст запроса ячейку для субъекта и возвращает функцию для его чтения.
+// Позволяет внешним middleware, например логированию, узнать субъекта после обработки запроса.
+func Track(r *http.Request) (*http.Request, func() *Principal) {
+	s := new(slot)
+	r = r.WithContext(context.WithValue(r.Context(), slotKey{}, s))
+
+	return r, func() *Principal { return s.principal }
+}
+
+// New создает middleware, которое аутентифицирует запросы с помощью cfg.Authenticators.
+// Запросы без учетных данных или с недействительными учетными данными отклоняются с кодом 401.
+// Параметры:
+//   - log: логгер для записи ошибок аутентификации.
+//   - cfg: параметры аутентификации.
+//
+// Возвращает функцию middleware, которая сохраняет субъекта в контексте запроса и передает управление следующему обработчику.
+func New(log *slog.Logger, cfg Config) func(next http.Handler) http.Handler {
+	public := make(map[string]struct{}, len(cfg.PublicPaths))
+	for _, path := range cfg.PublicPaths {
+		public[path] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+		log.Info("auth middleware enabled", slog.Int("authenticators", len(cfg.Authenticators)))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := public[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := cfg.Authenticate(r)
+			if err != nil {
+				log.Debug("authentication failed", sl.Err(err), slog.String("path", r.URL.Path))
+
+				w.Header().Set("WWW-Authenticate", `Bearer realm="lru-cache"`)
+				problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, err.Error()))
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), principal)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Enabled сообщает, задан ли хотя бы один способ аутентификации.
+func (c Config) Enabled() bool {
+	return len(c.Authenticators) > 0
+}
+
+// Authenticate возвращает субъекта запроса r с правами из учетных данных, c.Scopes или c.DefaultScopes.
+// Серверы других протоколов (gRPC, RESP) передают учетные данные клиента в r.Header и r.TLS.
+func (c Config) Authenticate(r *http.Request) (*Principal, error) {
+	principal, err := authenticate(r, c.Authenticators)
+	if err != nil {
+		return nil, err
+	}
+
+	principal.Scopes = append(principal.Scopes, c.Scopes[principal.Name]...)
+	if len(principal.Scopes) == 0 {
+		principal.Scopes = append([]string(nil), c.DefaultScopes...)
+	}
+
+	return principal, nil
+}
+
+// authenticate перебирает способы аутентификации, пока один из них не найдет учетные данные.
+func authenticate(r *http.Request, authenticators []Authenticator) (*Principal, error) {
+	for _, a := range authenticators {
+		principal, err := a.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return principal, nil
+	}
+
+	return nil, ErrNoCredentials
+}
+
+// bearerToken возвращает токен из заголовка Authorization со схемой scheme (без учета регистра).
+func bearerToken(r *http.Request, scheme string) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(scheme)+1 || !strings.EqualFold(header[:len(scheme)], scheme) || header[len(scheme)] != ' ' {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[len(scheme)+1:]), true
+}
@@ -0,0 +1,283 @@
+package mw_auth_test
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	"github.com/instinctG/lru-cache/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func signHS256(t *testing.T, secret []byte, claims map[string]interface{}) string {
+	t.Helper()
+	unsigned := encodeSegment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	t.Helper()
+	unsigned := encodeSegment(t, map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestMiddleware(t *testing.T) {
+	secret := []byte("test-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwtAuth, err := mw_auth.NewJWTAuthenticator(mw_auth.JWTConfig{
+		HS256Secret:    secret,
+		RS256PublicKey: &rsaKey.PublicKey,
+		Issuer:         "issuer",
+		Audience:       "lru-cache",
+	})
+	require.NoError(t, err)
+
+	mw := mw_auth.New(logger.NewDiscardLogger(), mw_auth.Config{
+		Authenticators: []mw_auth.Authenticator{
+			mw_auth.NewAPIKeyAuthenticator(map[string]string{"ci": "ci-key"}),
+			jwtAuth,
+		},
+		PublicPaths: []string{"/healthz"},
+	})
+
+	var got *mw_auth.Principal
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = mw_auth.FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	valid := map[string]interface{}{
+		"sub":   "svc",
+		"iss":   "issuer",
+		"aud":   []string{"other", "lru-cache"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "read write",
+	}
+	expired := map[string]interface{}{"sub": "svc", "iss": "issuer", "aud": "lru-cache", "exp": time.Now().Add(-time.Hour).Unix()}
+	wrongIssuer := map[string]interface{}{"sub": "svc", "iss": "other", "aud": "lru-cache"}
+
+	tests := []struct {
+		name          string
+		path          string
+		header        string
+		value         string
+		expectedCode  int
+		expectedName  string
+		expectedAuth  string
+		expectedScope []string
+	}{
+		{name: "Public path", path: "/healthz", expectedCode: http.StatusOK},
+		{name: "No credentials", path: "/api/lru", expectedCode: http.StatusUnauthorized},
+		{name: "API key header", path: "/api/lru", header: "X-API-Key", value: "ci-key", expectedCode: http.StatusOK, expectedName: "ci", expectedAuth: mw_auth.MethodAPIKey},
+		{name: "API key scheme", path: "/api/lru", header: "Authorization", value: "ApiKey ci-key", expectedCode: http.StatusOK, expectedName: "ci", expectedAuth: mw_auth.MethodAPIKey},
+		{name: "Unknown API key", path: "/api/lru", header: "X-API-Key", value: "wrong", expectedCode: http.StatusUnauthorized},
+		{name: "HS256 token", path: "/api/lru", header: "Authorization", value: "Bearer " + signHS256(t, secret, valid), expectedCode: http.StatusOK, expectedName: "svc", expectedAuth: mw_auth.MethodJWT, expectedScope: []string{"read", "write"}},
+		{name: "RS256 token", path: "/api/lru", header: "Authorization", value: "bearer " + signRS256(t, rsaKey, valid), expectedCode: http.StatusOK, expectedName: "svc", expectedAuth: mw_auth.MethodJWT, expectedScope: []string{"read", "write"}},
+		{name: "Wrong HS256 secret", path: "/api/lru", header: "Authorization", value: "Bearer " + signHS256(t, []byte("other"), valid), expectedCode: http.StatusUnauthorized},
+		{name: "Expired token", path: "/api/lru", header: "Authorization", value: "Bearer " + signHS256(t, secret, expired), expectedCode: http.StatusUnauthorized},
+		{name: "Wrong issuer", path: "/api/lru", header: "Authorization", value: "Bearer " + signHS256(t, secret, wrongIssuer), expectedCode: http.StatusUnauthorized},
+		{name: "Algorithm none", path: "/api/lru", header: "Authorization", value: "Bearer " + encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, valid) + ".", expectedCode: http.StatusUnauthorized},
+		{name: "Malformed token", path: "/api/lru", header: "Authorization", value: "Bearer abc", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedCode == http.StatusUnauthorized {
+				assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+				assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+				return
+			}
+			if tt.expectedName == "" {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, tt.expectedName, got.Name)
+			assert.Equal(t, tt.expectedAuth, got.Method)
+			assert.Equal(t, tt.expectedScope, got.Scopes)
+		})
+	}
+}
+
+func TestTrack(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req, principal := mw_auth.Track(req)
+	assert.Nil(t, principal())
+
+	// Субъект, сохраненный во внутреннем контексте, виден через Track
+	_ = mw_auth.NewContext(req.Context(), &mw_auth.Principal{Name: "svc"})
+	require.NotNil(t, principal())
+	assert.Equal(t, "svc", principal().Name)
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, []byte("# comment\n\nci:ci-key\nops: ops-key \n"), 0o600))
+
+	keys, err := mw_auth.LoadAPIKeys(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ci": "ci-key", "ops": "ops-key"}, keys)
+
+	require.NoError(t, os.WriteFile(path, []byte("ci\n"), 0o600))
+	_, err = mw_auth.LoadAPIKeys(path)
+	assert.Error(t, err)
+
+	keys, err = mw_auth.ParseAPIKeys("ci:ci-key, ops:ops-key")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ci": "ci-key", "ops": "ops-key"}, keys)
+
+	_, err = mw_auth.ParseAPIKeys("ci:a,ci:b")
+	assert.Error(t, err)
+}
+
+func TestParseRSAPublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	parsed, err := mw_auth.ParseRSAPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(parsed))
+
+	_, err = mw_auth.ParseRSAPublicKey([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestPrincipalHasScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   []string
+		scope    string
+		expected bool
+	}{
+		{name: "No scopes", scope: mw_auth.ScopeRead, expected: false},
+		{name: "Same scope", scopes: []string{mw_auth.ScopeRead}, scope: mw_auth.ScopeRead, expected: true},
+		{name: "Read is not write", scopes: []string{mw_auth.ScopeRead}, scope: mw_auth.ScopeWrite, expected: false},
+		{name: "Write implies read", scopes: []string{mw_auth.ScopeWrite}, scope: mw_auth.ScopeRead, expected: true},
+		{name: "Write is not admin", scopes: []string{mw_auth.ScopeWrite}, scope: mw_auth.ScopeAdmin, expected: false},
+		{name: "Admin implies write", scopes: []string{"other", mw_auth.ScopeAdmin}, scope: mw_auth.ScopeWrite, expected: true},
+		{name: "Unknown scope", scopes: []string{mw_auth.ScopeAdmin}, scope: "other", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &mw_auth.Principal{Name: "svc", Scopes: tt.scopes}
+			assert.Equal(t, tt.expected, p.HasScope(tt.scope))
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	handler := mw_auth.New(logger.NewDiscardLogger(), mw_auth.Config{
+		Authenticators: []mw_auth.Authenticator{
+			mw_auth.NewAPIKeyAuthenticator(map[string]string{"reader": "reader-key", "ops": "ops-key"}),
+		},
+		Scopes:        map[string][]string{"ops": {mw_auth.ScopeAdmin}},
+		DefaultScopes: []string{mw_auth.ScopeRead},
+	})(mw_auth.Authorize(logger.NewDiscardLogger(), mw_auth.ScopeWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	tests := []struct {
+		name         string
+		key          string
+		expectedCode int
+	}{
+		{name: "Default scopes", key: "reader-key", expectedCode: http.StatusForbidden},
+		{name: "Configured scopes", key: "ops-key", expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/v2/cache/entries/a", nil)
+			req.Header.Set(mw_auth.APIKeyHeader, tt.key)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+
+	// Без аутентификации проверка прав отклоняет запрос
+	rec := httptest.NewRecorder()
+	mw_auth.Authorize(logger.NewDiscardLogger(), mw_auth.ScopeRead)(http.NotFoundHandler()).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := mw_auth.ParseScopes("consumer:read, ops:read,ops:admin")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"consumer": {"read"}, "ops": {"read", "admin"}}, scopes)
+
+	_, err = mw_auth.ParseScopes("ops:root")
+	assert.Error(t, err)
+
+	_, err = mw_auth.ParseScopes("ops")
+	assert.Error(t, err)
+}
+
+func TestClientCertAuthenticator(t *testing.T) {
+	a := mw_auth.NewClientCertAuthenticator()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err := a.Authenticate(req)
+	assert.ErrorIs(t, err, mw_auth.ErrNoCredentials)
+
+	// Имя субъекта берется из CommonName, а без него - из полного Subject
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "svc"}}}}}
+	p, err := a.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "svc", p.Name)
+	assert.Equal(t, mw_auth.MethodClientCert, p.Method)
+
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{Organization: []string{"ops"}}}}}}
+	p, err = a.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "O=ops", p.Name)
+}
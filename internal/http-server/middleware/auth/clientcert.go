@@ -0,0 +1,31 @@
+package mw_auth
+
+import (
+	"net/http"
+)
+
+// ClientCertAuthenticator аутентифицирует запросы по клиентскому сертификату, проверенному при установке TLS-соединения (mTLS).
+// Имя субъекта - CommonName сертификата, а если он не задан - полный Subject.
+type ClientCertAuthenticator struct{}
+
+// NewClientCertAuthenticator создает ClientCertAuthenticator.
+// Сертификаты проверяет TLS-сервер, поэтому сервер должен быть настроен с доверенными CA клиентов.
+func NewClientCertAuthenticator() *ClientCertAuthenticator {
+	return &ClientCertAuthenticator{}
+}
+
+// Authenticate возвращает субъекта из проверенного клиентского сертификата.
+func (a *ClientCertAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+
+	name := cert.Subject.CommonName
+	if name == "" {
+		name = cert.Subject.String()
+	}
+
+	return &Principal{Name: name, Method: MethodClientCert}, nil
+}
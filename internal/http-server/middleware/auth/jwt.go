@@ -0,0 +1,237 @@
+package mw_auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// JWTConfig содержит ключи и ожидаемые значения claims для проверки JWT-токенов.
+type JWTConfig struct {
+	HS256Secret    []byte         // Секрет для токенов HS256, nil - HS256 не принимается.
+	RS256PublicKey *rsa.PublicKey // Открытый ключ для токенов RS256, nil - RS256 не принимается.
+	Issuer         string         // Ожидаемое значение claim iss, пустое - не проверяется.
+	Audience       string         // Ожидаемое значение claim aud, пустое - не проверяется.
+	Leeway         time.Duration  // Допустимое расхождение часов при проверке exp и nbf.
+}
+
+// JWTAuthenticator аутентифицирует запросы по JWT-токенам в заголовке Authorization: Bearer <токен>.
+// Токены проверяются только локально настроенными ключами, алгоритм none не поддерживается.
+type JWTAuthenticator struct {
+	cfg JWTConfig
+	now func() time.Time
+}
+
+// NewJWTAuthenticator создает JWTAuthenticator. Должен быть задан хотя бы один ключ.
+func NewJWTAuthenticator(cfg JWTConfig) (*JWTAuthenticator, error) {
+	if len(cfg.HS256Secret) == 0 && cfg.RS256PublicKey == nil {
+		return nil, errors.New("jwt: no verification keys configured")
+	}
+
+	return &JWTAuthenticator{cfg: cfg, now: time.Now}, nil
+}
+
+// jwtHeader представляет заголовок JWT-токена.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// jwtClaims представляет проверяемые claims JWT-токена.
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	Scope     string   `json:"scope"`
+	Scopes    []string `json:"scopes"`
+}
+
+// audience разбирает claim aud, который может быть строкой или массивом строк.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(b, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// Authenticate проверяет подпись и claims JWT-токена и возвращает субъекта из claim sub.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := bearerToken(r, "Bearer")
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := a.verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	scopes := claims.Scopes
+	if claims.Scope != "" {
+		scopes = append(scopes, strings.Fields(claims.Scope)...)
+	}
+
+	return &Principal{Name: claims.Subject, Method: MethodJWT, Scopes: scopes}, nil
+}
+
+func (a *JWTAuthenticator) verify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %w", err)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	digest := sha256.Sum256(signed)
+
+	switch header.Alg {
+	case "HS256":
+		if len(a.cfg.HS256Secret) == 0 {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		mac := hmac.New(sha256.New, a.cfg.HS256Secret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, errors.New("invalid signature")
+		}
+	case "RS256":
+		if a.cfg.RS256PublicKey == nil {
+			return nil, errors.New("RS256 tokens are not accepted")
+		}
+		if err = rsa.VerifyPKCS1v15(a.cfg.RS256PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, errors.New("invalid signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	var claims jwtClaims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %w", err)
+	}
+
+	if err = a.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+func (a *JWTAuthenticator) validateClaims(claims *jwtClaims) error {
+	now := a.now()
+
+	if claims.Subject == "" {
+		return errors.New("missing sub claim")
+	}
+	if claims.ExpiresAt != nil && now.After(unixTime(*claims.ExpiresAt).Add(a.cfg.Leeway)) {
+		return errors.New("token is expired")
+	}
+	if claims.NotBefore != nil && now.Add(a.cfg.Leeway).Before(unixTime(*claims.NotBefore)) {
+		return errors.New("token is not valid yet")
+	}
+	if a.cfg.Issuer != "" && claims.Issuer != a.cfg.Issuer {
+		return errors.New("unexpected issuer")
+	}
+	if a.cfg.Audience != "" && !claims.Audience.contains(a.cfg.Audience) {
+		return errors.New("unexpected audience")
+	}
+
+	return nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// LoadRSAPublicKey загружает открытый ключ RSA из PEM-файла (PUBLIC KEY, RSA PUBLIC KEY или CERTIFICATE).
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRSAPublicKey(data)
+}
+
+// ParseRSAPublicKey разбирает открытый ключ RSA в формате PEM.
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: no PEM block found")
+	}
+
+	var key interface{}
+	var err error
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("jwt: unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwt: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("jwt: public key is not RSA")
+	}
+
+	return rsaKey, nil
+}
@@ -0,0 +1,104 @@
+package mw_auth
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+)
+
+// Права субъектов. Каждое следующее право включает предыдущие: admin включает write, write включает read.
+const (
+	ScopeRead  = "read"  // ScopeRead - чтение элементов кэша (GET, HEAD).
+	ScopeWrite = "write" // ScopeWrite - запись и удаление отдельных элементов (POST, PUT, PATCH, DELETE элемента).
+	ScopeAdmin = "admin" // ScopeAdmin - операции над всем кэшем (очистка кэша).
+)
+
+// scopeLevels задает порядок прав для проверки их вложенности.
+var scopeLevels = map[string]int{
+	ScopeRead:  1,
+	ScopeWrite: 2,
+	ScopeAdmin: 3,
+}
+
+// HasScope сообщает, есть ли у субъекта право scope напрямую или через более сильное право.
+func (p *Principal) HasScope(scope string) bool {
+	required, known := scopeLevels[scope]
+
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+		if level, ok := scopeLevels[s]; known && ok && level >= required {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Authorize создает middleware, которое пропускает только запросы субъектов с правом scope.
+// Запросы без субъекта отклоняются с кодом 401, запросы субъектов без нужного права - с кодом 403.
+// Параметры:
+//   - log: логгер для записи отказов в доступе.
+//   - scope: право, необходимое для выполнения запроса.
+//
+// Возвращает функцию middleware, которая передает управление следующему обработчику, если право есть.
+func Authorize(log *slog.Logger, scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+			slog.String("scope", scope),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := FromContext(r.Context())
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="lru-cache"`)
+				problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, ErrNoCredentials.Error()))
+
+				return
+			}
+
+			if !principal.HasScope(scope) {
+				log.Debug("access denied", slog.String("principal", principal.Name), slog.String("path", r.URL.Path))
+
+				problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden,
+					fmt.Sprintf("principal %q has no %s scope", principal.Name, scope)))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// ParseScopes разбирает права субъектов в формате "имя:право,имя:право".
+// Несколько прав одного субъекта задаются повторением имени, например "ops:read,ops:admin".
+func ParseScopes(spec string) (map[string][]string, error) {
+	scopes := make(map[string][]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, scope, ok := strings.Cut(entry, ":")
+		name, scope = strings.TrimSpace(name), strings.TrimSpace(scope)
+		if !ok || name == "" || scope == "" {
+			return nil, fmt.Errorf("invalid scope entry %q, expected name:scope", entry)
+		}
+		if _, known := scopeLevels[scope]; !known {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+
+		scopes[name] = append(scopes[name], scope)
+	}
+	return scopes, nil
+}
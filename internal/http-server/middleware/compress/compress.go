@@ -0,0 +1,369 @@
+// Package mw_compress предоставляет middleware для сжатия ответов (gzip, deflate)
+// с выбором кодирования по заголовку Accept-Encoding.
+package mw_compress
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Поддерживаемые кодирования в порядке предпочтения сервера.
+const (
+	EncodingGzip    = "gzip"    // EncodingGzip - сжатие gzip.
+	EncodingDeflate = "deflate" // EncodingDeflate - сжатие deflate в формате zlib (RFC 1950), как требует HTTP (RFC 9110).
+)
+
+var encodings = []string{EncodingGzip, EncodingDeflate}
+
+// DefaultSkipTypes - типы содержимого, которые уже сжаты и не сжимаются повторно.
+// Тип вида "image/*" соответствует всем подтипам.
+var DefaultSkipTypes = []string{
+	"image/*",
+	"video/*",
+	"audio/*",
+	"font/woff",
+	"font/woff2",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/zstd",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+}
+
+// Config содержит параметры сжатия ответов.
+type Config struct {
+	MinSize   int      // Минимальный размер ответа в байтах, начиная с которого он сжимается.
+	Level     int      // Уровень сжатия от flate.BestSpeed до flate.BestCompression, 0 - уровень по умолчанию.
+	SkipTypes []string // Типы содержимого, которые не сжимаются, nil - DefaultSkipTypes.
+}
+
+// New создает middleware, которое сжимает ответы кодированием, выбранным по заголовку Accept-Encoding.
+// Ответ сжимается, только если его размер не меньше cfg.MinSize, у него нет Content-Encoding,
+// а тип содержимого не входит в cfg.SkipTypes. Сильный ETag сжатого ответа становится слабым,
+// так как байты ответа отличаются от несжатого представления.
+// Параметры:
+//   - log: логгер для записи ошибок сжатия.
+//   - cfg: параметры сжатия.
+//
+// Возвращает функцию middleware, которая передает управление следующему обработчику со сжимающим ResponseWriter.
+func New(log *slog.Logger, cfg Config) func(next http.Handler) http.Handler {
+	log = log.With(
+		slog.String("component", "middleware/compress"),
+	)
+
+	level := cfg.Level
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		log.Warn("invalid compression level, using default", slog.Int("level", level))
+		level = 0
+	}
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+
+	skip := cfg.SkipTypes
+	if skip == nil {
+		skip = DefaultSkipTypes
+	}
+
+	c := &compressor{minSize: cfg.MinSize, skip: make(map[string]struct{}, len(skip)), pools: make(map[string]*sync.Pool)}
+	for _, t := range skip {
+		c.skip[strings.ToLower(t)] = struct{}{}
+	}
+	c.pools[EncodingGzip] = &sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, level)
+		return w
+	}}
+	c.pools[EncodingDeflate] = &sync.Pool{New: func() interface{} {
+		w, _ := zlib.NewWriterLevel(io.Discard, level)
+		return w
+	}}
+
+	return func(next http.Handler) http.Handler {
+
+		log.Info("compress middleware enabled", slog.Int("min_size", cfg.MinSize), slog.Int("level", level))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := Negotiate(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, c: c, encoding: encoding}
+			defer func() {
+				if err := cw.close(); err != nil {
+					log.Debug("failed to finish compressed response", slog.String("error", err.Error()))
+				}
+			}()
+
+			next.ServeHTTP(cw, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Negotiate выбирает кодирование ответа по заголовку Accept-Encoding.
+// Из поддерживаемых кодирований выбирается кодирование с наибольшим весом q, при равных весах - в порядке предпочтения сервера.
+// Возвращает пустую строку, если клиент не принимает ни одно из поддерживаемых кодирований.
+func Negotiate(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		weights[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range encodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+// compressor содержит общие для всех запросов параметры и пулы кодировщиков.
+type compressor struct {
+	minSize int
+	skip    map[string]struct{}
+	pools   map[string]*sync.Pool
+}
+
+// skipType сообщает, что содержимое типа contentType уже сжато.
+func (c *compressor) skipType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if _, ok := c.skip[mediaType]; ok {
+		return true
+	}
+	major, _, _ := strings.Cut(mediaType, "/")
+	_, ok := c.skip[major+"/*"]
+	return ok
+}
+
+// encoder - общий интерфейс gzip.Writer и zlib.Writer.
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+	Flush() error
+}
+
+// compressWriter накапливает начало ответа, пока не станет ясно, нужно ли его сжимать.
+type compressWriter struct {
+	http.ResponseWriter
+	c        *compressor
+	encoding string
+
+	status  int     // Статус, переданный в WriteHeader, 0 - не задан.
+	buf     []byte  // Начало ответа, пока решение о сжатии не принято.
+	decided bool    // Решение о сжатии принято, заголовки отправлены.
+	enc     encoder // Кодировщик, nil - ответ передается без сжатия.
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+
+	// Информационные ответы отправляются сразу и не влияют на итоговый ответ.
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.status = status
+
+	if !bodyAllowed(status) {
+		w.start(false)
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		if !w.eligible() {
+			if _, err := w.flushBuffer(false); err != nil {
+				return 0, err
+			}
+		} else {
+			w.buf = append(w.buf, p...)
+			if len(w.buf) < w.c.minSize {
+				return len(p), nil
+			}
+
+			if _, err := w.flushBuffer(true); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush отправляет накопленные данные клиенту, в том числе не дожидаясь порога MinSize.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_, _ = w.flushBuffer(w.eligible() && len(w.buf) > 0)
+	}
+
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack передает соединение обработчику, если исходный ResponseWriter это поддерживает.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("compress: %T does not implement http.Hijacker", w.ResponseWriter)
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// eligible сообщает, можно ли сжимать ответ с текущими заголовками.
+func (w *compressWriter) eligible() bool {
+	h := w.Header()
+
+	if w.status != 0 && !bodyAllowed(w.status) {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if ct := h.Get("Content-Type"); ct != "" && w.c.skipType(ct) {
+		return false
+	}
+
+	return true
+}
+
+// flushBuffer принимает решение о сжатии и записывает накопленное начало ответа.
+func (w *compressWriter) flushBuffer(compress bool) (int, error) {
+	buffered := w.buf
+	w.start(compress)
+	w.buf = nil
+
+	return w.write(buffered)
+}
+
+// start отправляет заголовки ответа и, если compress истинно, начинает сжатие.
+func (w *compressWriter) start(compress bool) {
+	w.decided = true
+	h := w.Header()
+
+	if compress {
+		// Тип содержимого определяется по несжатым данным, иначе net/http определил бы его по сжатым.
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(w.buf))
+		}
+
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+
+		w.enc = w.c.pools[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// write записывает данные после принятия решения о сжатии.
+func (w *compressWriter) write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// close завершает ответ: отправляет данные, не достигшие порога, без сжатия или завершает поток сжатия.
+func (w *compressWriter) close() error {
+	if !w.decided {
+		if w.status == 0 && len(w.buf) == 0 {
+			return nil
+		}
+
+		_, err := w.flushBuffer(false)
+		return err
+	}
+
+	if w.enc == nil {
+		return nil
+	}
+
+	err := w.enc.Close()
+	w.enc.Reset(io.Discard)
+	w.c.pools[w.encoding].Put(w.enc)
+	w.enc = nil
+
+	return err
+}
+
+// bodyAllowed сообщает, может ли ответ с данным статусом содержать тело.
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified && (status < 100 || status >= 200)
+}
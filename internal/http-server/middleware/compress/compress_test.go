@@ -0,0 +1,145 @@
+package mw_compress_test
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	mw_compress "github.com/instinctG/lru-cache/internal/http-server/middleware/compress"
+	"github.com/instinctG/lru-cache/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{acceptEncoding: "", expected: ""},
+		{acceptEncoding: "gzip", expected: mw_compress.EncodingGzip},
+		{acceptEncoding: "deflate, gzip", expected: mw_compress.EncodingGzip},
+		{acceptEncoding: "gzip;q=0.5, deflate", expected: mw_compress.EncodingDeflate},
+		{acceptEncoding: "GZIP;q=0", expected: ""},
+		{acceptEncoding: "br, zstd", expected: ""},
+		{acceptEncoding: "*", expected: mw_compress.EncodingGzip},
+		{acceptEncoding: "gzip;q=0, *;q=0.1", expected: mw_compress.EncodingDeflate},
+		{acceptEncoding: "identity", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			assert.Equal(t, tt.expected, mw_compress.Negotiate(tt.acceptEncoding))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	mw := mw_compress.New(logger.NewDiscardLogger(), mw_compress.Config{MinSize: 100})
+
+	large := strings.Repeat(`{"key":"value"}`, 20)
+
+	tests := []struct {
+		name             string
+		acceptEncoding   string
+		method           string
+		contentType      string
+		etag             string
+		status           int
+		body             []string
+		expectedEncoding string
+		expectedETag     string
+	}{
+		{name: "Gzip", acceptEncoding: "gzip", contentType: "application/json", etag: `"abc"`, body: []string{large}, expectedEncoding: "gzip", expectedETag: `W/"abc"`},
+		{name: "Deflate", acceptEncoding: "deflate", contentType: "application/json", body: []string{large}, expectedEncoding: "deflate"},
+		{name: "Threshold reached by several writes", acceptEncoding: "gzip", contentType: "application/json", body: []string{large[:60], large[60:]}, expectedEncoding: "gzip"},
+		{name: "Below threshold", acceptEncoding: "gzip", contentType: "application/json", etag: `"abc"`, body: []string{`{"key":"value"}`}, expectedETag: `"abc"`},
+		{name: "Not accepted", contentType: "application/json", body: []string{large}},
+		{name: "Compressed content type", acceptEncoding: "gzip", contentType: "image/png", body: []string{large}},
+		{name: "Content type with parameters", acceptEncoding: "gzip", contentType: "application/zip; name=a", body: []string{large}},
+		{name: "Error status", acceptEncoding: "gzip", contentType: "application/problem+json", status: http.StatusNotFound, body: []string{large}, expectedEncoding: "gzip"},
+		{name: "No content", acceptEncoding: "gzip", status: http.StatusNoContent},
+		{name: "HEAD", acceptEncoding: "gzip", method: http.MethodHead, contentType: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				if tt.etag != "" {
+					w.Header().Set("ETag", tt.etag)
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				for _, chunk := range tt.body {
+					_, _ = io.WriteString(w, chunk)
+				}
+			}))
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			expectedStatus := tt.status
+			if expectedStatus == 0 {
+				expectedStatus = http.StatusOK
+			}
+			assert.Equal(t, expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedEncoding, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+			if tt.contentType != "" {
+				assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+			}
+			if tt.etag != "" {
+				assert.Equal(t, tt.expectedETag, rec.Header().Get("ETag"))
+			}
+
+			var err error
+			var body io.Reader = rec.Body
+			switch tt.expectedEncoding {
+			case "gzip":
+				body, err = gzip.NewReader(rec.Body)
+				require.NoError(t, err)
+			case "deflate":
+				body, err = zlib.NewReader(rec.Body)
+				require.NoError(t, err)
+			}
+
+			b, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, strings.Join(tt.body, ""), string(b))
+		})
+	}
+}
+
+func TestMiddlewareDetectsContentType(t *testing.T) {
+	mw := mw_compress.New(logger.NewDiscardLogger(), mw_compress.Config{Level: 42})
+
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "<html><body>hello</body></html>")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	// Некорректный уровень заменяется уровнем по умолчанию, тип содержимого определяется по несжатым данным
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+}
@@ -7,10 +7,12 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
 )
 
 // New создает middleware для логирования HTTP-запросов.
-// Логирует метод, путь, удаленный адрес, User-Agent, идентификатор запроса и время выполнения запроса.
+// Логирует метод, путь, удаленный адрес, User-Agent, идентификатор запроса, время выполнения запроса
+// и аутентифицированного субъекта, если запрос прошел аутентификацию.
 // Параметры:
 //   - log: объект логгера для записи информации о запросах.
 //
@@ -36,14 +38,25 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 			// Обертка для ResponseWriter для получения статуса и количества записанных байт
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
+			// Субъект запроса становится известен только после аутентификации во внутреннем middleware
+			r, principal := mw_auth.Track(r)
+
 			t1 := time.Now()
 			defer func() {
-				// Логируем завершение обработки запроса
-				entry.Debug("request completed",
+				attrs := []any{
 					slog.Int("status", ww.Status()),
 					slog.Int("bytes", ww.BytesWritten()),
 					slog.String("duration", time.Since(t1).String()),
-				)
+				}
+				if p := principal(); p != nil {
+					attrs = append(attrs,
+						slog.String("principal", p.Name),
+						slog.String("auth_method", p.Method),
+					)
+				}
+
+				// Логируем завершение обработки запроса
+				entry.Debug("request completed", attrs...)
 			}()
 
 			next.ServeHTTP(ww, r)
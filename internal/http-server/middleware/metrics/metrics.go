@@ -0,0 +1,57 @@
+// Package mw_metrics предоставляет middleware для сбора метрик HTTP-запросов.
+package mw_metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/instinctG/lru-cache/internal/metrics"
+)
+
+// New создает middleware, которое считает HTTP-запросы и время их обработки.
+// Метрики группируются по методу, шаблону маршрута chi и статусу ответа.
+// Параметры:
+//   - reg: реестр, в котором регистрируются метрики.
+//
+// Возвращает функцию middleware, которая собирает метрики и передает управление следующему обработчику.
+func New(reg *metrics.Registry) func(next http.Handler) http.Handler {
+	requests := reg.NewCounterVec("http_requests_total",
+		"Total number of HTTP requests by method, route and status.", "method", "route", "status")
+	duration := reg.NewHistogramVec("http_request_duration_seconds",
+		"HTTP request latency in seconds by method and route.", metrics.DefBuckets, "method", "route")
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			t1 := time.Now()
+			defer func() {
+				route := routePattern(r)
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				requests.Inc(r.Method, route, strconv.Itoa(status))
+				duration.ObserveDuration(t1, r.Method, route)
+			}()
+
+			next.ServeHTTP(ww, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// routePattern возвращает шаблон маршрута chi, чтобы ключи из пути не попадали в метки.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return "unmatched"
+}
@@ -0,0 +1,77 @@
+package mw_ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+)
+
+// NewAuthFailures создает middleware, которое ограничивает частоту неудачных попыток аутентификации с одного IP-адреса.
+// Должно выполняться до аутентификации: ответ 401 списывает запрос из бюджета IP-адреса, а когда бюджет исчерпан,
+// запросы с этого адреса отклоняются с кодом 429 и заголовком Retry-After без проверки учетных данных.
+// Успешно аутентифицированные запросы бюджет не расходуют.
+// Параметры:
+//   - log: логгер для записи отклоненных запросов.
+//   - limit: бюджет неудачных попыток аутентификации.
+//
+// Возвращает функцию middleware, которая передает управление следующему обработчику, если бюджет не исчерпан.
+func NewAuthFailures(log *slog.Logger, limit Limit) func(next http.Handler) http.Handler {
+	l := &limiter{now: time.Now, buckets: make(map[string]*bucket)}
+
+	return func(next http.Handler) http.Handler {
+
+		log := log.With(
+			slog.String("component", "middleware/ratelimit"),
+		)
+		log.Info("auth failures rate limit enabled", slog.Float64("rate", limit.Rate), slog.Int("burst", burstOf(limit)))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			client := ipKey(r)
+
+			if wait := l.wait(client, limit); wait > 0 {
+				log.Debug("auth failures rate limit exceeded", slog.String("client", client))
+
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+				problem.Write(w, r, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited,
+					fmt.Sprintf("too many failed authentication attempts, limit is %g per second", limit.Rate)))
+
+				return
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			if ww.Status() == http.StatusUnauthorized {
+				l.take(client, limit)
+			}
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// wait возвращает время в секундах до пополнения бюджета key на один запрос, 0 - бюджет не исчерпан.
+// В отличие от take, бюджет не расходуется.
+func (l *limiter) wait(key string, limit Limit) float64 {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		return 0
+	}
+
+	tokens := math.Min(float64(burstOf(limit)), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	if tokens >= 1 {
+		return 0
+	}
+	return (1 - tokens) / limit.Rate
+}
@@ -0,0 +1,59 @@
+package mw_ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+)
+
+// NewInFlight создает middleware, которое ограничивает число одновременно обрабатываемых запросов.
+// Запросы сверх лимита не ждут в очереди, а сразу отклоняются с кодом 503 и заголовком Retry-After,
+// чтобы под нагрузкой очередью не становился мьютекс кэша.
+// Параметры:
+//   - log: логгер для записи отклоненных запросов.
+//   - max: максимальное число одновременно обрабатываемых запросов.
+//   - exempt: пути, которые обрабатываются без учета лимита (например, проверки состояния).
+//
+// Возвращает функцию middleware, которая передает управление следующему обработчику, если лимит не превышен.
+func NewInFlight(log *slog.Logger, max int, exempt ...string) func(next http.Handler) http.Handler {
+	slots := make(chan struct{}, max)
+
+	skip := make(map[string]struct{}, len(exempt))
+	for _, path := range exempt {
+		skip[path] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+
+		log := log.With(
+			slog.String("component", "middleware/inflight"),
+		)
+		log.Info("in-flight limit middleware enabled", slog.Int("max_in_flight", max))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := skip[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			default:
+				log.Debug("in-flight limit exceeded", slog.String("path", r.URL.Path))
+
+				w.Header().Set("Retry-After", "1")
+				problem.Write(w, r, problem.New(http.StatusServiceUnavailable, problem.CodeOverloaded,
+					fmt.Sprintf("server is processing the maximum of %d requests", max)))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
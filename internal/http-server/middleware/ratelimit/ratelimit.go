@@ -0,0 +1,262 @@
+// Package mw_ratelimit предоставляет middleware для ограничения частоты запросов клиентов
+// и числа одновременно обрабатываемых запросов.
+package mw_ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+)
+
+// sweepInterval - период удаления корзин клиентов, бюджет которых полностью пополнился.
+const sweepInterval = time.Minute
+
+// Limit задает бюджет запросов клиента по алгоритму token bucket.
+type Limit struct {
+	Rate  float64 // Количество запросов в секунду, на которое пополняется бюджет. 0 - без ограничений.
+	Burst int     // Максимальное количество запросов подряд. Если не задано, равно Rate с округлением вверх.
+}
+
+// Config содержит параметры ограничения частоты запросов.
+type Config struct {
+	Default      Limit            // Бюджет клиента для маршрутов без собственного бюджета.
+	Routes       map[string]Limit // Бюджеты маршрутов по шаблону chi ("/api/lru") или методу и шаблону ("DELETE /api/lru").
+	AuthFailures Limit            // Бюджет неудачных попыток аутентификации с одного IP-адреса (см. NewAuthFailures).
+	// Exempt сообщает, что запрос уже учтен и не списывается из бюджета, например запрос,
+	// пересланный участником кластера, который сам ограничил клиента. nil - ограничиваются все запросы.
+	Exempt func(r *http.Request) bool
+}
+
+// Enabled сообщает, задан ли хотя бы один бюджет.
+func (c Config) Enabled() bool {
+	return c.Default.Rate > 0 || len(c.Routes) > 0
+}
+
+// bucket хранит оставшийся бюджет клиента.
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+// full сообщает, пополнится ли бюджет полностью к моменту now.
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(burstOf(b.limit))
+}
+
+// limiter ведет бюджеты клиентов.
+type limiter struct {
+	cfg    Config
+	routes chi.Routes
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New создает middleware, которое ограничивает частоту запросов клиента.
+// Клиент определяется по имени аутентифицированного субъекта (API-ключу или JWT), иначе по IP-адресу.
+// Бюджеты маршрутов ведутся отдельно от бюджета по умолчанию, который общий для всех остальных маршрутов.
+// Запросы сверх бюджета отклоняются с кодом 429 и заголовком Retry-After.
+// Параметры:
+//   - log: логгер для записи отклоненных запросов.
+//   - cfg: бюджеты запросов.
+//   - routes: маршрутизатор, по которому определяется шаблон маршрута запроса.
+//
+// Возвращает функцию middleware, которая добавляет заголовки RateLimit-* и передает управление следующему обработчику.
+func New(log *slog.Logger, cfg Config, routes chi.Routes) func(next http.Handler) http.Handler {
+	l := &limiter{cfg: cfg, routes: routes, now: time.Now, buckets: make(map[string]*bucket)}
+
+	return func(next http.Handler) http.Handler {
+
+		log := log.With(
+			slog.String("component", "middleware/ratelimit"),
+		)
+		log.Info("rate limit middleware enabled", slog.Int("route_budgets", len(cfg.Routes)))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Exempt != nil && cfg.Exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			budget, limit := l.budget(r)
+			if limit.Rate <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := clientKey(r)
+			allowed, remaining, wait := l.take(budget+"\xff"+client, limit)
+
+			burst := burstOf(limit)
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(float64(burst-remaining)/limit.Rate)))
+
+			if !allowed {
+				log.Debug("rate limit exceeded", slog.String("client", client), slog.String("budget", budget))
+
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+				problem.Write(w, r, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited,
+					fmt.Sprintf("rate limit of %g requests per second exceeded", limit.Rate)))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// budget возвращает имя и размер бюджета, которому подчиняется запрос.
+func (l *limiter) budget(r *http.Request) (string, Limit) {
+	if len(l.cfg.Routes) > 0 && l.routes != nil {
+		path := r.URL.RawPath
+		if path == "" {
+			path = r.URL.Path
+		}
+
+		rctx := chi.NewRouteContext()
+		if l.routes.Match(rctx, r.Method, path) {
+			pattern := rctx.RoutePattern()
+			if limit, ok := l.cfg.Routes[r.Method+" "+pattern]; ok {
+				return r.Method + " " + pattern, limit
+			}
+			if limit, ok := l.cfg.Routes[pattern]; ok {
+				return pattern, limit
+			}
+		}
+	}
+
+	return "", l.cfg.Default
+}
+
+// take списывает один запрос из бюджета key.
+// Возвращает, разрешен ли запрос, оставшийся бюджет и время до пополнения бюджета на один запрос в секундах.
+func (l *limiter) take(key string, limit Limit) (bool, int, float64) {
+	now := l.now()
+	burst := float64(burstOf(limit))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limit: limit, tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, 0, (1 - b.tokens) / limit.Rate
+	}
+
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// sweep периодически удаляет полностью пополнившиеся корзины: новая корзина клиента создается с полным бюджетом,
+// поэтому удаление не меняет его лимит, но не дает накапливаться корзинам ушедших клиентов.
+// Должен вызываться с захваченным l.mu.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.full(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientKey определяет клиента по аутентифицированному субъекту или IP-адресу.
+func clientKey(r *http.Request) string {
+	if p, ok := mw_auth.FromContext(r.Context()); ok {
+		return "principal:" + p.Name
+	}
+
+	return ipKey(r)
+}
+
+// ipKey определяет клиента по IP-адресу.
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func burstOf(limit Limit) int {
+	if limit.Burst > 0 {
+		return limit.Burst
+	}
+	return int(math.Max(1, math.Ceil(limit.Rate)))
+}
+
+func ceilSeconds(seconds float64) int {
+	return int(math.Ceil(seconds))
+}
+
+// ParseLimit разбирает бюджет в формате "rate" или "rate:burst", например "10:20".
+func ParseLimit(spec string) (Limit, error) {
+	rate, burst, hasBurst := strings.Cut(strings.TrimSpace(spec), ":")
+
+	var limit Limit
+	var err error
+	if limit.Rate, err = strconv.ParseFloat(strings.TrimSpace(rate), 64); err != nil || limit.Rate < 0 {
+		return Limit{}, fmt.Errorf("invalid rate %q", rate)
+	}
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || limit.Burst < 0 {
+			return Limit{}, fmt.Errorf("invalid burst %q", burst)
+		}
+	}
+
+	return limit, nil
+}
+
+// ParseRoutes разбирает бюджеты маршрутов в формате "маршрут=rate:burst,маршрут=rate:burst",
+// где маршрут - шаблон chi, перед которым может быть указан метод, например "DELETE /api/lru=1:1".
+func ParseRoutes(spec string) (map[string]Limit, error) {
+	routes := make(map[string]Limit)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, limitSpec, ok := strings.Cut(entry, "=")
+		route = strings.Join(strings.Fields(route), " ")
+		if !ok || route == "" {
+			return nil, fmt.Errorf("invalid route limit %q, expected route=rate:burst", entry)
+		}
+
+		limit, err := ParseLimit(limitSpec)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: %w", route, err)
+		}
+		routes[route] = limit
+	}
+	return routes, nil
+}
@@ -0,0 +1,222 @@
+package mw_ratelimit_test
+
+import (
+	"github.com/go-chi/chi/v5"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	mw_ratelimit "github.com/instinctG/lru-cache/internal/http-server/middleware/ratelimit"
+	"github.com/instinctG/lru-cache/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func newRouter(cfg mw_ratelimit.Config) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(mw_ratelimit.New(logger.NewDiscardLogger(), cfg, r))
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r.Get("/api/lru/{key}", ok)
+	r.Get("/api/lru", ok)
+	r.Delete("/api/lru", ok)
+
+	return r
+}
+
+func serve(r http.Handler, method, target, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit(t *testing.T) {
+	r := newRouter(mw_ratelimit.Config{Default: mw_ratelimit.Limit{Rate: 1, Burst: 2}})
+
+	rec := serve(r, http.MethodGet, "/api/lru/a", "10.0.0.1:1000")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+
+	// Бюджет по умолчанию общий для всех маршрутов без собственного бюджета
+	rec = serve(r, http.MethodGet, "/api/lru", "10.0.0.1:1001")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	rec = serve(r, http.MethodGet, "/api/lru/b", "10.0.0.1:1002")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `"code":"rate_limited"`)
+
+	// Бюджет другого клиента не затронут
+	rec = serve(r, http.MethodGet, "/api/lru/a", "10.0.0.2:1000")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRateLimitRoutes(t *testing.T) {
+	routes, err := mw_ratelimit.ParseRoutes("DELETE /api/lru=1:1, /api/lru/{key}=0")
+	require.NoError(t, err)
+
+	r := newRouter(mw_ratelimit.Config{Default: mw_ratelimit.Limit{Rate: 1, Burst: 1}, Routes: routes})
+
+	const client = "10.0.0.1:1000"
+
+	// Маршрут с нулевым бюджетом не ограничивается
+	for i := 0; i < 5; i++ {
+		rec := serve(r, http.MethodGet, "/api/lru/a", client)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	}
+
+	// Бюджет метода и маршрута ведется отдельно от бюджета по умолчанию
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodDelete, "/api/lru", client).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(r, http.MethodDelete, "/api/lru", client).Code)
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/api/lru", client).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(r, http.MethodGet, "/api/lru", client).Code)
+}
+
+func TestRateLimitByPrincipal(t *testing.T) {
+	limit := mw_ratelimit.New(logger.NewDiscardLogger(), mw_ratelimit.Config{Default: mw_ratelimit.Limit{Rate: 1}}, nil)
+	h := limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	request := func(name, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req = req.WithContext(mw_auth.NewContext(req.Context(), &mw_auth.Principal{Name: name}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Бюджет субъекта не зависит от адреса клиента
+	assert.Equal(t, http.StatusOK, request("batch", "10.0.0.1:1000"))
+	assert.Equal(t, http.StatusTooManyRequests, request("batch", "10.0.0.2:1000"))
+	assert.Equal(t, http.StatusOK, request("consumer", "10.0.0.1:1000"))
+}
+
+func TestRateLimitExempt(t *testing.T) {
+	r := newRouter(mw_ratelimit.Config{
+		Default: mw_ratelimit.Limit{Rate: 1, Burst: 1},
+		Exempt:  func(r *http.Request) bool { return r.Header.Get("X-Exempt") != "" },
+	})
+
+	require.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/api/lru/a", "10.0.0.1:1000").Code)
+	require.Equal(t, http.StatusTooManyRequests, serve(r, http.MethodGet, "/api/lru/a", "10.0.0.1:1000").Code)
+
+	// Освобожденные запросы не ограничиваются и не расходуют бюджет.
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/lru/a", nil)
+		req.RemoteAddr = "10.0.0.1:1000"
+		req.Header.Set("X-Exempt", "1")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestAuthFailures(t *testing.T) {
+	limit := mw_ratelimit.NewAuthFailures(logger.NewDiscardLogger(), mw_ratelimit.Limit{Rate: 0.01, Burst: 2})
+	h := limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(key, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/lru", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Успешные запросы бюджет не расходуют
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusOK, request("valid", "10.0.0.1:1000").Code)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request("wrong", "10.0.0.1:1000").Code)
+	assert.Equal(t, http.StatusUnauthorized, request("wrong", "10.0.0.1:1001").Code)
+
+	// Бюджет исчерпан: запросы с адреса отклоняются до проверки учетных данных
+	rec := request("valid", "10.0.0.1:1002")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), `"code":"rate_limited"`)
+
+	// Бюджет другого адреса не затронут
+	assert.Equal(t, http.StatusUnauthorized, request("wrong", "10.0.0.2:1000").Code)
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected mw_ratelimit.Limit
+		wantErr  bool
+	}{
+		{spec: "10", expected: mw_ratelimit.Limit{Rate: 10}},
+		{spec: "0.5:3", expected: mw_ratelimit.Limit{Rate: 0.5, Burst: 3}},
+		{spec: "abc", wantErr: true},
+		{spec: "-1", wantErr: true},
+		{spec: "1:x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			limit, err := mw_ratelimit.ParseLimit(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, limit)
+		})
+	}
+
+	_, err := mw_ratelimit.ParseRoutes("/api/lru")
+	assert.Error(t, err)
+}
+
+func TestInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	h := mw_ratelimit.NewInFlight(logger.NewDiscardLogger(), 1, "/healthz")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve(h, http.MethodGet, "/slow", "10.0.0.1:1000")
+	}()
+	<-started
+
+	rec := serve(h, http.MethodGet, "/api/lru", "10.0.0.2:1000")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), `"code":"overloaded"`)
+
+	// Исключенные пути обрабатываются без учета лимита
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodGet, "/healthz", "10.0.0.2:1000").Code)
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodGet, "/api/lru", "10.0.0.2:1000").Code)
+}
@@ -0,0 +1,72 @@
+// Package problem формирует ответы с ошибками в формате RFC 7807 (application/problem+json)
+// со стабильными машиночитаемыми кодами ошибок.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Стабильные машиночитаемые коды ошибок, возвращаемые в поле code ответа application/problem+json.
+const (
+	CodeKeyNotFound      = "key_not_found"     // CodeKeyNotFound - ключ не найден в кэше.
+	CodeCacheNotFound    = "cache_not_found"   // CodeCacheNotFound - именованный кэш не найден.
+	CodeTenantNotFound   = "tenant_not_found"  // CodeTenantNotFound - арендатор не найден.
+	CodeValidationFailed = "validation_failed" // CodeValidationFailed - запрос не прошел валидацию.
+	CodeEmptyBody        = "empty_body"        // CodeEmptyBody - тело запроса пустое.
+	CodeInvalidJSON      = "invalid_json"      // CodeInvalidJSON - тело запроса не является корректным JSON.
+	CodeBodyTooLarge     = "body_too_large"    // CodeBodyTooLarge - тело запроса превышает допустимый размер.
+	CodeValueTooLarge    = "value_too_large"   // CodeValueTooLarge - значение элемента превышает допустимый размер.
+	CodeQuotaExceeded    = "quota_exceeded"    // CodeQuotaExceeded - элемент превышает квоту арендатора на размер.
+	CodeConflict         = "conflict"          // CodeConflict - запрос конфликтует с текущим состоянием ресурса.
+	CodeUnauthorized     = "unauthorized"      // CodeUnauthorized - запрос не аутентифицирован.
+	CodeForbidden        = "forbidden"         // CodeForbidden - у субъекта нет прав на операцию.
+	CodeRateLimited      = "rate_limited"      // CodeRateLimited - клиент превысил допустимую частоту запросов.
+	CodeOverloaded       = "overloaded"        // CodeOverloaded - сервис обрабатывает максимальное число запросов.
+	CodePeerUnavailable  = "peer_unavailable"  // CodePeerUnavailable - узел кластера, которому принадлежит ключ, недоступен.
+	CodeInternal         = "internal_error"    // CodeInternal - внутренняя ошибка сервиса.
+)
+
+// ContentType - тип содержимого ответа с ошибкой согласно RFC 7807.
+const ContentType = "application/problem+json"
+
+// Problem представляет ответ с ошибкой в формате RFC 7807 (application/problem+json).
+type Problem struct {
+	Type     string       `json:"type"`               // URI типа ошибки.
+	Title    string       `json:"title"`              // Краткое описание типа ошибки.
+	Status   int          `json:"status"`             // HTTP-статус ответа.
+	Detail   string       `json:"detail,omitempty"`   // Подробное описание конкретной ошибки.
+	Instance string       `json:"instance,omitempty"` // Путь запроса, в котором возникла ошибка.
+	Code     string       `json:"code"`               // Стабильный машиночитаемый код ошибки.
+	Errors   []FieldError `json:"errors,omitempty"`   // Список полей, не прошедших валидацию.
+}
+
+// FieldError описывает ошибку валидации отдельного поля запроса.
+type FieldError struct {
+	Field   string `json:"field"`   // Имя поля в JSON.
+	Rule    string `json:"rule"`    // Нарушенное правило валидации.
+	Message string `json:"message"` // Описание ошибки.
+}
+
+// New создает Problem с заданным статусом, кодом и описанием.
+func New(status int, code, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Write отправляет ответ с ошибкой в формате application/problem+json.
+// Если Instance не задан, в него записывается путь запроса.
+func Write(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,239 @@
+// Package tlsconfig формирует настройки TLS HTTP-сервера: сертификат сервера с перезагрузкой при изменении файлов,
+// проверку клиентских сертификатов (mTLS) и минимальную версию протокола.
+package tlsconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	sl "github.com/instinctG/lru-cache/internal/logger"
+)
+
+// Режимы проверки клиентских сертификатов.
+const (
+	ClientAuthRequire       = "require"         // ClientAuthRequire - клиент обязан предъявить сертификат, подписанный доверенным CA.
+	ClientAuthVerifyIfGiven = "verify_if_given" // ClientAuthVerifyIfGiven - сертификат проверяется, только если клиент его предъявил.
+)
+
+// Config содержит параметры TLS сервера.
+type Config struct {
+	CertFile     string // PEM-файл сертификата сервера.
+	KeyFile      string // PEM-файл закрытого ключа сервера.
+	ClientCAFile string // PEM-файл сертификатов CA для проверки клиентов, пустой - клиенты не проверяются.
+	ClientAuth   string // Режим проверки клиентских сертификатов, по умолчанию ClientAuthRequire.
+	MinVersion   string // Минимальная версия TLS: "1.0", "1.1", "1.2" или "1.3", по умолчанию "1.2".
+}
+
+// Enabled сообщает, заданы ли параметры TLS. CA клиентских сертификатов без сертификата сервера
+// тоже включает TLS, чтобы New вернул ошибку, а не сервер работал по HTTP без проверки клиентов.
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.ClientCAFile != ""
+}
+
+// New создает настройки TLS сервера и загрузчик сертификата, который перечитывает файлы при их изменении.
+// Параметры:
+//   - cfg: параметры TLS.
+//
+// Возвращает: настройки TLS, загрузчик сертификата и ошибку, если файлы не удалось загрузить.
+func New(cfg Config) (*tls.Config, *CertReloader, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, nil, errors.New("tls: both certificate and key files must be set")
+	}
+
+	minVersion, err := ParseVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reloader, err := NewCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		if tlsCfg.ClientCAs, err = loadCertPool(x509.NewCertPool(), cfg.ClientCAFile); err != nil {
+			return nil, nil, err
+		}
+
+		switch cfg.ClientAuth {
+		case "", ClientAuthRequire:
+			tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		case ClientAuthVerifyIfGiven:
+			tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, nil, fmt.Errorf("tls: unknown client auth mode %q", cfg.ClientAuth)
+		}
+	}
+
+	return tlsCfg, reloader, nil
+}
+
+// NewClient создает настройки TLS для соединений с другими узлами, например реплики с ведущим узлом.
+// Сертификат узла проверяется системными CA и CA из ClientCAFile: при mTLS узлы обычно подписаны тем же CA,
+// что и клиенты. Если узел запрашивает сертификат клиента, предъявляется текущий сертификат reloader,
+// поэтому он должен допускать аутентификацию клиента (extKeyUsage clientAuth). Если reloader равен nil,
+// сертификат не предъявляется.
+func NewClient(cfg Config, reloader *CertReloader) (*tls.Config, error) {
+	minVersion, err := ParseVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg := &tls.Config{MinVersion: minVersion}
+
+	if cfg.ClientCAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if tlsCfg.RootCAs, err = loadCertPool(pool, cfg.ClientCAFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if reloader != nil {
+		tlsCfg.GetClientCertificate = reloader.GetClientCertificate
+	}
+
+	return tlsCfg, nil
+}
+
+// loadCertPool добавляет в pool сертификаты из PEM-файла file.
+func loadCertPool(pool *x509.CertPool, file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("tls: no certificates found in %s", file)
+	}
+	return pool, nil
+}
+
+// ParseVersion разбирает версию TLS в формате "1.2". Пустая строка соответствует TLS 1.2.
+func ParseVersion(version string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(version), "tls") {
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "", "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("tls: unknown version %q", version)
+	}
+}
+
+// CertReloader хранит сертификат сервера и перечитывает его, когда изменяются файлы сертификата или ключа.
+// Новые соединения используют новый сертификат, уже установленные соединения не прерываются.
+type CertReloader struct {
+	certFile, keyFile string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	// certPEM и keyPEM хранят содержимое загруженных файлов, чтобы перечитывать сертификат только при изменении.
+	certPEM, keyPEM []byte
+}
+
+// NewCertReloader загружает сертификат сервера из PEM-файлов.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate возвращает текущий сертификат сервера. Используется как tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// GetClientCertificate возвращает текущий сертификат как сертификат клиента.
+// Используется как tls.Config.GetClientCertificate.
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.GetCertificate(nil)
+}
+
+// Reload перечитывает файлы сертификата и ключа.
+// Если файлы не изменились или новая пара не прошла проверку, продолжает использоваться прежний сертификат.
+// Возвращает: true, если сертификат заменен, и ошибку загрузки.
+func (r *CertReloader) Reload() (bool, error) {
+	certPEM, err := os.ReadFile(r.certFile)
+	if err != nil {
+		return false, fmt.Errorf("tls: %w", err)
+	}
+	keyPEM, err := os.ReadFile(r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("tls: %w", err)
+	}
+
+	r.mu.RLock()
+	unchanged := bytes.Equal(certPEM, r.certPEM) && bytes.Equal(keyPEM, r.keyPEM)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("tls: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert, r.certPEM, r.keyPEM = &cert, certPEM, keyPEM
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+// Watch проверяет файлы сертификата с периодом interval, пока не будет отменен ctx.
+// Ошибки загрузки записываются в лог, при этом продолжает использоваться прежний сертификат.
+// Если interval не положителен, перезагрузка отключена и Watch сразу завершается.
+func (r *CertReloader) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	log = log.With(slog.String("component", "tls"), slog.String("cert_file", r.certFile))
+
+	if interval <= 0 {
+		log.Info("certificate reload disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				log.Error("failed to reload certificate", sl.Err(err))
+				continue
+			}
+			if reloaded {
+				log.Info("certificate reloaded")
+			}
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	"github.com/instinctG/lru-cache/internal/http-server/tlsconfig"
+	"github.com/instinctG/lru-cache/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert содержит сертификат и закрытый ключ, сгенерированные для теста.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+var serial int64
+
+// newCert создает сертификат, подписанный parent. Если parent не задан, создается самоподписанный CA.
+func newCert(t *testing.T, cn string, parent *testCert, usage x509.ExtKeyUsage) testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// pki содержит CA, сертификат сервера и клиента, записанные в файлы.
+type pki struct {
+	ca, server, client testCert
+	dir                string
+}
+
+func newPKI(t *testing.T) *pki {
+	p := &pki{dir: t.TempDir()}
+	p.ca = newCert(t, "test-ca", nil, x509.ExtKeyUsageAny)
+	p.server = newCert(t, "server", &p.ca, x509.ExtKeyUsageServerAuth)
+	p.client = newCert(t, "svc", &p.ca, x509.ExtKeyUsageClientAuth)
+
+	p.write(t, "ca.pem", p.ca.certPEM)
+	p.writeServer(t, p.server)
+	return p
+}
+
+func (p *pki) path(name string) string { return filepath.Join(p.dir, name) }
+
+func (p *pki) write(t *testing.T, name string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(p.path(name), data, 0o600))
+}
+
+func (p *pki) writeServer(t *testing.T, c testCert) {
+	p.write(t, "server.pem", c.certPEM)
+	p.write(t, "server-key.pem", c.keyPEM)
+}
+
+func (p *pki) config(clientAuth string) tlsconfig.Config {
+	return tlsconfig.Config{
+		CertFile:     p.path("server.pem"),
+		KeyFile:      p.path("server-key.pem"),
+		ClientCAFile: p.path("ca.pem"),
+		ClientAuth:   clientAuth,
+	}
+}
+
+// serve запускает HTTPS-сервер, который отвечает именем субъекта из клиентского сертификата.
+func serve(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	authenticator := mw_auth.NewClientCertAuthenticator()
+	srv := &http.Server{
+		TLSConfig: cfg,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p, err := authenticator.Authenticate(r); err == nil {
+				_, _ = io.WriteString(w, p.Name)
+			}
+		}),
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+	go func() { _ = srv.ServeTLS(ln, "", "") }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	return "https://" + ln.Addr().String()
+}
+
+func (p *pki) httpClient(withCert bool, maxVersion uint16) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(p.ca.cert)
+
+	cfg := &tls.Config{RootCAs: roots, MaxVersion: maxVersion}
+	if withCert {
+		cfg.Certificates = []tls.Certificate{{Certificate: [][]byte{p.client.cert.Raw}, PrivateKey: p.client.key}}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, DisableKeepAlives: true}}
+}
+
+func get(client *http.Client, url string) (string, *tls.ConnectionState, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return string(body), resp.TLS, err
+}
+
+func TestMutualTLS(t *testing.T) {
+	p := newPKI(t)
+
+	tests := []struct {
+		name       string
+		clientAuth string
+		withCert   bool
+		expected   string
+		wantErr    bool
+	}{
+		{name: "Require with certificate", clientAuth: tlsconfig.ClientAuthRequire, withCert: true, expected: "svc"},
+		{name: "Require without certificate", clientAuth: tlsconfig.ClientAuthRequire, wantErr: true},
+		{name: "Verify if given with certificate", clientAuth: tlsconfig.ClientAuthVerifyIfGiven, withCert: true, expected: "svc"},
+		{name: "Verify if given without certificate", clientAuth: tlsconfig.ClientAuthVerifyIfGiven, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, _, err := tlsconfig.New(p.config(tt.clientAuth))
+			require.NoError(t, err)
+
+			body, _, err := get(p.httpClient(tt.withCert, 0), serve(t, cfg))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, body)
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	p := newPKI(t)
+	// Сертификат узла служит и сертификатом сервера, и сертификатом клиента при подключении к другому узлу.
+	p.writeServer(t, newCert(t, "node", &p.ca, x509.ExtKeyUsageAny))
+
+	settings := p.config(tlsconfig.ClientAuthRequire)
+	serverCfg, reloader, err := tlsconfig.New(settings)
+	require.NoError(t, err)
+	url := serve(t, serverCfg)
+
+	clientCfg, err := tlsconfig.NewClient(settings, reloader)
+	require.NoError(t, err)
+	body, _, err := get(&http.Client{Transport: &http.Transport{TLSClientConfig: clientCfg, DisableKeepAlives: true}}, url)
+	require.NoError(t, err)
+	assert.Equal(t, "node", body)
+
+	// Без сертификата узел не проходит проверку клиента.
+	clientCfg, err = tlsconfig.NewClient(settings, nil)
+	require.NoError(t, err)
+	_, _, err = get(&http.Client{Transport: &http.Transport{TLSClientConfig: clientCfg, DisableKeepAlives: true}}, url)
+	assert.Error(t, err)
+
+	settings.MinVersion = "2.0"
+	_, err = tlsconfig.NewClient(settings, reloader)
+	assert.Error(t, err)
+}
+
+func TestMinVersion(t *testing.T) {
+	p := newPKI(t)
+
+	settings := p.config("")
+	settings.MinVersion = "1.3"
+	cfg, _, err := tlsconfig.New(settings)
+	require.NoError(t, err)
+	url := serve(t, cfg)
+
+	_, _, err = get(p.httpClient(true, tls.VersionTLS12), url)
+	assert.Error(t, err)
+
+	_, state, err := get(p.httpClient(true, 0), url)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), state.Version)
+}
+
+func TestCertReloader(t *testing.T) {
+	p := newPKI(t)
+
+	cfg, reloader, err := tlsconfig.New(p.config(""))
+	require.NoError(t, err)
+	url := serve(t, cfg)
+
+	serverSerial := func() *big.Int {
+		_, state, err := get(p.httpClient(true, 0), url)
+		require.NoError(t, err)
+		return state.PeerCertificates[0].SerialNumber
+	}
+
+	assert.Equal(t, p.server.cert.SerialNumber, serverSerial())
+
+	// Файлы не изменились
+	reloaded, err := reloader.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// Новый сертификат используется без перезапуска сервера
+	renewed := newCert(t, "server", &p.ca, x509.ExtKeyUsageServerAuth)
+	p.writeServer(t, renewed)
+
+	reloaded, err = reloader.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, renewed.cert.SerialNumber, serverSerial())
+
+	// Некорректная пара не заменяет действующий сертификат
+	p.write(t, "server-key.pem", p.client.keyPEM)
+
+	_, err = reloader.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, renewed.cert.SerialNumber, serverSerial())
+}
+
+func TestCertReloader_WatchDisabled(t *testing.T) {
+	p := newPKI(t)
+
+	_, reloader, err := tlsconfig.New(p.config(""))
+	require.NoError(t, err)
+
+	// Неположительный период отключает перезагрузку вместо паники time.NewTicker
+	for _, interval := range []time.Duration{0, -time.Second} {
+		done := make(chan struct{})
+		go func() {
+			reloader.Watch(context.Background(), logger.NewDiscardLogger(), interval)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Watch with interval %s did not return", interval)
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected uint16
+		wantErr  bool
+	}{
+		{version: "", expected: tls.VersionTLS12},
+		{version: "1.2", expected: tls.VersionTLS12},
+		{version: "1.3", expected: tls.VersionTLS13},
+		{version: "TLS1.1", expected: tls.VersionTLS11},
+		{version: "2.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			v, err := tlsconfig.ParseVersion(tt.version)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	p := newPKI(t)
+
+	_, _, err := tlsconfig.New(tlsconfig.Config{CertFile: p.path("server.pem")})
+	assert.Error(t, err)
+
+	settings := p.config("optional")
+	_, _, err = tlsconfig.New(settings)
+	assert.Error(t, err)
+
+	settings = p.config("")
+	settings.ClientCAFile = p.path("server-key.pem")
+	_, _, err = tlsconfig.New(settings)
+	assert.Error(t, err)
+
+	// CA клиентов без сертификата сервера не оставляет сервер на HTTP
+	settings = tlsconfig.Config{ClientCAFile: p.path("ca.pem")}
+	assert.True(t, settings.Enabled())
+	_, _, err = tlsconfig.New(settings)
+	assert.Error(t, err)
+}
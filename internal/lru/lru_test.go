@@ -5,6 +5,7 @@ import (
 	"github.com/instinctG/lru-cache/internal/lru"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"sync"
 	"testing"
 	"time"
 )
@@ -193,3 +194,309 @@ func TestLRUCache_MaxCapacityWithEviction(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "value2", value)
 }
+
+func TestLRUCache_Compute(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLRUCache(2, time.Minute)
+
+	appendFn := func(item string) lru.ComputeFunc {
+		return func(old interface{}, exists bool) (interface{}, bool) {
+			if !exists {
+				return []string{item}, true
+			}
+			return append(old.([]string), item), true
+		}
+	}
+
+	// Создаем элемент и дописываем в него значение
+	_, err := cache.Compute(ctx, "list", appendFn("a"))
+	require.NoError(t, err)
+	value, err := cache.Compute(ctx, "list", appendFn("b"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, value)
+
+	value, expiresAt, err := cache.Get(ctx, "list")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, value)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, time.Second)
+
+	// keep == false удаляет элемент
+	_, err = cache.Compute(ctx, "list", func(old interface{}, exists bool) (interface{}, bool) {
+		assert.True(t, exists)
+		return nil, false
+	})
+	assert.ErrorIs(t, err, lru.ErrKeyNotFound)
+
+	_, _, err = cache.Get(ctx, "list")
+	assert.ErrorIs(t, err, lru.ErrKeyNotFound)
+}
+
+func TestLRUCache_ComputePromotesKey(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLRUCache(2, time.Minute)
+
+	require.NoError(t, cache.Put(ctx, "key1", 1, time.Minute))
+	require.NoError(t, cache.Put(ctx, "key2", 2, time.Minute))
+
+	// Compute делает "key1" недавно использованным
+	_, err := cache.Compute(ctx, "key1", func(old interface{}, exists bool) (interface{}, bool) {
+		return old.(int) + 1, true
+	})
+	require.NoError(t, err)
+
+	// Добавляем третий элемент, "key2" должен быть удален
+	require.NoError(t, cache.Put(ctx, "key3", 3, time.Minute))
+
+	_, _, err = cache.Get(ctx, "key2")
+	assert.ErrorIs(t, err, lru.ErrKeyNotFound)
+
+	value, _, err := cache.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, value)
+}
+
+func TestLRUCache_ComputeConcurrent(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLRUCache(10, time.Minute)
+
+	const workers = 100
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.Compute(ctx, "counter", func(old interface{}, exists bool) (interface{}, bool) {
+				if !exists {
+					return 1, true
+				}
+				return old.(int) + 1, true
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	value, _, err := cache.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, workers, value)
+}
+
+func TestLRUCache_ComputeDoesNotBlockOtherKeys(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLRUCache(10, time.Minute)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		_, _ = cache.Compute(ctx, "slow", func(old interface{}, exists bool) (interface{}, bool) {
+			close(started)
+			<-release
+			return "slow", true
+		})
+	}()
+	<-started
+
+	// Пока выполняется Compute для "slow", остальные ключи доступны
+	require.NoError(t, cache.Put(ctx, "fast", "fast", time.Minute))
+	value, _, err := cache.Get(ctx, "fast")
+	require.NoError(t, err)
+	assert.Equal(t, "fast", value)
+
+	close(release)
+	<-done
+
+	value, _, err = cache.Get(ctx, "slow")
+	require.NoError(t, err)
+	assert.Equal(t, "slow", value)
+}
+
+func TestLRUCache_UpdateKeepsTTL(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLRUCache(2, time.Minute)
+
+	incr := func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return 1, true
+		}
+		return old.(int) + 1, true
+	}
+
+	// Новый элемент получает TTL по умолчанию
+	_, err := cache.Update(ctx, "counter", incr)
+	require.NoError(t, err)
+	_, expiresAt, err := cache.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, time.Second)
+
+	// Существующий элемент сохраняет время истечения
+	require.NoError(t, cache.Put(ctx, "counter", 10, time.Hour))
+	_, expiresAt, err = cache.Get(ctx, "counter")
+	require.NoError(t, err)
+
+	value, err := cache.Update(ctx, "counter", incr)
+	require.NoError(t, err)
+	assert.Equal(t, 11, value)
+
+	_, updatedExpiresAt, err := cache.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.WithinDuration(t, expiresAt, updatedExpiresAt, time.Millisecond)
+}
+
+func TestLRUCache_AddReplace(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLRUCache(2, time.Minute)
+
+	// Replace не создает отсутствующий элемент
+	assert.ErrorIs(t, cache.Replace(ctx, "key", "v1", 0), lru.ErrKeyNotFound)
+
+	require.NoError(t, cache.Add(ctx, "key", "v1", 0))
+	assert.ErrorIs(t, cache.Add(ctx, "key", "v2", 0), lru.ErrKeyExists)
+
+	require.NoError(t, cache.Replace(ctx, "key", "v3", time.Hour))
+	value, expiresAt, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v3", value)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Second)
+
+	// Истекший элемент считается отсутствующим
+	require.NoError(t, cache.Put(ctx, "expired", "v1", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	assert.ErrorIs(t, cache.Replace(ctx, "expired", "v2", 0), lru.ErrKeyNotFound)
+	require.NoError(t, cache.Add(ctx, "expired", "v3", 0))
+}
+
+func TestLRUCache_CompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLRUCache(2, time.Minute)
+
+	assert.ErrorIs(t, cache.CompareAndSwap(ctx, "key", "v1", 0, 1), lru.ErrKeyNotFound)
+
+	require.NoError(t, cache.Put(ctx, "key", "v1", 0))
+	_, _, version, err := cache.GetVersion(ctx, "key")
+	require.NoError(t, err)
+
+	// Продление TTL не меняет версию
+	require.NoError(t, cache.Expire(ctx, "key", time.Hour))
+	require.NoError(t, cache.CompareAndSwap(ctx, "key", "v2", 0, version))
+
+	// После записи версия меняется, старая версия больше не подходит
+	assert.ErrorIs(t, cache.CompareAndSwap(ctx, "key", "v3", 0, version), lru.ErrVersionMismatch)
+
+	value, _, newVersion, err := cache.GetVersion(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", value)
+	assert.NotEqual(t, version, newVersion)
+}
+
+func TestLRUCache_Expire(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLRUCache(2, time.Minute)
+
+	assert.ErrorIs(t, cache.Expire(ctx, "key", time.Hour), lru.ErrKeyNotFound)
+
+	require.NoError(t, cache.Put(ctx, "key", "value", 0))
+	require.NoError(t, cache.Expire(ctx, "key", time.Hour))
+
+	value, expiresAt, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Second)
+
+	// Неположительный TTL удаляет элемент
+	require.NoError(t, cache.Expire(ctx, "key", 0))
+	_, _, err = cache.Get(ctx, "key")
+	assert.ErrorIs(t, err, lru.ErrKeyNotFound)
+}
+
+func TestLRUCache_Stats(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLRUCache(2, time.Minute)
+
+	require.NoError(t, cache.Put(ctx, "key1", "value1", time.Minute))
+	require.NoError(t, cache.Put(ctx, "key2", "value2", -time.Minute))
+
+	// Одно попадание и два промаха, один из них по истекшему ключу
+	_, _, err := cache.Get(ctx, "key1")
+	require.NoError(t, err)
+	_, _, err = cache.Get(ctx, "key2")
+	assert.ErrorIs(t, err, lru.ErrKeyNotFound)
+	_, _, err = cache.Get(ctx, "missing")
+	assert.ErrorIs(t, err, lru.ErrKeyNotFound)
+
+	// Вытеснение по емкости, явное удаление и полная очистка
+	require.NoError(t, cache.Put(ctx, "key3", "value3", time.Minute))
+	require.NoError(t, cache.Put(ctx, "key4", "value4", time.Minute))
+	_, err = cache.Evict(ctx, "key4")
+	require.NoError(t, err)
+
+	stats := cache.Stats()
+	assert.Equal(t, 1, stats.Size)
+	assert.Equal(t, 2, stats.Capacity)
+
+	require.NoError(t, cache.EvictAll(ctx))
+
+	stats = cache.Stats()
+	assert.Equal(t, 0, stats.Size)
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, map[lru.EvictionReason]uint64{
+		lru.EvictionCapacity: 1,
+		lru.EvictionExpired:  1,
+		lru.EvictionExplicit: 1,
+		lru.EvictionFlush:    1,
+	}, stats.Evictions)
+}
+
+func TestLRUCache_Subscribe(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLRUCache(1, time.Minute)
+
+	events, cancel := cache.Subscribe(10)
+	defer cancel()
+
+	require.NoError(t, cache.Put(ctx, "a", "1", 0))
+	require.NoError(t, cache.Put(ctx, "b", "2", 0))
+	_, err := cache.Evict(ctx, "b")
+	require.NoError(t, err)
+	require.NoError(t, cache.EvictAll(ctx))
+
+	want := []struct {
+		typ    lru.EventType
+		key    string
+		value  interface{}
+		reason lru.EvictionReason
+	}{
+		{lru.EventPut, "a", "1", 0},
+		{lru.EventPut, "b", "2", 0},
+		{lru.EventEvict, "a", nil, lru.EvictionCapacity},
+		{lru.EventEvict, "b", nil, lru.EvictionExplicit},
+		{lru.EventFlush, "", nil, 0},
+	}
+	for _, w := range want {
+		event := <-events
+		assert.Equal(t, w.typ, event.Type)
+		assert.Equal(t, w.key, event.Key)
+		assert.Equal(t, w.value, event.Value)
+		if w.typ == lru.EventEvict {
+			assert.Equal(t, w.reason, event.Reason)
+		}
+	}
+
+	// Подписчик, который не успевает читать, отключается, не блокируя запись.
+	slow, cancelSlow := cache.Subscribe(1)
+	defer cancelSlow()
+	require.NoError(t, cache.Put(ctx, "c", "3", 0))
+	require.NoError(t, cache.Put(ctx, "c", "4", 0))
+
+	<-slow
+	_, ok := <-slow
+	assert.False(t, ok)
+
+	// После отмены канал закрыт.
+	cancel()
+	for range events {
+	}
+}
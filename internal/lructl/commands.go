@@ -0,0 +1,292 @@
+package lructl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/instinctG/lru-cache/internal/models"
+	"github.com/instinctG/lru-cache/pkg/client"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// command описывает подкоманду lructl.
+type command struct {
+	usage   string
+	summary string
+	run     func(ctx context.Context, c *CLI, args []string) (result, error)
+}
+
+// commands - подкоманды lructl по имени. scan - синоним keys.
+var commands = map[string]command{
+	"get":     {usage: "get <key>", summary: "print the value of a key", run: runGet},
+	"put":     {usage: "put <key> <value> [--ttl duration]", summary: "store a value, JSON scalars keep their type", run: runPut},
+	"del":     {usage: "del <key>", summary: "delete a key and print its value", run: runDel},
+	"flush":   {usage: "flush", summary: "delete all keys", run: runFlush},
+	"keys":    {usage: "keys [pattern]", summary: "list keys matching a glob pattern, least recently used first", run: runKeys},
+	"scan":    {usage: "scan [pattern]", summary: "alias for keys", run: runKeys},
+	"ttl":     {usage: "ttl <key>", summary: "print the remaining time to live of a key", run: runTTL},
+	"stats":   {usage: "stats [--all]", summary: "print cache statistics, --all prints every metric", run: runStats},
+	"dump":    {usage: "dump [--file path]", summary: "write all entries with their expiration as JSON", run: runDump},
+	"restore": {usage: "restore [--file path] [--flush]", summary: "load entries written by dump", run: runRestore},
+}
+
+// commandNames возвращает имена подкоманд в алфавитном порядке.
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func runGet(ctx context.Context, c *CLI, args []string) (result, error) {
+	if len(args) != 1 {
+		return nil, ErrUsage
+	}
+
+	val, exp, err := c.client.Get(ctx, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return newEntryResult(args[0], val, exp), nil
+}
+
+func runPut(ctx context.Context, c *CLI, args []string) (result, error) {
+	fs := flag.NewFlagSet("put", flag.ContinueOnError)
+	ttl := fs.Duration("ttl", 0, "time to live, the server default is used when zero")
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 2 || *ttl < 0 {
+		return nil, ErrUsage
+	}
+
+	if err := c.client.Put(ctx, args[0], parseValue(args[1]), *ttl); err != nil {
+		return nil, err
+	}
+
+	return message("OK"), nil
+}
+
+func runDel(ctx context.Context, c *CLI, args []string) (result, error) {
+	if len(args) != 1 {
+		return nil, ErrUsage
+	}
+
+	val, err := c.client.Evict(ctx, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return newEntryResult(args[0], val, time.Time{}), nil
+}
+
+func runFlush(ctx context.Context, c *CLI, args []string) (result, error) {
+	if len(args) != 0 {
+		return nil, ErrUsage
+	}
+
+	if err := c.client.EvictAll(ctx); err != nil {
+		return nil, err
+	}
+
+	return message("OK"), nil
+}
+
+func runKeys(ctx context.Context, c *CLI, args []string) (result, error) {
+	if len(args) > 1 {
+		return nil, ErrUsage
+	}
+
+	pattern := "*"
+	if len(args) == 1 {
+		pattern = args[0]
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	keys, vals, err := c.client.GetAll(ctx)
+	if err != nil && !errors.Is(err, client.ErrCacheIsEmpty) {
+		return nil, err
+	}
+
+	res := entriesResult{Items: []entryResult{}}
+	for i, key := range keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			res.Items = append(res.Items, entryResult{Key: key, Value: vals[i]})
+		}
+	}
+	res.Count = len(res.Items)
+
+	return res, nil
+}
+
+func runTTL(ctx context.Context, c *CLI, args []string) (result, error) {
+	if len(args) != 1 {
+		return nil, ErrUsage
+	}
+
+	_, exp, err := c.client.Get(ctx, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	res := ttlResult{Key: args[0], TTLSeconds: int64(remaining(exp, time.Now()) / time.Second)}
+	if !exp.IsZero() {
+		res.ExpiresAt = &exp
+	}
+
+	return res, nil
+}
+
+func runStats(ctx context.Context, c *CLI, args []string) (result, error) {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	all := fs.Bool("all", false, "print every metric")
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 0 {
+		return nil, ErrUsage
+	}
+
+	metrics, err := c.client.Metrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := statsResult{}
+	for name, value := range metrics {
+		// Гистограмма времени операций занимает десятки строк и полезна только в --all.
+		if *all || strings.HasPrefix(name, "lru_cache_") && !strings.HasPrefix(name, "lru_cache_operation_duration_seconds") {
+			res[name] = value
+		}
+	}
+
+	return res, nil
+}
+
+// runDump выгружает элементы вместе со временем истечения одним запросом списка
+// в порядке вытеснения (от давно использованных к недавно использованным).
+func runDump(ctx context.Context, c *CLI, args []string) (result, error) {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	file := fs.String("file", "", "output file, stdout when empty")
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 0 {
+		return nil, ErrUsage
+	}
+
+	items, err := c.client.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w := c.Stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(models.EntryList{Items: items, Count: len(items)}); err != nil {
+		return nil, err
+	}
+
+	if *file != "" {
+		return message(fmt.Sprintf("dumped %d entries to %s", len(items), *file)), nil
+	}
+	return nil, nil
+}
+
+// runRestore загружает элементы, выгруженные командой dump. Элементы записываются в порядке файла,
+// поэтому порядок вытеснения совпадает с исходным, а истекшие элементы пропускаются.
+func runRestore(ctx context.Context, c *CLI, args []string) (result, error) {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	file := fs.String("file", "", "input file, stdin when empty")
+	flush := fs.Bool("flush", false, "delete all keys before restoring")
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 0 {
+		return nil, ErrUsage
+	}
+
+	var r io.Reader = c.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var dump models.EntryList
+	if err := json.NewDecoder(r).Decode(&dump); err != nil {
+		return nil, fmt.Errorf("invalid dump: %w", err)
+	}
+
+	if *flush {
+		if err := c.client.EvictAll(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	restored, expired := 0, 0
+	for _, item := range dump.Items {
+		var ttl time.Duration
+		if item.ExpiresAt != nil {
+			if ttl = remaining(*item.ExpiresAt, now); ttl == 0 {
+				expired++
+				continue
+			}
+		}
+
+		if err := c.client.Put(ctx, item.Key, item.Value, ttl); err != nil {
+			return nil, fmt.Errorf("restore %q: %w", item.Key, err)
+		}
+		restored++
+	}
+
+	return message(fmt.Sprintf("restored %d entries, skipped %d expired", restored, expired)), nil
+}
+
+// parseValue разбирает значение команды put: числа, true, false и строки в кавычках JSON
+// сохраняют свой тип, остальное записывается строкой как есть.
+func parseValue(s string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return s
+	}
+
+	switch v.(type) {
+	case string, float64, bool:
+		return v
+	}
+	return s
+}
@@ -0,0 +1,181 @@
+// Package lructl реализует консольный клиент lructl для операторов сервиса lru-cache:
+// подкоманды для работы с кэшем, форматы вывода и интерактивный режим.
+package lructl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/instinctG/lru-cache/pkg/client"
+	"io"
+	"strings"
+	"time"
+)
+
+// Переменные окружения, из которых берутся значения глобальных флагов по умолчанию.
+const (
+	EnvAddr   = "LRUCTL_ADDR"    // EnvAddr - адрес сервиса.
+	EnvAPIKey = "LRUCTL_API_KEY" // EnvAPIKey - API-ключ.
+	EnvToken  = "LRUCTL_TOKEN"   // EnvToken - JWT для заголовка Authorization: Bearer.
+	EnvOutput = "LRUCTL_OUTPUT"  // EnvOutput - формат вывода.
+)
+
+// DefaultAddr - адрес сервиса по умолчанию.
+const DefaultAddr = "http://localhost:8080"
+
+// ErrUsage возвращается, если команда вызвана с неверными аргументами.
+var ErrUsage = errors.New("invalid usage")
+
+// CLI - консольный клиент. Потоки ввода-вывода и окружение передаются явно, чтобы клиент можно было тестировать.
+type CLI struct {
+	Stdin       io.Reader
+	Stdout      io.Writer
+	Stderr      io.Writer
+	Getenv      func(string) string
+	HistoryFile string // Файл истории интерактивного режима. Если не задан, история не сохраняется.
+
+	client *client.Client
+	output format
+}
+
+// Run разбирает глобальные флаги и выполняет подкоманду из args (без имени программы).
+// Без подкоманды запускается интерактивный режим.
+func (c *CLI) Run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("lructl", flag.ContinueOnError)
+	fs.SetOutput(c.Stderr)
+	fs.Usage = func() { c.usage(fs) }
+
+	addr := fs.String("addr", c.env(EnvAddr, DefaultAddr), "service address, env "+EnvAddr)
+	apiKey := fs.String("api-key", c.env(EnvAPIKey, ""), "API key sent in X-API-Key, env "+EnvAPIKey)
+	token := fs.String("token", c.env(EnvToken, ""), "JWT sent in Authorization: Bearer, env "+EnvToken)
+	output := fs.String("o", c.env(EnvOutput, string(formatTable)), "output format: table, json or raw, env "+EnvOutput)
+	timeout := fs.Duration("timeout", client.DefaultTimeout, "request timeout")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return ErrUsage
+	}
+
+	var err error
+	if c.output, err = parseFormat(*output); err != nil {
+		return err
+	}
+
+	opts := []client.Option{client.WithTimeout(*timeout)}
+	if *apiKey != "" {
+		opts = append(opts, client.WithAPIKey(*apiKey))
+	}
+	if *token != "" {
+		opts = append(opts, client.WithBearerToken(*token))
+	}
+	if c.client, err = client.New(*addr, opts...); err != nil {
+		return err
+	}
+	defer c.client.Close()
+
+	if fs.NArg() == 0 || fs.Arg(0) == "repl" {
+		return c.repl(ctx)
+	}
+
+	return c.exec(ctx, fs.Args())
+}
+
+// exec выполняет одну подкоманду.
+func (c *CLI) exec(ctx context.Context, args []string) error {
+	name := args[0]
+	if name == "help" {
+		c.help()
+		return nil
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q, see lructl help", name)
+	}
+
+	res, err := cmd.run(ctx, c, args[1:])
+	if err != nil {
+		if errors.Is(err, ErrUsage) {
+			return fmt.Errorf("%w: usage: lructl %s", ErrUsage, cmd.usage)
+		}
+		return err
+	}
+	if res == nil {
+		return nil
+	}
+
+	return write(c.Stdout, c.output, res)
+}
+
+// help выводит список подкоманд.
+func (c *CLI) help() {
+	fmt.Fprintln(c.Stdout, "Commands:")
+	for _, name := range commandNames() {
+		cmd := commands[name]
+		fmt.Fprintf(c.Stdout, "  %-36s %s\n", cmd.usage, cmd.summary)
+	}
+}
+
+// usage выводит справку по глобальным флагам и подкомандам.
+func (c *CLI) usage(fs *flag.FlagSet) {
+	fmt.Fprintln(c.Stderr, "Usage: lructl [flags] <command> [args]")
+	fmt.Fprintln(c.Stderr, "Without a command lructl starts an interactive shell.")
+	fmt.Fprintln(c.Stderr)
+	fmt.Fprintln(c.Stderr, "Flags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(c.Stderr)
+
+	stdout := c.Stdout
+	c.Stdout = c.Stderr
+	c.help()
+	c.Stdout = stdout
+}
+
+// env возвращает значение переменной окружения name или def, если она не задана.
+func (c *CLI) env(name, def string) string {
+	if c.Getenv == nil {
+		return def
+	}
+	if v := strings.TrimSpace(c.Getenv(name)); v != "" {
+		return v
+	}
+	return def
+}
+
+// parseArgs разбирает флаги подкоманды, которые могут стоять как до, так и после позиционных аргументов.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, ErrUsage
+		}
+		rest := fs.Args()
+		// После "--" все аргументы позиционные, даже если начинаются с "-".
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		args = rest
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// remaining возвращает оставшееся время жизни элемента, округленное вверх до секунды.
+func remaining(expiresAt time.Time, now time.Time) time.Duration {
+	if expiresAt.IsZero() {
+		return 0
+	}
+	d := expiresAt.Sub(now)
+	if d <= 0 {
+		return 0
+	}
+	return (d + time.Second - 1).Truncate(time.Second)
+}
@@ -0,0 +1,227 @@
+package lructl_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/instinctG/lru-cache/internal/http-server/handler"
+	"github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"github.com/instinctG/lru-cache/internal/lructl"
+	"github.com/instinctG/lru-cache/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newServer запускает httptest-сервер с настоящим обработчиком кэша.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger())
+	srv := httptest.NewServer(h.Router)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// run выполняет lructl с адресом из переменной окружения и возвращает stdout и stderr.
+func run(t *testing.T, srv *httptest.Server, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	cli := &lructl.CLI{
+		Stdin:  strings.NewReader(stdin),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Getenv: func(name string) string {
+			if name == lructl.EnvAddr {
+				return srv.URL
+			}
+			return ""
+		},
+	}
+
+	err := cli.Run(context.Background(), args)
+	return stdout.String(), stderr.String(), err
+}
+
+func TestCommands(t *testing.T) {
+	srv := newServer(t)
+
+	tests := []struct {
+		name        string
+		args        []string
+		expected    string
+		expectedErr error
+	}{
+		{name: "Put string", args: []string{"put", "greeting", "hello", "--ttl", "1m"}, expected: "OK\n"},
+		{name: "Put number", args: []string{"put", "--ttl=1h", "answer", "42"}, expected: "OK\n"},
+		{name: "Put quoted number", args: []string{"put", "zip", `"01234"`}, expected: "OK\n"},
+		{name: "Put negative TTL", args: []string{"put", "key", "value", "--ttl", "-1s"}, expectedErr: lructl.ErrUsage},
+		{name: "Get raw string", args: []string{"-o", "raw", "get", "greeting"}, expected: "hello\n"},
+		{name: "Get raw number", args: []string{"-o", "raw", "get", "answer"}, expected: "42\n"},
+		{name: "Get raw quoted number", args: []string{"-o", "raw", "get", "zip"}, expected: "01234\n"},
+		{name: "Get missing key", args: []string{"get", "missing"}, expectedErr: client.ErrKeyNotFound},
+		{name: "Get without key", args: []string{"get"}, expectedErr: lructl.ErrUsage},
+		{name: "TTL raw", args: []string{"-o", "raw", "ttl", "answer"}, expected: "3600\n"},
+		{name: "Keys raw", args: []string{"-o", "raw", "keys"}, expected: "greeting\nzip\nanswer\n"},
+		{name: "Keys pattern", args: []string{"-o", "raw", "scan", "g*"}, expected: "greeting\n"},
+		{name: "Keys table", args: []string{"keys", "[az]*"}, expected: "KEY     VALUE\nzip     01234\nanswer  42\n"},
+		{name: "Keys invalid pattern", args: []string{"keys", "["}, expectedErr: nil},
+		{name: "Del raw", args: []string{"-o", "raw", "del", "zip"}, expected: "01234\n"},
+		{name: "Unknown command", args: []string{"frobnicate"}},
+		{name: "Unknown output format", args: []string{"-o", "yaml", "keys"}},
+		{name: "Flush", args: []string{"flush"}, expected: "OK\n"},
+		{name: "Keys after flush", args: []string{"-o", "json", "keys"}, expected: "{\n  \"items\": [],\n  \"count\": 0\n}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, _, err := run(t, srv, "", tt.args...)
+			if tt.expected == "" {
+				require.Error(t, err)
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, stdout)
+		})
+	}
+}
+
+func TestGetJSON(t *testing.T) {
+	srv := newServer(t)
+
+	_, _, err := run(t, srv, "", "put", "flag", "true", "--ttl", "30s")
+	require.NoError(t, err)
+
+	stdout, _, err := run(t, srv, "", "-o", "json", "get", "flag")
+	require.NoError(t, err)
+
+	var entry struct {
+		Key       string    `json:"key"`
+		Value     bool      `json:"value"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &entry))
+	assert.Equal(t, "flag", entry.Key)
+	assert.True(t, entry.Value)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), entry.ExpiresAt, 2*time.Second)
+}
+
+func TestStats(t *testing.T) {
+	srv := newServer(t)
+
+	_, _, err := run(t, srv, "", "put", "key", "value")
+	require.NoError(t, err)
+	_, _, err = run(t, srv, "", "get", "key")
+	require.NoError(t, err)
+
+	stdout, _, err := run(t, srv, "", "-o", "json", "stats")
+	require.NoError(t, err)
+
+	var stats map[string]float64
+	require.NoError(t, json.Unmarshal([]byte(stdout), &stats))
+	assert.Equal(t, float64(1), stats["lru_cache_size"])
+	assert.Equal(t, float64(10), stats["lru_cache_capacity"])
+	assert.Equal(t, float64(1), stats["lru_cache_hits_total"])
+	for name := range stats {
+		assert.True(t, strings.HasPrefix(name, "lru_cache_"), name)
+		assert.False(t, strings.HasPrefix(name, "lru_cache_operation_duration_seconds"), name)
+	}
+}
+
+func TestDumpRestore(t *testing.T) {
+	source := newServer(t)
+	target := newServer(t)
+
+	for _, args := range [][]string{
+		{"put", "a", "1", "--ttl", "1h"},
+		{"put", "b", "two", "--ttl", "1m"},
+		{"put", "c", "true"},
+	} {
+		_, _, err := run(t, source, "", args...)
+		require.NoError(t, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "dump.json")
+	stdout, _, err := run(t, source, "", "dump", "--file", file)
+	require.NoError(t, err)
+	assert.Equal(t, "dumped 3 entries to "+file+"\n", stdout)
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	// Восстановление из stdin сохраняет значения, порядок вытеснения и время жизни.
+	stdout, _, err = run(t, target, string(data), "restore")
+	require.NoError(t, err)
+	assert.Equal(t, "restored 3 entries, skipped 0 expired\n", stdout)
+
+	stdout, _, err = run(t, target, "", "-o", "raw", "keys")
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\nc\n", stdout)
+
+	stdout, _, err = run(t, target, "", "-o", "raw", "ttl", "b")
+	require.NoError(t, err)
+	assert.Equal(t, "60\n", stdout)
+
+	expired := `{"items":[{"key":"old","value":1,"expires_at":"2000-01-01T00:00:00Z"},{"key":"new","value":2}],"count":2}`
+	stdout, _, err = run(t, target, expired, "restore", "--flush")
+	require.NoError(t, err)
+	assert.Equal(t, "restored 1 entries, skipped 1 expired\n", stdout)
+
+	stdout, _, err = run(t, target, "", "-o", "raw", "keys")
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", stdout)
+}
+
+func TestREPL(t *testing.T) {
+	srv := newServer(t)
+	history := filepath.Join(t.TempDir(), "history")
+
+	var stdout, stderr bytes.Buffer
+	cli := &lructl.CLI{
+		Stdin: strings.NewReader(strings.Join([]string{
+			`put "two words" 'it''s'`,
+			"output raw",
+			`get "two words"`,
+			"!!",
+			"get missing",
+			"history",
+			"quit",
+			"get never",
+		}, "\n")),
+		Stdout:      &stdout,
+		Stderr:      &stderr,
+		HistoryFile: history,
+	}
+
+	require.NoError(t, cli.Run(context.Background(), []string{"-addr", srv.URL}))
+
+	assert.Equal(t, strings.Join([]string{
+		"lructl> OK",
+		"lructl> lructl> its",
+		`lructl> get "two words"`,
+		"its",
+		"lructl> lructl>     1  put \"two words\" 'it''s'",
+		"    2  output raw",
+		"    3  get \"two words\"",
+		"    4  get \"two words\"",
+		"    5  get missing",
+		"    6  history",
+		"lructl> ",
+	}, "\n"), stdout.String())
+	assert.Contains(t, stderr.String(), "key not found")
+
+	data, err := os.ReadFile(history)
+	require.NoError(t, err)
+	assert.Equal(t, 7, strings.Count(string(data), "\n"))
+}
@@ -0,0 +1,168 @@
+package lructl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// format - формат вывода результатов команд.
+type format string
+
+// Поддерживаемые форматы вывода.
+const (
+	formatTable format = "table" // formatTable - таблица с заголовками колонок.
+	formatJSON  format = "json"  // formatJSON - JSON-документ.
+	formatRaw   format = "raw"   // formatRaw - только значения, по одному в строке, для использования в скриптах.
+)
+
+// parseFormat проверяет название формата вывода.
+func parseFormat(s string) (format, error) {
+	switch f := format(s); f {
+	case formatTable, formatJSON, formatRaw:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown output format %q, expected table, json or raw", s)
+}
+
+// result - результат команды, который выводится в одном из форматов. В формате json результат кодируется как есть.
+type result interface {
+	table(w io.Writer)
+	raw(w io.Writer)
+}
+
+// write выводит результат команды в формате f.
+func write(w io.Writer, f format, res result) error {
+	switch f {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	case formatRaw:
+		res.raw(w)
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		res.table(tw)
+		return tw.Flush()
+	}
+	return nil
+}
+
+// message - текстовый результат команды, изменяющей кэш.
+type message string
+
+func (m message) table(w io.Writer) { fmt.Fprintln(w, string(m)) }
+func (m message) raw(w io.Writer)   { fmt.Fprintln(w, string(m)) }
+
+// MarshalJSON кодирует сообщение как объект {"message": ...}.
+func (m message) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Message string `json:"message"`
+	}{string(m)})
+}
+
+// entryResult - элемент кэша.
+type entryResult struct {
+	Key       string      `json:"key"`
+	Value     interface{} `json:"value"`
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"`
+}
+
+func newEntryResult(key string, value interface{}, expiresAt time.Time) entryResult {
+	res := entryResult{Key: key, Value: value}
+	if !expiresAt.IsZero() {
+		expiresAt = expiresAt.UTC()
+		res.ExpiresAt = &expiresAt
+	}
+	return res
+}
+
+func (e entryResult) table(w io.Writer) {
+	if e.ExpiresAt == nil {
+		fmt.Fprintln(w, "KEY\tVALUE")
+		fmt.Fprintf(w, "%s\t%s\n", e.Key, formatValue(e.Value))
+		return
+	}
+	fmt.Fprintln(w, "KEY\tVALUE\tEXPIRES_AT")
+	fmt.Fprintf(w, "%s\t%s\t%s\n", e.Key, formatValue(e.Value), e.ExpiresAt.Format(time.RFC3339))
+}
+
+func (e entryResult) raw(w io.Writer) { fmt.Fprintln(w, formatValue(e.Value)) }
+
+// entriesResult - список элементов кэша.
+type entriesResult struct {
+	Items []entryResult `json:"items"`
+	Count int           `json:"count"`
+}
+
+func (l entriesResult) table(w io.Writer) {
+	fmt.Fprintln(w, "KEY\tVALUE")
+	for _, item := range l.Items {
+		fmt.Fprintf(w, "%s\t%s\n", item.Key, formatValue(item.Value))
+	}
+}
+
+func (l entriesResult) raw(w io.Writer) {
+	for _, item := range l.Items {
+		fmt.Fprintln(w, item.Key)
+	}
+}
+
+// ttlResult - оставшееся время жизни элемента.
+type ttlResult struct {
+	Key        string     `json:"key"`
+	TTLSeconds int64      `json:"ttl_seconds"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+func (t ttlResult) table(w io.Writer) {
+	expiresAt := "-"
+	if t.ExpiresAt != nil {
+		expiresAt = t.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	fmt.Fprintln(w, "KEY\tTTL\tEXPIRES_AT")
+	fmt.Fprintf(w, "%s\t%s\t%s\n", t.Key, time.Duration(t.TTLSeconds)*time.Second, expiresAt)
+}
+
+func (t ttlResult) raw(w io.Writer) { fmt.Fprintln(w, t.TTLSeconds) }
+
+// statsResult - значения метрик по имени серии.
+type statsResult map[string]float64
+
+func (s statsResult) table(w io.Writer) {
+	fmt.Fprintln(w, "METRIC\tVALUE")
+	for _, name := range s.names() {
+		fmt.Fprintf(w, "%s\t%s\n", name, strconv.FormatFloat(s[name], 'g', -1, 64))
+	}
+}
+
+func (s statsResult) raw(w io.Writer) {
+	for _, name := range s.names() {
+		fmt.Fprintf(w, "%s %s\n", name, strconv.FormatFloat(s[name], 'g', -1, 64))
+	}
+}
+
+func (s statsResult) names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formatValue форматирует значение для вывода: строки выводятся без кавычек, остальные значения - в JSON.
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
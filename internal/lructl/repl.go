@@ -0,0 +1,188 @@
+package lructl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxHistory - число последних команд, которые хранятся в файле истории.
+const maxHistory = 1000
+
+// prompt - приглашение интерактивного режима.
+const prompt = "lructl> "
+
+// repl запускает интерактивный режим: команды читаются построчно до exit, quit или конца ввода.
+// Кроме подкоманд доступны history, !N и !! для повтора команды из истории и output для смены формата вывода.
+func (c *CLI) repl(ctx context.Context) error {
+	history := c.loadHistory()
+
+	scanner := bufio.NewScanner(c.Stdin)
+	for {
+		fmt.Fprint(c.Stdout, prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(c.Stdout)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			recalled, err := recall(history, line)
+			if err != nil {
+				fmt.Fprintln(c.Stderr, "error:", err)
+				continue
+			}
+			line = recalled
+			fmt.Fprintln(c.Stdout, line)
+		}
+
+		history = append(history, line)
+		c.appendHistory(line)
+
+		args, err := splitLine(line)
+		if err != nil {
+			fmt.Fprintln(c.Stderr, "error:", err)
+			continue
+		}
+
+		switch args[0] {
+		case "exit", "quit":
+			return nil
+		case "history":
+			for i, h := range history {
+				fmt.Fprintf(c.Stdout, "%5d  %s\n", i+1, h)
+			}
+			continue
+		case "output":
+			if len(args) != 2 {
+				fmt.Fprintf(c.Stderr, "error: usage: output table|json|raw (current %s)\n", c.output)
+				continue
+			}
+			if f, err := parseFormat(args[1]); err != nil {
+				fmt.Fprintln(c.Stderr, "error:", err)
+			} else {
+				c.output = f
+			}
+			continue
+		}
+
+		if err := c.exec(ctx, args); err != nil {
+			fmt.Fprintln(c.Stderr, "error:", err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// recall возвращает команду из истории по ссылке !! (последняя команда) или !N (команда с номером N).
+func recall(history []string, ref string) (string, error) {
+	if ref == "!!" {
+		if len(history) == 0 {
+			return "", errors.New("history is empty")
+		}
+		return history[len(history)-1], nil
+	}
+
+	n, err := strconv.Atoi(ref[1:])
+	if err != nil || n < 1 || n > len(history) {
+		return "", fmt.Errorf("%s: event not found", ref)
+	}
+	return history[n-1], nil
+}
+
+// loadHistory читает последние команды из файла истории. Ошибки чтения не мешают работе.
+func (c *CLI) loadHistory() []string {
+	if c.HistoryFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.HistoryFile)
+	if err != nil {
+		return nil
+	}
+
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			history = append(history, line)
+		}
+	}
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	return history
+}
+
+// appendHistory дописывает команду в файл истории.
+func (c *CLI) appendHistory(line string) {
+	if c.HistoryFile == "" {
+		return
+	}
+
+	f, err := os.OpenFile(c.HistoryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	_, _ = fmt.Fprintln(f, line)
+}
+
+// splitLine разбивает строку на аргументы по пробелам с учетом одинарных и двойных кавычек
+// и экранирования обратной косой чертой, как это делает командная оболочка.
+func splitLine(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return args, nil
+}
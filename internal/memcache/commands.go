@@ -0,0 +1,496 @@
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version - версия протокола memcached, сообщаемая командами version и stats.
+const version = "1.6.21"
+
+const (
+	maxKeyLength  = 250               // maxKeyLength - максимальная длина ключа в протоколе memcached.
+	maxLineLength = 64 << 10          // maxLineLength - максимальная длина строки команды.
+	relativeLimit = 30 * 24 * 60 * 60 // relativeLimit - exptime больше 30 дней считается абсолютным временем unix.
+)
+
+// Ответы об ошибках, которые ожидают клиентские библиотеки memcached.
+const (
+	replyError       = "ERROR"
+	replyBadFormat   = "CLIENT_ERROR bad command line format"
+	replyBadChunk    = "CLIENT_ERROR bad data chunk"
+	replyTooLarge    = "SERVER_ERROR object too large for cache"
+	replyNonNumeric  = "CLIENT_ERROR cannot increment or decrement non-numeric value"
+	replyInvalidIncr = "CLIENT_ERROR invalid numeric delta argument"
+)
+
+var errLineTooLong = errors.New("line is too long")
+
+// Value - значение, записанное по протоколу memcached с ненулевыми флагами.
+// Значения с нулевыми флагами хранятся строками и доступны через HTTP API и RESP без изменений.
+type Value struct {
+	Data  string `json:"data"`  // Данные значения.
+	Flags uint32 `json:"flags"` // Непрозрачные флаги клиента, например признак сериализации.
+}
+
+// client хранит состояние соединения.
+type client struct {
+	server *Server
+	r      *bufio.Reader
+	w      *bufio.Writer
+	quit   bool
+}
+
+// ctx возвращает контекст для операций с кэшем.
+func (c *client) ctx() context.Context {
+	return context.Background()
+}
+
+// readLine читает строку команды без завершающего CRLF.
+func (c *client) readLine() (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := c.r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxLineLength {
+			return "", errLineTooLong
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+func (c *client) reply(s string) {
+	c.w.WriteString(s)
+	c.w.WriteString("\r\n")
+}
+
+// execute выполняет команду. Ошибка возвращается, только если соединение нужно закрыть.
+func (c *client) execute(line string) error {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		c.reply(replyError)
+		return nil
+	}
+
+	switch name := args[0]; name {
+	case "get", "gets":
+		c.get(args[1:], name == "gets")
+	case "set", "add", "replace", "cas":
+		return c.store(name, args[1:])
+	case "delete":
+		c.delete(args[1:])
+	case "incr", "decr":
+		c.incr(args[1:], name == "incr")
+	case "touch":
+		c.touch(args[1:])
+	case "flush_all":
+		c.flushAll(args[1:])
+	case "stats":
+		c.stats(args[1:])
+	case "version":
+		c.reply("VERSION " + version)
+	case "verbosity":
+		c.withNoreply(args[1:], 1, func() string { return "OK" })
+	case "quit":
+		c.quit = true
+	default:
+		c.reply(replyError)
+	}
+	return nil
+}
+
+// withNoreply проверяет количество аргументов (required и необязательный noreply)
+// и отправляет ответ fn, если клиент не передал noreply.
+func (c *client) withNoreply(args []string, required int, fn func() string) {
+	noreply := len(args) == required+1 && args[required] == "noreply"
+	if len(args) != required && !noreply {
+		c.reply(replyError)
+		return
+	}
+
+	reply := fn()
+	if !noreply || strings.HasPrefix(reply, "CLIENT_ERROR") {
+		c.reply(reply)
+	}
+}
+
+// get отвечает значениями найденных ключей. Отсутствующие ключи пропускаются.
+func (c *client) get(keys []string, withCAS bool) {
+	if len(keys) == 0 {
+		c.reply(replyError)
+		return
+	}
+	for _, key := range keys {
+		if !validKey(key) {
+			c.reply(replyBadFormat)
+			return
+		}
+	}
+
+	for _, key := range keys {
+		c.server.stats.cmdGet.Add(1)
+
+		value, _, cas, err := c.server.LRU.GetVersion(c.ctx(), key)
+		if err != nil {
+			c.server.stats.getMisses.Add(1)
+			continue
+		}
+		c.server.stats.getHits.Add(1)
+
+		data, flags := decodeValue(value)
+		if withCAS {
+			fmt.Fprintf(c.w, "VALUE %s %d %d %d\r\n", key, flags, len(data), cas)
+		} else {
+			fmt.Fprintf(c.w, "VALUE %s %d %d\r\n", key, flags, len(data))
+		}
+		c.w.WriteString(data)
+		c.w.WriteString("\r\n")
+	}
+	c.reply("END")
+}
+
+// store выполняет команды записи set, add, replace и cas:
+// <command> <key> <flags> <exptime> <bytes> [<cas unique>] [noreply]\r\n<data>\r\n.
+func (c *client) store(name string, args []string) error {
+	required := 4
+	if name == "cas" {
+		required = 5
+	}
+	noreply := len(args) == required+1 && args[required] == "noreply"
+	if len(args) != required && !noreply {
+		c.reply(replyError)
+		return nil
+	}
+
+	key := args[0]
+	flags, errFlags := strconv.ParseUint(args[1], 10, 32)
+	exptime, errExp := strconv.ParseInt(args[2], 10, 64)
+	size, errSize := strconv.Atoi(args[3])
+	var cas uint64
+	var errCAS error
+	if name == "cas" {
+		cas, errCAS = strconv.ParseUint(args[4], 10, 64)
+	}
+	if !validKey(key) || errFlags != nil || errExp != nil || errSize != nil || errCAS != nil || size < 0 {
+		c.reply(replyBadFormat)
+		return nil
+	}
+
+	// Данные слишком большого значения пропускаются, чтобы не нарушить разбор следующих команд.
+	if size > c.server.maxItemSize {
+		if _, err := io.CopyN(io.Discard, c.r, int64(size)+2); err != nil {
+			return err
+		}
+		c.reply(replyTooLarge)
+		return nil
+	}
+
+	buf := make([]byte, size+2)
+	if _, err := io.ReadFull(c.r, buf); err != nil {
+		return err
+	}
+	if buf[size] != '\r' || buf[size+1] != '\n' {
+		// Остаток строки с лишними данными пропускается, как в memcached.
+		if buf[size+1] != '\n' {
+			if _, err := c.readLine(); err != nil {
+				return err
+			}
+		}
+		c.reply(replyBadChunk)
+		return nil
+	}
+
+	c.server.stats.cmdSet.Add(1)
+	value := encodeValue(string(buf[:size]), uint32(flags))
+	ttl := expiration(exptime)
+
+	var err error
+	switch name {
+	case "set":
+		err = c.server.LRU.Put(c.ctx(), key, value, ttl)
+	case "add":
+		err = c.server.LRU.Add(c.ctx(), key, value, ttl)
+	case "replace":
+		err = c.server.LRU.Replace(c.ctx(), key, value, ttl)
+	case "cas":
+		err = c.server.LRU.CompareAndSwap(c.ctx(), key, value, ttl, cas)
+	}
+
+	var reply string
+	switch {
+	case err == nil:
+		reply = "STORED"
+		if name == "cas" {
+			c.server.stats.casHits.Add(1)
+		}
+	case errors.Is(err, lru.ErrVersionMismatch):
+		c.server.stats.casBadval.Add(1)
+		reply = "EXISTS"
+	case errors.Is(err, lru.ErrKeyNotFound) && name == "cas":
+		c.server.stats.casMisses.Add(1)
+		reply = "NOT_FOUND"
+	case errors.Is(err, lru.ErrKeyExists), errors.Is(err, lru.ErrKeyNotFound):
+		reply = "NOT_STORED"
+	default:
+		reply = "SERVER_ERROR " + err.Error()
+	}
+
+	if !noreply {
+		c.reply(reply)
+	}
+	return nil
+}
+
+func (c *client) delete(args []string) {
+	if len(args) == 0 {
+		c.reply(replyError)
+		return
+	}
+
+	c.withNoreply(args[1:], 0, func() string {
+		if !validKey(args[0]) {
+			return replyBadFormat
+		}
+		_, err := c.server.LRU.Evict(c.ctx(), args[0])
+		switch {
+		case errors.Is(err, lru.ErrKeyNotFound):
+			return "NOT_FOUND"
+		case err != nil:
+			return "SERVER_ERROR " + err.Error()
+		}
+		return "DELETED"
+	})
+}
+
+// incr изменяет десятичное значение ключа без знака, сохраняя флаги и время истечения.
+// Увеличение переполняется по модулю 2^64, уменьшение не опускается ниже 0, как в memcached.
+func (c *client) incr(args []string, increment bool) {
+	if len(args) < 2 {
+		c.reply(replyError)
+		return
+	}
+
+	c.withNoreply(args[2:], 0, func() string {
+		key := args[0]
+		if !validKey(key) {
+			return replyBadFormat
+		}
+		delta, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return replyInvalidIncr
+		}
+
+		var result uint64
+		var nonNumeric bool
+		_, err = c.server.LRU.Update(c.ctx(), key, func(old interface{}, exists bool) (interface{}, bool) {
+			if !exists {
+				return nil, false
+			}
+
+			data, flags := decodeValue(old)
+			current, err := strconv.ParseUint(strings.TrimSpace(data), 10, 64)
+			if err != nil {
+				nonNumeric = true
+				return old, true
+			}
+
+			switch {
+			case increment:
+				result = current + delta
+			case delta > current:
+				result = 0
+			default:
+				result = current - delta
+			}
+			return encodeValue(strconv.FormatUint(result, 10), flags), true
+		})
+
+		switch {
+		case nonNumeric:
+			return replyNonNumeric
+		case errors.Is(err, lru.ErrKeyNotFound):
+			return "NOT_FOUND"
+		case err != nil:
+			return "SERVER_ERROR " + err.Error()
+		default:
+			return strconv.FormatUint(result, 10)
+		}
+	})
+}
+
+func (c *client) touch(args []string) {
+	if len(args) < 2 {
+		c.reply(replyError)
+		return
+	}
+
+	c.withNoreply(args[2:], 0, func() string {
+		exptime, err := strconv.ParseInt(args[1], 10, 64)
+		if !validKey(args[0]) || err != nil {
+			return replyBadFormat
+		}
+		c.server.stats.cmdTouch.Add(1)
+
+		ttl := expiration(exptime)
+		if ttl == 0 {
+			// Нулевой exptime возвращает элементу время жизни по умолчанию.
+			ttl = c.server.defaultTTL
+		}
+		err = c.server.LRU.Expire(c.ctx(), args[0], ttl)
+		switch {
+		case errors.Is(err, lru.ErrKeyNotFound):
+			return "NOT_FOUND"
+		case err != nil:
+			return "SERVER_ERROR " + err.Error()
+		}
+		return "TOUCHED"
+	})
+}
+
+// flushAll удаляет все элементы сразу или через delay секунд.
+func (c *client) flushAll(args []string) {
+	var delay int64
+	if len(args) > 0 && args[0] != "noreply" {
+		var err error
+		if delay, err = strconv.ParseInt(args[0], 10, 64); err != nil || delay < 0 {
+			c.reply(replyBadFormat)
+			return
+		}
+		args = args[1:]
+	}
+
+	c.withNoreply(args, 0, func() string {
+		c.server.stats.cmdFlush.Add(1)
+
+		if delay > 0 {
+			time.AfterFunc(time.Duration(delay)*time.Second, func() {
+				_ = c.server.LRU.EvictAll(context.Background())
+			})
+			return "OK"
+		}
+		if err := c.server.LRU.EvictAll(c.ctx()); err != nil {
+			return "SERVER_ERROR " + err.Error()
+		}
+		return "OK"
+	})
+}
+
+// stats отвечает общей статистикой сервера. Разделы stats (items, slabs, ...) не поддерживаются.
+func (c *client) stats(args []string) {
+	if len(args) > 0 {
+		c.reply(replyError)
+		return
+	}
+
+	s := &c.server.stats
+	cache := c.server.LRU.Stats()
+
+	c.server.mu.Lock()
+	connections := len(c.server.conns)
+	c.server.mu.Unlock()
+
+	now := time.Now()
+	stat := func(name string, value interface{}) {
+		fmt.Fprintf(c.w, "STAT %s %v\r\n", name, value)
+	}
+	stat("pid", os.Getpid())
+	stat("uptime", int64(now.Sub(c.server.started).Seconds()))
+	stat("time", now.Unix())
+	stat("version", version)
+	stat("curr_connections", connections)
+	stat("total_connections", s.totalConnections.Load())
+	stat("cmd_get", s.cmdGet.Load())
+	stat("cmd_set", s.cmdSet.Load())
+	stat("cmd_flush", s.cmdFlush.Load())
+	stat("cmd_touch", s.cmdTouch.Load())
+	stat("get_hits", s.getHits.Load())
+	stat("get_misses", s.getMisses.Load())
+	stat("cas_misses", s.casMisses.Load())
+	stat("cas_hits", s.casHits.Load())
+	stat("cas_badval", s.casBadval.Load())
+	stat("curr_items", cache.Size)
+	stat("limit_items", cache.Capacity)
+	stat("evictions", cache.Evictions[lru.EvictionCapacity])
+	stat("expired_unfetched", cache.Evictions[lru.EvictionExpired])
+	stat("item_size_max", c.server.maxItemSize)
+	c.reply("END")
+}
+
+// expiration переводит exptime memcached во время жизни элемента:
+// 0 - время жизни кэша по умолчанию, значение до 30 дней - секунды от текущего момента,
+// большее значение - абсолютное время unix. Отрицательное или прошедшее время
+// делает элемент сразу истекшим, как в memcached.
+func expiration(exptime int64) time.Duration {
+	switch {
+	case exptime == 0:
+		return 0
+	case exptime < 0:
+		return time.Nanosecond
+	case exptime > relativeLimit:
+		ttl := time.Until(time.Unix(exptime, 0))
+		if ttl <= 0 {
+			return time.Nanosecond
+		}
+		return ttl
+	default:
+		return time.Duration(exptime) * time.Second
+	}
+}
+
+// encodeValue возвращает значение для записи в кэш: строку для нулевых флагов и Value для остальных.
+func encodeValue(data string, flags uint32) interface{} {
+	if flags == 0 {
+		return data
+	}
+	return Value{Data: data, Flags: flags}
+}
+
+// decodeValue возвращает данные и флаги значения кэша. Значения, записанные через HTTP API
+// не строками, возвращаются в виде JSON с нулевыми флагами.
+func decodeValue(value interface{}) (string, uint32) {
+	switch v := value.(type) {
+	case Value:
+		return v.Data, v.Flags
+	case string:
+		return v, 0
+	case []byte:
+		return string(v), 0
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), 0
+	case bool:
+		return strconv.FormatBool(v), 0
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v), 0
+		}
+		return string(data), 0
+	}
+}
+
+// validKey проверяет ключ по правилам memcached: не длиннее 250 байт и без управляющих символов.
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
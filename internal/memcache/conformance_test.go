@@ -0,0 +1,428 @@
+package memcache_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"github.com/instinctG/lru-cache/internal/memcache"
+	"github.com/instinctG/lru-cache/internal/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// step - шаг сценария: отправляемые данные и ожидаемый ответ сервера.
+type step struct {
+	send string
+	want string
+}
+
+// startServer запускает сервер memcached на свободном порту и возвращает его кэш и адрес.
+func startServer(t *testing.T, opts ...memcache.Option) (*lru.Cache, string) {
+	t.Helper()
+
+	cache := lru.NewLRUCache(100, time.Minute)
+	return cache, serve(t, cache, opts...)
+}
+
+// serve запускает сервер над переданным кэшем на свободном порту и возвращает его адрес.
+func serve(t *testing.T, cache memcache.Cache, opts ...memcache.Option) string {
+	t.Helper()
+
+	srv := memcache.NewServer("127.0.0.1:0", cache, slog.New(slog.NewTextHandler(io.Discard, nil)), opts...)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ln) }()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		require.NoError(t, srv.Shutdown(ctx))
+		require.ErrorIs(t, <-done, memcache.ErrServerClosed)
+	})
+
+	return ln.Addr().String()
+}
+
+// run выполняет шаги сценария в одном соединении. Ответ читается ровно по длине ожидаемого.
+func run(t *testing.T, addr string, steps []step) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for i, s := range steps {
+		_, err = conn.Write([]byte(s.send))
+		require.NoError(t, err)
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+		got := make([]byte, len(s.want))
+		_, err = io.ReadFull(r, got)
+		require.NoError(t, err, "step %d %q: partial reply %q", i, s.send, got)
+		require.Equal(t, s.want, string(got), "step %d %q", i, s.send)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "set and get",
+			steps: []step{
+				{"set foo 0 0 3\r\nbar\r\n", "STORED\r\n"},
+				{"get foo\r\n", "VALUE foo 0 3\r\nbar\r\nEND\r\n"},
+				{"get missing\r\n", "END\r\n"},
+			},
+		},
+		{
+			name: "flags are preserved",
+			steps: []step{
+				{"set foo 42 0 3\r\nbar\r\n", "STORED\r\n"},
+				{"get foo\r\n", "VALUE foo 42 3\r\nbar\r\nEND\r\n"},
+				{"set foo 4294967295 0 0\r\n\r\n", "STORED\r\n"},
+				{"get foo\r\n", "VALUE foo 4294967295 0\r\n\r\nEND\r\n"},
+			},
+		},
+		{
+			name: "multi get skips missing keys",
+			steps: []step{
+				{"set a 0 0 1\r\n1\r\n", "STORED\r\n"},
+				{"set b 0 0 1\r\n2\r\n", "STORED\r\n"},
+				{"get a missing b\r\n", "VALUE a 0 1\r\n1\r\nVALUE b 0 1\r\n2\r\nEND\r\n"},
+			},
+		},
+		{
+			name: "binary safe data",
+			steps: []step{
+				{"set foo 0 0 7\r\na\r\nb\x00 c\r\n", "STORED\r\n"},
+				{"get foo\r\n", "VALUE foo 0 7\r\na\r\nb\x00 c\r\nEND\r\n"},
+			},
+		},
+		{
+			name: "add",
+			steps: []step{
+				{"add foo 0 0 1\r\n1\r\n", "STORED\r\n"},
+				{"add foo 0 0 1\r\n2\r\n", "NOT_STORED\r\n"},
+				{"get foo\r\n", "VALUE foo 0 1\r\n1\r\nEND\r\n"},
+			},
+		},
+		{
+			name: "replace",
+			steps: []step{
+				{"replace foo 0 0 1\r\n1\r\n", "NOT_STORED\r\n"},
+				{"set foo 0 0 1\r\n1\r\n", "STORED\r\n"},
+				{"replace foo 5 0 1\r\n2\r\n", "STORED\r\n"},
+				{"get foo\r\n", "VALUE foo 5 1\r\n2\r\nEND\r\n"},
+			},
+		},
+		{
+			name: "delete",
+			steps: []step{
+				{"delete foo\r\n", "NOT_FOUND\r\n"},
+				{"set foo 0 0 1\r\n1\r\n", "STORED\r\n"},
+				{"delete foo\r\n", "DELETED\r\n"},
+				{"get foo\r\n", "END\r\n"},
+			},
+		},
+		{
+			name: "incr and decr",
+			steps: []step{
+				{"incr foo 1\r\n", "NOT_FOUND\r\n"},
+				{"set foo 3 0 2\r\n10\r\n", "STORED\r\n"},
+				{"incr foo 5\r\n", "15\r\n"},
+				{"decr foo 20\r\n", "0\r\n"},
+				{"incr foo 18446744073709551615\r\n", "18446744073709551615\r\n"},
+				{"incr foo 2\r\n", "1\r\n"},
+				{"get foo\r\n", "VALUE foo 3 1\r\n1\r\nEND\r\n"},
+				{"incr foo x\r\n", "CLIENT_ERROR invalid numeric delta argument\r\n"},
+				{"set text 0 0 3\r\nabc\r\n", "STORED\r\n"},
+				{"incr text 1\r\n", "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"},
+			},
+		},
+		{
+			name: "touch",
+			steps: []step{
+				{"touch foo 10\r\n", "NOT_FOUND\r\n"},
+				{"set foo 0 0 1\r\n1\r\n", "STORED\r\n"},
+				{"touch foo 10\r\n", "TOUCHED\r\n"},
+				{"touch foo -1\r\n", "TOUCHED\r\n"},
+				{"get foo\r\n", "END\r\n"},
+			},
+		},
+		{
+			name: "negative exptime stores an expired item",
+			steps: []step{
+				{"set foo 0 0 1\r\n1\r\n", "STORED\r\n"},
+				{"set foo 0 -1 1\r\n2\r\n", "STORED\r\n"},
+				{"get foo\r\n", "END\r\n"},
+			},
+		},
+		{
+			name: "absolute exptime in the past",
+			steps: []step{
+				{"set foo 0 2592001 1\r\n1\r\n", "STORED\r\n"},
+				{"get foo\r\n", "END\r\n"},
+			},
+		},
+		{
+			name: "noreply",
+			steps: []step{
+				{"set foo 0 0 1 noreply\r\n1\r\n", ""},
+				{"add foo 0 0 1 noreply\r\n2\r\n", ""},
+				{"incr foo 1 noreply\r\n", ""},
+				{"touch foo 100 noreply\r\n", ""},
+				{"get foo\r\n", "VALUE foo 0 1\r\n2\r\nEND\r\n"},
+				{"delete foo noreply\r\n", ""},
+				{"get foo\r\n", "END\r\n"},
+			},
+		},
+		{
+			name: "flush_all",
+			steps: []step{
+				{"set a 0 0 1\r\n1\r\n", "STORED\r\n"},
+				{"set b 0 0 1\r\n1\r\n", "STORED\r\n"},
+				{"flush_all\r\n", "OK\r\n"},
+				{"get a b\r\n", "END\r\n"},
+				{"flush_all noreply\r\n", ""},
+				{"flush_all x\r\n", "CLIENT_ERROR bad command line format\r\n"},
+			},
+		},
+		{
+			name: "version and verbosity",
+			steps: []step{
+				{"version\r\n", "VERSION 1.6.21\r\n"},
+				{"verbosity 1\r\n", "OK\r\n"},
+			},
+		},
+		{
+			name: "errors",
+			steps: []step{
+				{"bogus\r\n", "ERROR\r\n"},
+				{"\r\n", "ERROR\r\n"},
+				{"get\r\n", "ERROR\r\n"},
+				{"set foo 0 0\r\n", "ERROR\r\n"},
+				{"set foo x 0 1\r\n", "CLIENT_ERROR bad command line format\r\n"},
+				{"get " + strings.Repeat("k", 251) + "\r\n", "CLIENT_ERROR bad command line format\r\n"},
+				{"set foo 0 0 1\r\nab\r\n", "CLIENT_ERROR bad data chunk\r\n"},
+				{"get foo\r\n", "END\r\n"},
+				{"set big 0 0 11\r\n01234567890\r\n", "SERVER_ERROR object too large for cache\r\n"},
+				{"get big\r\n", "END\r\n"},
+			},
+		},
+		{
+			name: "pipelining",
+			steps: []step{
+				{
+					"set a 0 0 1\r\n1\r\nset b 0 0 1\r\n2\r\nincr a 9\r\nget a b\r\ndelete b\r\n",
+					"STORED\r\nSTORED\r\n10\r\nVALUE a 0 2\r\n10\r\nVALUE b 0 1\r\n2\r\nEND\r\nDELETED\r\n",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, addr := startServer(t, memcache.WithMaxItemSize(10))
+			run(t, addr, tt.steps)
+		})
+	}
+}
+
+// readCAS читает уникальное значение cas ключа командой gets.
+func readCAS(t *testing.T, conn net.Conn, r *bufio.Reader, key string) uint64 {
+	t.Helper()
+
+	_, err := fmt.Fprintf(conn, "gets %s\r\n", key)
+	require.NoError(t, err)
+
+	var k string
+	var flags, size int
+	var cas uint64
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	_, err = fmt.Sscanf(line, "VALUE %s %d %d %d\r\n", &k, &flags, &size, &cas)
+	require.NoError(t, err, line)
+
+	_, err = io.ReadFull(r, make([]byte, size+2))
+	require.NoError(t, err)
+	line, err = r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "END\r\n", line)
+
+	return cas
+}
+
+func TestConformanceCAS(t *testing.T) {
+	_, addr := startServer(t)
+
+	run(t, addr, []step{
+		{"cas foo 0 0 1 1\r\n1\r\n", "NOT_FOUND\r\n"},
+		{"set foo 0 0 1\r\n1\r\n", "STORED\r\n"},
+	})
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	cas := readCAS(t, conn, r, "foo")
+
+	// Другой клиент изменяет значение, и cas с устаревшим значением отклоняется.
+	run(t, addr, []step{
+		{"set foo 0 0 1\r\n2\r\n", "STORED\r\n"},
+		{fmt.Sprintf("cas foo 0 0 1 %d\r\n3\r\n", cas), "EXISTS\r\n"},
+	})
+
+	cas = readCAS(t, conn, r, "foo")
+	run(t, addr, []step{
+		{fmt.Sprintf("cas foo 7 0 1 %d\r\n4\r\n", cas), "STORED\r\n"},
+		{"get foo\r\n", "VALUE foo 7 1\r\n4\r\nEND\r\n"},
+		{"cas foo 0 0 1 x\r\n1\r\n", "CLIENT_ERROR bad command line format\r\n"},
+	})
+
+	// touch не меняет значение cas.
+	cas = readCAS(t, conn, r, "foo")
+	run(t, addr, []step{{"touch foo 100\r\n", "TOUCHED\r\n"}})
+	assert.Equal(t, cas, readCAS(t, conn, r, "foo"))
+}
+
+func TestConformanceExptime(t *testing.T) {
+	cache, addr := startServer(t)
+	ctx := context.Background()
+
+	absolute := time.Now().Add(time.Hour).Unix()
+	run(t, addr, []step{
+		{"set relative 0 100 1\r\n1\r\n", "STORED\r\n"},
+		{"set month 0 2592000 1\r\n1\r\n", "STORED\r\n"},
+		{fmt.Sprintf("set absolute 0 %d 1\r\n1\r\n", absolute), "STORED\r\n"},
+		{"set default 0 0 1\r\n1\r\n", "STORED\r\n"},
+	})
+
+	tests := []struct {
+		key  string
+		want time.Time
+	}{
+		{"relative", time.Now().Add(100 * time.Second)},
+		// Ровно 30 дней - еще относительное время.
+		{"month", time.Now().Add(30 * 24 * time.Hour)},
+		// Больше 30 дней - абсолютное время unix.
+		{"absolute", time.Unix(absolute, 0)},
+		// 0 - время жизни кэша по умолчанию.
+		{"default", time.Now().Add(time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			_, expiresAt, err := cache.Get(ctx, tt.key)
+			require.NoError(t, err)
+			assert.WithinDuration(t, tt.want, expiresAt, 2*time.Second)
+		})
+	}
+}
+
+func TestConformanceSharesCache(t *testing.T) {
+	cache, addr := startServer(t)
+	ctx := context.Background()
+	require.NoError(t, cache.Put(ctx, "http", "from http", 0))
+	require.NoError(t, cache.Put(ctx, "num", float64(7), 0))
+
+	run(t, addr, []step{
+		{"get http num\r\n", "VALUE http 0 9\r\nfrom http\r\nVALUE num 0 1\r\n7\r\nEND\r\n"},
+		{"set plain 0 0 5\r\nvalue\r\n", "STORED\r\n"},
+		{"set flagged 1 0 5\r\nvalue\r\n", "STORED\r\n"},
+	})
+
+	// Значения с нулевыми флагами доступны другим протоколам как строки.
+	value, _, err := cache.Get(ctx, "plain")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	value, _, err = cache.Get(ctx, "flagged")
+	require.NoError(t, err)
+	assert.Equal(t, memcache.Value{Data: "value", Flags: 1}, value)
+}
+
+func TestConformanceStats(t *testing.T) {
+	_, addr := startServer(t)
+
+	run(t, addr, []step{
+		{"set foo 0 0 1\r\n1\r\n", "STORED\r\n"},
+		{"get foo missing\r\n", "VALUE foo 0 1\r\n1\r\nEND\r\n"},
+	})
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	_, err = conn.Write([]byte("stats\r\n"))
+	require.NoError(t, err)
+
+	stats := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		if line == "END\r\n" {
+			break
+		}
+		fields := strings.Fields(line)
+		require.Len(t, fields, 3, line)
+		require.Equal(t, "STAT", fields[0])
+		stats[fields[1]] = fields[2]
+	}
+
+	assert.Equal(t, "1", stats["cmd_set"])
+	assert.Equal(t, "2", stats["cmd_get"])
+	assert.Equal(t, "1", stats["get_hits"])
+	assert.Equal(t, "1", stats["get_misses"])
+	assert.Equal(t, "1", stats["curr_items"])
+	assert.Equal(t, "2", stats["total_connections"])
+	assert.Equal(t, "1.6.21", stats["version"])
+
+	run(t, addr, []step{{"stats slabs\r\n", "ERROR\r\n"}})
+}
+
+func TestConformanceQuit(t *testing.T) {
+	_, addr := startServer(t)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("quit\r\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = bufio.NewReader(conn).ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestConformanceReadOnly(t *testing.T) {
+	cache := lru.NewLRUCache(100, time.Minute)
+	require.NoError(t, cache.Put(context.Background(), "a", "1", 0))
+	addr := serve(t, replication.ReadOnly{Cache: cache})
+
+	readOnly := "SERVER_ERROR " + replication.ErrReadOnly.Error() + "\r\n"
+	run(t, addr, []step{
+		{"get a\r\n", "VALUE a 0 1\r\n1\r\nEND\r\n"},
+		{"set a 0 0 1\r\n2\r\n", readOnly},
+		{"delete a\r\n", readOnly},
+		{"incr a 1\r\n", readOnly},
+		{"touch a 10\r\n", readOnly},
+		{"delete missing\r\n", readOnly},
+		{"get a\r\n", "VALUE a 0 1\r\n1\r\nEND\r\n"},
+	})
+}
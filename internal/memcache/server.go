@@ -0,0 +1,273 @@
+// Package memcache реализует TCP-сервер текстового протокола memcached поверх LRU-кэша
+// для сервисов, которые работают только с клиентами memcached.
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxItemSize - максимальный размер значения в байтах по умолчанию, как в memcached.
+const DefaultMaxItemSize = 1 << 20
+
+// ErrServerClosed возвращается методами Serve и ListenAndServe после вызова Shutdown.
+var ErrServerClosed = errors.New("memcache: server closed")
+
+// Cache описывает операции кэша, используемые сервером memcached.
+// Реализуется *lru.Cache, тот же экземпляр обслуживает HTTP-запросы.
+type Cache interface {
+	// Put добавляет или обновляет элемент в кэше.
+	Put(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// GetVersion возвращает значение, время истечения и версию элемента.
+	GetVersion(ctx context.Context, key string) (value interface{}, expiresAt time.Time, version uint64, err error)
+	// Evict удаляет элемент из кэша по ключу.
+	Evict(ctx context.Context, key string) (value interface{}, err error)
+	// EvictAll удаляет все элементы из кэша.
+	EvictAll(ctx context.Context) error
+	// Add добавляет элемент, только если ключа нет в кэше.
+	Add(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// Replace заменяет значение, только если ключ есть в кэше.
+	Replace(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// CompareAndSwap заменяет значение, только если версия элемента не изменилась.
+	CompareAndSwap(ctx context.Context, key string, value interface{}, ttl time.Duration, version uint64) error
+	// Expire задает новое время жизни элемента.
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	// Update атомарно изменяет значение элемента, сохраняя время его истечения.
+	Update(ctx context.Context, key string, fn lru.ComputeFunc) (value interface{}, err error)
+	// Stats возвращает статистику кэша.
+	Stats() lru.Stats
+}
+
+// Server обслуживает клиентов memcached по текстовому протоколу.
+type Server struct {
+	Addr string       // Адрес, на котором сервер принимает соединения.
+	LRU  Cache        // Кэш, над которым выполняются команды.
+	Log  *slog.Logger // Логгер для записи событий сервера.
+
+	maxItemSize int           // Максимальный размер значения в байтах.
+	defaultTTL  time.Duration // Время жизни, которое touch с exptime 0 возвращает элементу.
+	started     time.Time     // Время создания сервера, отдается в stats.
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+
+	stats counters
+}
+
+// counters содержит счетчики команд, отдаваемые командой stats.
+type counters struct {
+	totalConnections atomic.Uint64
+	cmdGet           atomic.Uint64
+	cmdSet           atomic.Uint64
+	cmdTouch         atomic.Uint64
+	cmdFlush         atomic.Uint64
+	getHits          atomic.Uint64
+	getMisses        atomic.Uint64
+	casHits          atomic.Uint64
+	casMisses        atomic.Uint64
+	casBadval        atomic.Uint64
+}
+
+// Option задает дополнительные параметры Server.
+type Option func(s *Server)
+
+// WithMaxItemSize задает максимальный размер значения в байтах.
+// Значения большего размера отклоняются ответом "SERVER_ERROR object too large for cache".
+func WithMaxItemSize(n int) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxItemSize = n
+		}
+	}
+}
+
+// WithDefaultTTL задает время жизни элементов кэша по умолчанию. Команда touch с exptime 0
+// устанавливает элементу это время жизни. По умолчанию одна минута.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		if ttl > 0 {
+			s.defaultTTL = ttl
+		}
+	}
+}
+
+// NewServer создает сервер memcached, который будет принимать соединения по адресу address.
+func NewServer(address string, cache Cache, log *slog.Logger, opts ...Option) *Server {
+	s := &Server{
+		Addr:        address,
+		LRU:         cache,
+		Log:         log.With(slog.String("component", "memcache")),
+		maxItemSize: DefaultMaxItemSize,
+		defaultTTL:  time.Minute,
+		started:     time.Now(),
+		conns:       make(map[net.Conn]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// ListenAndServe открывает TCP-сокет по адресу Addr и обслуживает соединения до вызова Shutdown.
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve принимает соединения из ln и обслуживает каждое в отдельной горутине.
+// Всегда возвращает ненулевую ошибку, после Shutdown - ErrServerClosed.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = ln.Close()
+		return ErrServerClosed
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	s.Log.Info("starting memcache server on " + ln.Addr().String())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		if !s.track(conn) {
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+// Shutdown прекращает прием соединений и ожидает завершения текущих команд.
+// Соединения закрываются после отправки ответа на выполняемую команду.
+// Если ctx завершается раньше, оставшиеся соединения закрываются принудительно.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	// Прерываем ожидание следующей команды, ответ на текущую команду будет дописан.
+	for conn := range s.conns {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			_ = conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// track регистрирует соединение. Возвращает false, если сервер уже останавливается.
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	s.stats.totalConnections.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+// serveConn читает команды соединения и отвечает на них в порядке поступления.
+// Ответы отправляются, когда прочитаны все уже полученные команды, что ускоряет
+// мульти-запросы клиентов, отправляющих несколько команд без ожидания ответа.
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.untrack(conn)
+	defer conn.Close()
+
+	c := &client{
+		server: s,
+		r:      bufio.NewReader(conn),
+		w:      bufio.NewWriter(conn),
+	}
+	log := s.Log.With(slog.String("remote_addr", conn.RemoteAddr().String()))
+
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			if errors.Is(err, errLineTooLong) {
+				c.reply("CLIENT_ERROR line is too long")
+				_ = c.w.Flush()
+				log.Warn("closing connection", sl.Err(err))
+			} else if !errors.Is(err, io.EOF) {
+				log.Debug("failed to read command", sl.Err(err))
+			}
+			return
+		}
+
+		if err = c.execute(line); err != nil {
+			log.Debug("failed to read command data", sl.Err(err))
+			return
+		}
+
+		if c.r.Buffered() == 0 || c.quit {
+			if err = c.w.Flush(); err != nil {
+				log.Debug("failed to write reply", sl.Err(err))
+				return
+			}
+		}
+		if c.quit {
+			return
+		}
+	}
+}
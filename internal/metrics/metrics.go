@@ -0,0 +1,326 @@
+// Package metrics реализует реестр метрик и их вывод в текстовом формате Prometheus.
+// Пакет не зависит от клиентских библиотек Prometheus: формат вывода (exposition format 0.0.4) сформирован вручную.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType - тип содержимого ответа в текстовом формате Prometheus.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets - границы корзин гистограммы по умолчанию в секундах, совпадают с клиентом Prometheus.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Sample представляет значение метрики с набором значений меток.
+type Sample struct {
+	LabelValues []string // Значения меток в порядке их объявления.
+	Value       float64  // Значение метрики.
+}
+
+// family описывает семейство метрик с общим именем.
+type family interface {
+	write(w *bufio.Writer)
+}
+
+// Registry хранит зарегистрированные метрики и выводит их в текстовом формате Prometheus.
+type Registry struct {
+	mu       sync.Mutex
+	names    map[string]struct{}
+	families []family
+}
+
+// NewRegistry создает пустой реестр метрик.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]struct{})}
+}
+
+func (r *Registry) register(name string, f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.names[name]; exists {
+		panic("metrics: duplicate metric name " + name)
+	}
+	r.names[name] = struct{}{}
+	r.families = append(r.families, f)
+}
+
+// WriteTo выводит все метрики реестра в текстовом формате Prometheus.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := append([]family(nil), r.families...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, f := range families {
+		f.write(bw)
+	}
+	err := bw.Flush()
+
+	return cw.n, err
+}
+
+// Handler возвращает HTTP-обработчик, который отдает метрики реестра.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// CounterVec представляет счетчик с набором меток.
+type CounterVec struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounterVec регистрирует счетчик с заданными именами меток.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*counterValue)}
+	r.register(name, c)
+	return c
+}
+
+// Inc увеличивает счетчик с заданными значениями меток на единицу.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add увеличивает счетчик с заданными значениями меток на delta.
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	key := seriesKey(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.values[key]
+	if !ok {
+		v = &counterValue{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = v
+	}
+	v.value += delta
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	samples := make([]Sample, 0, len(c.values))
+	for _, v := range c.values {
+		samples = append(samples, Sample{LabelValues: v.labelValues, Value: v.value})
+	}
+	c.mu.Unlock()
+
+	writeSamples(w, c.name, c.help, "counter", c.labels, samples)
+}
+
+// HistogramVec представляет гистограмму с набором меток.
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+// NewHistogramVec регистрирует гистограмму с заданными границами корзин и именами меток.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*histogramValue)}
+	r.register(name, h)
+	return h
+}
+
+// Observe добавляет наблюдение в гистограмму с заданными значениями меток.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := seriesKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{labelValues: append([]string(nil), labelValues...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = v
+	}
+
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		v.counts[i]++
+	}
+	v.count++
+	v.sum += value
+}
+
+// ObserveDuration добавляет в гистограмму время, прошедшее с момента start, в секундах.
+func (h *HistogramVec) ObserveDuration(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+
+	for _, key := range sortedKeys(h.values) {
+		v := h.values[key]
+
+		bucketLabels := withLabel(h.labels, "le")
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += v.counts[i]
+			writeSample(w, h.name+"_bucket", bucketLabels, withLabel(v.labelValues, formatFloat(upper)), float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", bucketLabels, withLabel(v.labelValues, "+Inf"), float64(v.count))
+		writeSample(w, h.name+"_sum", h.labels, v.labelValues, v.sum)
+		writeSample(w, h.name+"_count", h.labels, v.labelValues, float64(v.count))
+	}
+}
+
+// funcFamily представляет метрику, значения которой вычисляются при каждом выводе.
+type funcFamily struct {
+	name, help, typ string
+	labels          []string
+	fn              func() []Sample
+}
+
+// NewGaugeFunc регистрирует метрику-индикатор, значение которой возвращает fn.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcFamily{name: name, help: help, typ: "gauge", fn: func() []Sample {
+		return []Sample{{Value: fn()}}
+	}})
+}
+
+// NewGaugeVecFunc регистрирует метрику-индикатор с метками, значения которой возвращает fn.
+func (r *Registry) NewGaugeVecFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(name, &funcFamily{name: name, help: help, typ: "gauge", labels: labels, fn: fn})
+}
+
+// NewCounterFunc регистрирует счетчик с метками, значения которого возвращает fn.
+// Используется для счетчиков, которые ведет сам источник данных, например кэш.
+func (r *Registry) NewCounterFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(name, &funcFamily{name: name, help: help, typ: "counter", labels: labels, fn: fn})
+}
+
+func (f *funcFamily) write(w *bufio.Writer) {
+	writeSamples(w, f.name, f.help, f.typ, f.labels, f.fn())
+}
+
+func writeSamples(w *bufio.Writer, name, help, typ string, labels []string, samples []Sample) {
+	sort.Slice(samples, func(i, j int) bool {
+		return seriesKey(samples[i].LabelValues) < seriesKey(samples[j].LabelValues)
+	})
+
+	writeHeader(w, name, help, typ)
+	for _, s := range samples {
+		writeSample(w, name, labels, s.LabelValues, s.Value)
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+func writeSample(w *bufio.Writer, name string, labels, labelValues []string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			var lv string
+			if i < len(labelValues) {
+				lv = labelValues[i]
+			}
+			w.WriteString(label)
+			w.WriteString(`="`)
+			w.WriteString(escapeLabelValue(lv))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string { return helpReplacer.Replace(s) }
+
+func escapeLabelValue(s string) string { return labelReplacer.Replace(s) }
+
+// withLabel возвращает копию списка с добавленным в конец элементом.
+func withLabel(values []string, value string) []string {
+	return append(append(make([]string, 0, len(values)+1), values...), value)
+}
+
+// seriesKey формирует ключ временного ряда по значениям меток.
+func seriesKey(labelValues []string) string { return strings.Join(labelValues, "\xff") }
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// countingWriter подсчитывает количество записанных байт.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
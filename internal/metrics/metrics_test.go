@@ -0,0 +1,89 @@
+package metrics_test
+
+import (
+	"bytes"
+	"github.com/instinctG/lru-cache/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	requests := reg.NewCounterVec("requests_total", "Total requests.", "method", "path")
+	requests.Inc("GET", "/a")
+	requests.Add(2, "GET", `/b"\`)
+
+	latency := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{1, 0.1}, "method")
+	latency.Observe(0.05, "GET")
+	latency.Observe(0.5, "GET")
+	latency.Observe(5, "GET")
+
+	reg.NewGaugeFunc("size", "Current size.", func() float64 { return 3 })
+	reg.NewGaugeVecFunc("tenant_size", "Size by tenant.", []string{"tenant"}, func() []metrics.Sample {
+		return []metrics.Sample{
+			{LabelValues: []string{"b"}, Value: 4},
+			{LabelValues: []string{"a"}, Value: 5},
+		}
+	})
+	reg.NewCounterFunc("evictions_total", "Evictions\nby reason.", []string{"reason"}, func() []metrics.Sample {
+		return []metrics.Sample{
+			{LabelValues: []string{"expired"}, Value: 1},
+			{LabelValues: []string{"capacity"}, Value: 2},
+		}
+	})
+
+	var buf bytes.Buffer
+	n, err := reg.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	expected := `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{method="GET",path="/a"} 1
+requests_total{method="GET",path="/b\"\\"} 2
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="GET",le="0.1"} 1
+latency_seconds_bucket{method="GET",le="1"} 2
+latency_seconds_bucket{method="GET",le="+Inf"} 3
+latency_seconds_sum{method="GET"} 5.55
+latency_seconds_count{method="GET"} 3
+# HELP size Current size.
+# TYPE size gauge
+size 3
+# HELP tenant_size Size by tenant.
+# TYPE tenant_size gauge
+tenant_size{tenant="a"} 5
+tenant_size{tenant="b"} 4
+# HELP evictions_total Evictions\nby reason.
+# TYPE evictions_total counter
+evictions_total{reason="capacity"} 2
+evictions_total{reason="expired"} 1
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestRegistry_DuplicateName(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.NewCounterVec("requests_total", "Total requests.")
+
+	assert.Panics(t, func() {
+		reg.NewGaugeFunc("requests_total", "Duplicate.", func() float64 { return 0 })
+	})
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.NewGaugeFunc("up", "Service is up.", func() float64 { return 1 })
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, metrics.ContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "up 1\n")
+}
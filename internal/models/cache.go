@@ -0,0 +1,27 @@
+package models
+
+// CreateCacheRequest представляет тело запроса на создание именованного кэша.
+type CreateCacheRequest struct {
+	Name       string `json:"name" validate:"required,max=64"`                      // Имя кэша: буквы, цифры, '-' и '_' (обязательное поле).
+	Capacity   int    `json:"capacity" validate:"required,gt=0"`                    // Максимальное количество элементов (обязательное поле).
+	TTLSeconds int    `json:"ttl_seconds,omitempty" validate:"gte=0"`               // Время жизни элементов по умолчанию в секундах, 0 - как у кэша по умолчанию.
+	Policy     string `json:"policy,omitempty" validate:"omitempty,oneof=lru fifo"` // Политика вытеснения: lru (по умолчанию) или fifo.
+}
+
+// CacheInfo описывает именованный кэш и его статистику.
+type CacheInfo struct {
+	Name       string `json:"name"`              // Имя кэша.
+	Default    bool   `json:"default,omitempty"` // Признак кэша по умолчанию.
+	Capacity   int    `json:"capacity"`          // Максимальное количество элементов.
+	TTLSeconds int64  `json:"ttl_seconds"`       // Время жизни элементов по умолчанию в секундах.
+	Policy     string `json:"policy"`            // Политика вытеснения.
+	Size       int    `json:"size"`              // Текущее количество элементов.
+	Hits       uint64 `json:"hits"`              // Количество успешных чтений.
+	Misses     uint64 `json:"misses"`            // Количество чтений отсутствующих ключей.
+}
+
+// CacheList представляет список именованных кэшей.
+type CacheList struct {
+	Items []CacheInfo `json:"items"` // Кэши, первым идет кэш по умолчанию.
+	Count int         `json:"count"` // Количество кэшей.
+}
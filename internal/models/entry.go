@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Entry представляет элемент кэша в API версии 2.
+type Entry struct {
+	Key       string      `json:"key"`                  // Ключ элемента.
+	Value     interface{} `json:"value"`                // Значение элемента.
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"` // Время истечения срока действия элемента в формате RFC 3339.
+}
+
+// EntryList представляет список элементов кэша в API версии 2.
+type EntryList struct {
+	Items []Entry `json:"items"` // Элементы кэша.
+	Count int     `json:"count"` // Количество элементов.
+}
+
+// PutEntryRequest представляет тело запроса на запись элемента в API версии 2.
+// Ключ передается в пути запроса.
+type PutEntryRequest struct {
+	Value      interface{} `json:"value" validate:"required"`                     // Значение элемента (обязательное поле).
+	TTLSeconds int         `json:"ttl_seconds,omitempty" validate:"number,gte=0"` // Время жизни элемента в секундах (необязательное поле, должно быть >= 0).
+}
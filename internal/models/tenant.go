@@ -0,0 +1,27 @@
+package models
+
+// SetTenantQuotaRequest представляет тело запроса на изменение квоты арендатора.
+type SetTenantQuotaRequest struct {
+	MaxEntries int   `json:"max_entries" validate:"required,gt=0"`   // Максимальное количество элементов (обязательное поле).
+	MaxBytes   int64 `json:"max_bytes,omitempty" validate:"gte=0"`   // Максимальный суммарный размер элементов в байтах, 0 - без ограничения.
+	TTLSeconds int   `json:"ttl_seconds,omitempty" validate:"gte=0"` // Время жизни элементов по умолчанию в секундах, 0 - по умолчанию сервиса.
+}
+
+// TenantInfo описывает квоту арендатора и статистику его кэша.
+type TenantInfo struct {
+	Name       string            `json:"name"`        // Имя арендатора.
+	MaxEntries int               `json:"max_entries"` // Максимальное количество элементов.
+	MaxBytes   int64             `json:"max_bytes"`   // Максимальный суммарный размер элементов в байтах, 0 - без ограничения.
+	TTLSeconds int64             `json:"ttl_seconds"` // Время жизни элементов по умолчанию в секундах.
+	Size       int               `json:"size"`        // Текущее количество элементов.
+	Bytes      int64             `json:"bytes"`       // Суммарный размер элементов в байтах, учитывается только при max_bytes > 0.
+	Hits       uint64            `json:"hits"`        // Количество успешных чтений.
+	Misses     uint64            `json:"misses"`      // Количество чтений отсутствующих ключей.
+	Evictions  map[string]uint64 `json:"evictions"`   // Количество удаленных элементов по причинам.
+}
+
+// TenantList представляет список арендаторов.
+type TenantList struct {
+	Items []TenantInfo `json:"items"` // Арендаторы в порядке имен.
+	Count int          `json:"count"` // Количество арендаторов.
+}
@@ -0,0 +1,211 @@
+// Package namespace управляет именованными кэшами (пространствами имен), которые обслуживает один сервер.
+// У каждого кэша своя емкость, время жизни элементов по умолчанию и политика вытеснения.
+package namespace
+
+import (
+	"errors"
+	"fmt"
+	"github.com/instinctG/lru-cache/pkg/lrucache"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultName - имя кэша по умолчанию, с которым работают /api/lru, /api/v2, gRPC, RESP и memcached.
+const DefaultName = "default"
+
+var (
+	ErrNotFound    = errors.New("cache not found")                 // ErrNotFound возвращается, если кэша с таким именем нет.
+	ErrExists      = errors.New("cache already exists")            // ErrExists возвращается при создании кэша с занятым именем.
+	ErrDefault     = errors.New("default cache cannot be dropped") // ErrDefault возвращается при попытке удалить кэш по умолчанию.
+	ErrInvalidName = errors.New("invalid cache name")              // ErrInvalidName возвращается, если имя не подходит под шаблон.
+)
+
+// namePattern - допустимые имена кэшей: они используются в пути запроса.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Spec описывает параметры именованного кэша.
+type Spec struct {
+	Name     string          // Имя кэша.
+	Capacity int             // Максимальное количество элементов.
+	TTL      time.Duration   // Время жизни элемента по умолчанию.
+	Policy   lrucache.Policy // Политика вытеснения.
+}
+
+// Validate проверяет имя и емкость кэша.
+func (s Spec) Validate() error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("%w %q: expected 1-64 letters, digits, '-' or '_'", ErrInvalidName, s.Name)
+	}
+	if s.Capacity <= 0 {
+		return fmt.Errorf("cache %q: capacity must be positive", s.Name)
+	}
+	if s.TTL < 0 {
+		return fmt.Errorf("cache %q: ttl must not be negative", s.Name)
+	}
+	return nil
+}
+
+// Namespace - именованный кэш.
+type Namespace struct {
+	Spec
+	Cache *lrucache.Cache
+}
+
+// NewCache создает кэш с параметрами spec.
+func NewCache(spec Spec) *lrucache.Cache {
+	return lrucache.New(
+		lrucache.WithCapacity(spec.Capacity),
+		lrucache.WithTTL(spec.TTL),
+		lrucache.WithPolicy(spec.Policy),
+	)
+}
+
+// Registry хранит именованные кэши. Кэш по умолчанию создается и обслуживается отдельно,
+// реестр хранит только его параметры для вывода списка кэшей и значений по умолчанию.
+type Registry struct {
+	mu     sync.RWMutex
+	def    Spec
+	caches map[string]*Namespace
+}
+
+// NewRegistry создает пустой реестр. def - параметры кэша по умолчанию, его TTL и политика
+// используются для новых кэшей, в которых они не заданы.
+func NewRegistry(def Spec) *Registry {
+	def.Name = DefaultName
+
+	return &Registry{
+		def:    def,
+		caches: make(map[string]*Namespace),
+	}
+}
+
+// Default возвращает параметры кэша по умолчанию.
+func (r *Registry) Default() Spec {
+	return r.def
+}
+
+// Create создает именованный кэш. Нулевой TTL заменяется TTL кэша по умолчанию.
+func (r *Registry) Create(spec Spec) (*Namespace, error) {
+	if spec.TTL == 0 {
+		spec.TTL = r.def.TTL
+	}
+	if spec.TTL == 0 {
+		spec.TTL = lrucache.DefaultTTL
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.caches[spec.Name]; exists || spec.Name == DefaultName {
+		return nil, fmt.Errorf("%w: %s", ErrExists, spec.Name)
+	}
+
+	ns := &Namespace{Spec: spec, Cache: NewCache(spec)}
+	r.caches[spec.Name] = ns
+
+	return ns, nil
+}
+
+// Get возвращает именованный кэш. Кэш по умолчанию реестр не хранит.
+func (r *Registry) Get(name string) (*Namespace, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ns, ok := r.caches[name]
+	return ns, ok
+}
+
+// Drop удаляет именованный кэш вместе с элементами. Запросы, которые уже получили кэш, завершаются с ним.
+func (r *Registry) Drop(name string) error {
+	if name == DefaultName {
+		return ErrDefault
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.caches[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(r.caches, name)
+
+	return nil
+}
+
+// List возвращает именованные кэши в порядке имен.
+func (r *Registry) List() []*Namespace {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*Namespace, 0, len(r.caches))
+	for _, ns := range r.caches {
+		list = append(list, ns)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list
+}
+
+// ParseSpecs разбирает описание кэшей в формате "имя=емкость:ttl[:политика],...",
+// например "sessions=1000:30m,thumbnails=500:1h:fifo". TTL и политику можно не указывать,
+// тогда используются TTL и политика кэша по умолчанию def.
+func ParseSpecs(spec string, def Spec) ([]Spec, error) {
+	var specs []Spec
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, params, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid cache %q, expected name=capacity:ttl[:policy]", entry)
+		}
+
+		parts := strings.Split(params, ":")
+		if len(parts) > 3 {
+			return nil, fmt.Errorf("invalid cache %q, expected name=capacity:ttl[:policy]", entry)
+		}
+
+		s := Spec{Name: name, TTL: def.TTL, Policy: def.Policy}
+
+		capacity, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("cache %q: invalid capacity %q", name, parts[0])
+		}
+		s.Capacity = capacity
+
+		if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" {
+			if s.TTL, err = time.ParseDuration(strings.TrimSpace(parts[1])); err != nil {
+				return nil, fmt.Errorf("cache %q: invalid ttl: %w", name, err)
+			}
+		}
+		if len(parts) > 2 {
+			if s.Policy, err = lrucache.ParsePolicy(parts[2]); err != nil {
+				return nil, fmt.Errorf("cache %q: %w", name, err)
+			}
+		}
+
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[name] || name == DefaultName {
+			return nil, fmt.Errorf("%w: %s", ErrExists, name)
+		}
+		seen[name] = true
+
+		specs = append(specs, s)
+	}
+
+	return specs, nil
+}
@@ -0,0 +1,102 @@
+package namespace_test
+
+import (
+	"context"
+	"github.com/instinctG/lru-cache/internal/namespace"
+	"github.com/instinctG/lru-cache/pkg/lrucache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseSpecs(t *testing.T) {
+	def := namespace.Spec{Capacity: 10, TTL: time.Minute, Policy: lrucache.PolicyFIFO}
+
+	tests := []struct {
+		name     string
+		spec     string
+		expected []namespace.Spec
+		wantErr  bool
+	}{
+		{name: "Empty", spec: ""},
+		{
+			name: "Defaults",
+			spec: "sessions=100",
+			expected: []namespace.Spec{
+				{Name: "sessions", Capacity: 100, TTL: time.Minute, Policy: lrucache.PolicyFIFO},
+			},
+		},
+		{
+			name: "Full",
+			spec: " sessions=100:30m:lru , thumbs=5:1h,",
+			expected: []namespace.Spec{
+				{Name: "sessions", Capacity: 100, TTL: 30 * time.Minute, Policy: lrucache.PolicyLRU},
+				{Name: "thumbs", Capacity: 5, TTL: time.Hour, Policy: lrucache.PolicyFIFO},
+			},
+		},
+		{name: "Without capacity", spec: "sessions", wantErr: true},
+		{name: "Invalid capacity", spec: "sessions=many", wantErr: true},
+		{name: "Zero capacity", spec: "sessions=0", wantErr: true},
+		{name: "Invalid ttl", spec: "sessions=1:forever", wantErr: true},
+		{name: "Unknown policy", spec: "sessions=1:1m:lfu", wantErr: true},
+		{name: "Too many parts", spec: "sessions=1:1m:lru:x", wantErr: true},
+		{name: "Invalid name", spec: "a/b=1", wantErr: true},
+		{name: "Duplicate name", spec: "a=1,a=2", wantErr: true},
+		{name: "Default name", spec: "default=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specs, err := namespace.ParseSpecs(tt.spec, def)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, specs)
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	reg := namespace.NewRegistry(namespace.Spec{Capacity: 10, TTL: time.Minute})
+
+	assert.Equal(t, namespace.DefaultName, reg.Default().Name)
+
+	ns, err := reg.Create(namespace.Spec{Name: "b", Capacity: 1})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ns.TTL, "zero TTL is taken from the default cache")
+
+	_, err = reg.Create(namespace.Spec{Name: "a", Capacity: 2, TTL: time.Hour, Policy: lrucache.PolicyFIFO})
+	require.NoError(t, err)
+
+	_, err = reg.Create(namespace.Spec{Name: "a", Capacity: 1})
+	assert.ErrorIs(t, err, namespace.ErrExists)
+	_, err = reg.Create(namespace.Spec{Name: namespace.DefaultName, Capacity: 1})
+	assert.ErrorIs(t, err, namespace.ErrExists)
+	_, err = reg.Create(namespace.Spec{Name: "", Capacity: 1})
+	assert.ErrorIs(t, err, namespace.ErrInvalidName)
+
+	list := reg.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "a", list[0].Name)
+	assert.Equal(t, "b", list[1].Name)
+
+	// Емкость кэша ограничивает только его собственные элементы.
+	require.NoError(t, ns.Cache.Put(ctx, "x", 1, 0))
+	require.NoError(t, ns.Cache.Put(ctx, "y", 2, 0))
+	assert.Equal(t, 1, ns.Cache.Stats().Size)
+
+	got, ok := reg.Get("b")
+	require.True(t, ok)
+	assert.Same(t, ns, got)
+
+	assert.ErrorIs(t, reg.Drop(namespace.DefaultName), namespace.ErrDefault)
+	require.NoError(t, reg.Drop("b"))
+	assert.ErrorIs(t, reg.Drop("b"), namespace.ErrNotFound)
+
+	_, ok = reg.Get("b")
+	assert.False(t, ok)
+}
@@ -0,0 +1,390 @@
+package replication
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/instinctG/lru-cache/internal/http-server/problem"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBacklog - размер журнала изменений ведущего узла по умолчанию.
+	DefaultBacklog = 10000
+	// DefaultHeartbeat - интервал сообщений OpPing в потоке без изменений по умолчанию.
+	DefaultHeartbeat = time.Second
+)
+
+const (
+	// batchSize - максимальное количество записей журнала, отправляемых реплике без сброса буфера ответа.
+	batchSize = 256
+	// eventsBuffer - размер буфера подписки журнала на изменения кэша.
+	eventsBuffer = 4096
+)
+
+// Source - кэш ведущего узла, изменения которого передаются репликам.
+type Source interface {
+	// Subscribe подписывает на изменения элементов кэша.
+	Subscribe(buffer int) (events <-chan lru.Event, cancel func())
+	// Snapshot возвращает элементы кэша и смещение последнего изменения, отраженного в снимке.
+	Snapshot() (entries []lru.Entry, seq uint64)
+}
+
+// record - запись журнала изменений.
+type record struct {
+	event lru.Event
+	time  time.Time
+}
+
+// stream - поток репликации, открытый репликой.
+type stream struct {
+	address string
+	offset  uint64
+	since   time.Time
+}
+
+// Primary - ведущий узел репликации. Primary ведет журнал изменений кэша (см. Run)
+// и передает его репликам по HTTP (см. ServeHTTP).
+type Primary struct {
+	source    Source
+	log       *slog.Logger
+	id        string
+	heartbeat time.Duration
+
+	mu      sync.Mutex
+	backlog []record             // Кольцевой буфер журнала, запись со смещением o хранится в backlog[o%len(backlog)].
+	first   uint64               // Смещение самой старой записи журнала.
+	next    uint64               // Смещение следующей записи журнала, 0 - журнал еще пуст.
+	notify  chan struct{}        // Закрывается при добавлении записи в журнал.
+	streams map[*stream]struct{} // Открытые потоки репликации.
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// PrimaryOption задает параметры ведущего узла.
+type PrimaryOption func(p *Primary)
+
+// WithBacklog задает количество последних изменений, с которых реплика может продолжить поток
+// без загрузки снимка. По умолчанию DefaultBacklog.
+func WithBacklog(n int) PrimaryOption {
+	return func(p *Primary) {
+		if n > 0 {
+			p.backlog = make([]record, n)
+		}
+	}
+}
+
+// WithHeartbeat задает интервал сообщений OpPing в потоке без изменений. По умолчанию DefaultHeartbeat.
+func WithHeartbeat(d time.Duration) PrimaryOption {
+	return func(p *Primary) {
+		if d > 0 {
+			p.heartbeat = d
+		}
+	}
+}
+
+// NewPrimary создает ведущий узел для кэша source. Идентификатор журнала генерируется заново
+// при каждом создании, поэтому после перезапуска ведущего узла реплики загружают снимок.
+func NewPrimary(source Source, log *slog.Logger, opts ...PrimaryOption) *Primary {
+	p := &Primary{
+		source:    source,
+		log:       log,
+		id:        newID(),
+		heartbeat: DefaultHeartbeat,
+		notify:    make(chan struct{}),
+		streams:   make(map[*stream]struct{}),
+		closed:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.backlog == nil {
+		p.backlog = make([]record, DefaultBacklog)
+	}
+
+	return p
+}
+
+// newID возвращает случайный идентификатор журнала.
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ID возвращает идентификатор журнала ведущего узла.
+func (p *Primary) ID() string {
+	return p.id
+}
+
+// Run записывает изменения кэша в журнал, пока не завершится ctx или не будет вызван Close.
+// Если журнал не успевает читать изменения, подписка возобновляется, журнал очищается,
+// и реплики загружают снимок заново.
+func (p *Primary) Run(ctx context.Context) {
+	for {
+		events, cancel := p.source.Subscribe(eventsBuffer)
+		done := p.consume(ctx, events)
+		cancel()
+		if done {
+			return
+		}
+
+		p.log.Warn("replication log fell behind the cache, replicas will resync")
+		p.reset()
+	}
+}
+
+// consume записывает события в журнал. Возвращает true, если работа журнала завершена,
+// и false, если подписка была закрыта кэшем.
+func (p *Primary) consume(ctx context.Context, events <-chan lru.Event) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return true
+		case <-p.closed:
+			return true
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			p.append(event)
+		}
+	}
+}
+
+// Close завершает открытые потоки репликации и работу журнала.
+func (p *Primary) Close() {
+	p.closeOnce.Do(func() { close(p.closed) })
+}
+
+// append добавляет изменение в журнал. Изменения, уже отраженные в переданном снимке, пропускаются.
+// При пропуске изменений журнал начинается заново с event.
+func (p *Primary) append(event lru.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case p.next == 0:
+		p.first = event.Seq
+	case event.Seq < p.next:
+		return
+	case event.Seq > p.next:
+		p.log.Warn("replication log lost changes, replicas will resync",
+			slog.Uint64("expected", p.next), slog.Uint64("got", event.Seq))
+		p.first = event.Seq
+	}
+
+	p.backlog[event.Seq%uint64(len(p.backlog))] = record{event: event, time: time.Now()}
+	p.next = event.Seq + 1
+	if p.next-p.first > uint64(len(p.backlog)) {
+		p.first = p.next - uint64(len(p.backlog))
+	}
+
+	close(p.notify)
+	p.notify = make(chan struct{})
+}
+
+// reset очищает журнал после пропуска изменений. Открытые потоки завершаются при следующем чтении журнала.
+func (p *Primary) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.first, p.next = 0, 0
+	close(p.notify)
+	p.notify = make(chan struct{})
+}
+
+// anchor начинает пустой журнал после снимка со смещением seq.
+func (p *Primary) anchor(seq uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next == 0 {
+		p.first, p.next = seq+1, seq+1
+	}
+}
+
+// resumable проверяет, может ли реплика с журналом id, применившая изменения до offset, продолжить поток.
+func (p *Primary) resumable(id string, offset uint64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return id == p.id && p.next != 0 && offset+1 >= p.first && offset+1 <= p.next
+}
+
+// read возвращает не более max записей журнала начиная со смещения from, последнее смещение журнала
+// и канал, который закрывается при добавлении записи. ok равно false, если записи from уже нет в журнале.
+func (p *Primary) read(from uint64, max int) (records []record, head uint64, wait <-chan struct{}, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next == 0 || from < p.first {
+		return nil, 0, nil, false
+	}
+	for offset := from; offset < p.next && len(records) < max; offset++ {
+		records = append(records, p.backlog[offset%uint64(len(p.backlog))])
+	}
+
+	return records, p.next - 1, p.notify, true
+}
+
+// ServeHTTP передает реплике поток репликации. Параметры запроса id и offset - идентификатор журнала
+// и последнее примененное репликой смещение. Если поток нельзя продолжить с offset, сначала передается снимок.
+func (p *Primary) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var offset uint64
+	if s := r.URL.Query().Get("offset"); s != "" {
+		var err error
+		if offset, err = strconv.ParseUint(s, 10, 64); err != nil {
+			problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "offset must be a non-negative integer"))
+			return
+		}
+	}
+
+	st := &stream{address: r.RemoteAddr, since: time.Now()}
+	p.mu.Lock()
+	p.streams[st] = struct{}{}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.streams, st)
+		p.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	rc := http.NewResponseController(w)
+	log := p.log.With(slog.String("replica", r.RemoteAddr))
+
+	from, err := p.start(enc, r.URL.Query().Get("id"), offset, log)
+	if err == nil {
+		err = rc.Flush()
+	}
+
+	p.mu.Lock()
+	st.offset = from - 1
+	p.mu.Unlock()
+
+	heartbeat := time.NewTicker(p.heartbeat)
+	defer heartbeat.Stop()
+
+	for err == nil {
+		records, head, wait, ok := p.read(from, batchSize)
+		if !ok {
+			log.Warn("replica fell behind the replication log", slog.Uint64("offset", from-1))
+			return
+		}
+
+		if len(records) > 0 {
+			for _, rec := range records {
+				if err = enc.Encode(message(rec)); err != nil {
+					break
+				}
+				from = rec.event.Seq + 1
+			}
+			if err == nil {
+				err = rc.Flush()
+			}
+
+			p.mu.Lock()
+			st.offset = from - 1
+			p.mu.Unlock()
+
+			continue
+		}
+
+		select {
+		case <-wait:
+		case <-heartbeat.C:
+			if err = enc.Encode(Message{Op: OpPing, ID: p.id, Offset: head, Time: time.Now()}); err == nil {
+				err = rc.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		case <-p.closed:
+			return
+		}
+	}
+
+	log.Info("replication stream closed", sl.Err(err))
+}
+
+// start отправляет первое сообщение потока и, если нужно, снимок кэша.
+// Возвращает смещение, с которого поток продолжается изменениями журнала.
+func (p *Primary) start(enc *json.Encoder, id string, offset uint64, log *slog.Logger) (uint64, error) {
+	if p.resumable(id, offset) {
+		log.Info("replica resumed", slog.Uint64("offset", offset))
+
+		return offset + 1, enc.Encode(Message{Op: OpContinue, ID: p.id, Offset: offset, Time: time.Now()})
+	}
+
+	entries, seq := p.source.Snapshot()
+	p.anchor(seq)
+
+	log.Info("replica full sync", slog.Uint64("offset", seq), slog.Int("entries", len(entries)))
+
+	now := time.Now()
+	if err := enc.Encode(Message{Op: OpSync, ID: p.id, Offset: seq, Entries: len(entries), Time: now}); err != nil {
+		return 0, err
+	}
+	for i := range entries {
+		msg := Message{Op: OpPut, Offset: seq, Key: entries[i].Key, ExpiresAt: &entries[i].ExpiresAt, Time: now}
+		msg.setValue(entries[i].Value)
+		if err := enc.Encode(msg); err != nil {
+			return 0, err
+		}
+	}
+
+	return seq + 1, nil
+}
+
+// message преобразует запись журнала в сообщение потока.
+func message(rec record) Message {
+	msg := Message{Offset: rec.event.Seq, Key: rec.event.Key, Time: rec.time}
+
+	switch rec.event.Type {
+	case lru.EventPut:
+		msg.Op = OpPut
+		msg.setValue(rec.event.Value)
+		msg.ExpiresAt = &rec.event.ExpiresAt
+	case lru.EventEvict:
+		msg.Op = OpEvict
+	case lru.EventFlush:
+		msg.Op = OpFlush
+	}
+
+	return msg
+}
+
+// Status возвращает состояние журнала и подключенных реплик.
+func (p *Primary) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := Status{Role: RolePrimary, ID: p.id}
+	if p.next > 0 {
+		status.Offset = p.next - 1
+	}
+
+	for st := range p.streams {
+		replica := ReplicaStatus{Address: st.address, Offset: st.offset, Since: st.since}
+		if status.Offset > st.offset {
+			replica.LagEntries = status.Offset - st.offset
+		}
+		status.Replicas = append(status.Replicas, replica)
+	}
+	sort.Slice(status.Replicas, func(i, j int) bool { return status.Replicas[i].Address < status.Replicas[j].Address })
+
+	return status
+}
@@ -0,0 +1,63 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"time"
+)
+
+// ErrReadOnly возвращается при попытке изменить кэш реплики.
+var ErrReadOnly = errors.New("replica is read-only, write to the primary")
+
+// ReadOnly - кэш реплики для протоколов без перенаправления запросов (gRPC, RESP, memcached):
+// чтение и подписка на изменения передаются кэшу, а методы изменения возвращают ErrReadOnly.
+// Изменения ведущего узла применяются к исходному кэшу.
+type ReadOnly struct {
+	*lru.Cache
+}
+
+// Put возвращает ErrReadOnly.
+func (c ReadOnly) Put(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return ErrReadOnly
+}
+
+// Add возвращает ErrReadOnly.
+func (c ReadOnly) Add(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return ErrReadOnly
+}
+
+// Replace возвращает ErrReadOnly.
+func (c ReadOnly) Replace(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return ErrReadOnly
+}
+
+// CompareAndSwap возвращает ErrReadOnly.
+func (c ReadOnly) CompareAndSwap(ctx context.Context, key string, value interface{}, ttl time.Duration, version uint64) error {
+	return ErrReadOnly
+}
+
+// Expire возвращает ErrReadOnly.
+func (c ReadOnly) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return ErrReadOnly
+}
+
+// Compute возвращает ErrReadOnly.
+func (c ReadOnly) Compute(ctx context.Context, key string, fn lru.ComputeFunc) (interface{}, error) {
+	return nil, ErrReadOnly
+}
+
+// Update возвращает ErrReadOnly.
+func (c ReadOnly) Update(ctx context.Context, key string, fn lru.ComputeFunc) (interface{}, error) {
+	return nil, ErrReadOnly
+}
+
+// Evict возвращает ErrReadOnly.
+func (c ReadOnly) Evict(ctx context.Context, key string) (interface{}, error) {
+	return nil, ErrReadOnly
+}
+
+// EvictAll возвращает ErrReadOnly.
+func (c ReadOnly) EvictAll(ctx context.Context) error {
+	return ErrReadOnly
+}
@@ -0,0 +1,334 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamPath - путь потока репликации на ведущем узле.
+const StreamPath = "/api/replication/stream"
+
+const (
+	// DefaultReadTimeout - время ожидания сообщения ведущего узла по умолчанию, после которого реплика переподключается.
+	DefaultReadTimeout = 5 * time.Second
+	// DefaultRetryInterval - начальная задержка переподключения по умолчанию. Задержка удваивается
+	// после каждой неудачной попытки до maxRetryInterval.
+	DefaultRetryInterval = time.Second
+)
+
+// maxRetryInterval - максимальная задержка переподключения.
+const maxRetryInterval = 30 * time.Second
+
+// Target - кэш реплики, к которому применяются изменения ведущего узла.
+type Target interface {
+	// Put добавляет или обновляет элемент в кэше.
+	Put(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// Evict удаляет элемент из кэша по ключу.
+	Evict(ctx context.Context, key string) (value interface{}, err error)
+	// EvictAll удаляет все элементы из кэша.
+	EvictAll(ctx context.Context) error
+}
+
+// Replica - реплика, получающая изменения кэша от ведущего узла (см. Run).
+type Replica struct {
+	primary     string
+	cache       Target
+	log         *slog.Logger
+	client      *http.Client
+	apiKey      string
+	readTimeout time.Duration
+	retry       time.Duration
+
+	mu          sync.Mutex
+	id          string    // Идентификатор журнала ведущего узла.
+	offset      uint64    // Последнее примененное смещение, 0 - снимок еще не загружен.
+	head        uint64    // Последнее известное смещение ведущего узла.
+	snapshot    uint64    // Смещение загружаемого снимка.
+	pending     int       // Количество еще не примененных элементов загружаемого снимка.
+	synced      bool      // Снимок ведущего узла загружен полностью.
+	connected   bool      // Реплика получает поток ведущего узла.
+	lastContact time.Time // Время последнего сообщения ведущего узла.
+	syncedAt    time.Time // Время, когда реплика последний раз применила все известные изменения.
+	fullSyncs   uint64    // Количество загрузок снимка.
+}
+
+// ReplicaOption задает параметры реплики.
+type ReplicaOption func(r *Replica)
+
+// WithClient задает HTTP-клиент для подключения к ведущему узлу. По умолчанию http.DefaultClient.
+func WithClient(c *http.Client) ReplicaOption {
+	return func(r *Replica) {
+		r.client = c
+	}
+}
+
+// WithAPIKey задает API-ключ, с которым реплика подключается к ведущему узлу. Ключ должен иметь право admin.
+func WithAPIKey(key string) ReplicaOption {
+	return func(r *Replica) {
+		r.apiKey = key
+	}
+}
+
+// WithReadTimeout задает время ожидания сообщения ведущего узла, после которого реплика переподключается.
+// Должно превышать интервал OpPing ведущего узла. По умолчанию DefaultReadTimeout.
+func WithReadTimeout(d time.Duration) ReplicaOption {
+	return func(r *Replica) {
+		if d > 0 {
+			r.readTimeout = d
+		}
+	}
+}
+
+// WithRetryInterval задает начальную задержку переподключения. По умолчанию DefaultRetryInterval.
+func WithRetryInterval(d time.Duration) ReplicaOption {
+	return func(r *Replica) {
+		if d > 0 {
+			r.retry = d
+		}
+	}
+}
+
+// NewReplica создает реплику ведущего узла primary, применяющую изменения к cache.
+// primary - базовый URL ведущего узла, например http://10.0.0.1:8080.
+func NewReplica(primary string, cache Target, log *slog.Logger, opts ...ReplicaOption) (*Replica, error) {
+	primary = strings.TrimSuffix(strings.TrimSpace(primary), "/")
+
+	u, err := url.Parse(primary)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replication primary: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" || u.Path != "" {
+		return nil, fmt.Errorf("invalid replication primary %q: expected http(s)://host:port", primary)
+	}
+
+	r := &Replica{
+		primary:     primary,
+		cache:       cache,
+		log:         log,
+		client:      http.DefaultClient,
+		readTimeout: DefaultReadTimeout,
+		retry:       DefaultRetryInterval,
+		syncedAt:    time.Now(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+}
+
+// Primary возвращает базовый URL ведущего узла.
+func (r *Replica) Primary() string {
+	return r.primary
+}
+
+// Run получает и применяет изменения ведущего узла, пока не завершится ctx. При обрыве потока
+// реплика переподключается с увеличивающейся задержкой и продолжает поток с последнего примененного смещения.
+func (r *Replica) Run(ctx context.Context) {
+	delay := r.retry
+	for {
+		received, err := r.stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if received {
+			delay = r.retry
+		}
+
+		r.log.Warn("replication stream interrupted", slog.String("primary", r.primary),
+			slog.String("retry_in", delay.String()), sl.Err(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxRetryInterval)
+	}
+}
+
+// stream открывает поток репликации и применяет его сообщения до обрыва соединения.
+// received равно true, если от ведущего узла было получено хотя бы одно сообщение.
+func (r *Replica) stream(ctx context.Context) (received bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Ведущий узел отправляет OpPing в потоке без изменений, поэтому долгое молчание означает потерю соединения.
+	watchdog := time.AfterFunc(r.readTimeout, cancel)
+	defer watchdog.Stop()
+
+	r.mu.Lock()
+	query := url.Values{"id": {r.id}, "offset": {strconv.FormatUint(r.offset, 10)}}
+	r.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.primary+StreamPath+"?"+query.Encode(), nil)
+	if err != nil {
+		return false, err
+	}
+	if r.apiKey != "" {
+		req.Header.Set(mw_auth.APIKeyHeader, r.apiKey)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	r.setConnected(true)
+	defer r.setConnected(false)
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg Message
+		if err := dec.Decode(&msg); err != nil {
+			return received, err
+		}
+		watchdog.Reset(r.readTimeout)
+		received = true
+
+		if err := r.apply(ctx, msg); err != nil {
+			return received, err
+		}
+	}
+}
+
+// setConnected отмечает подключение к ведущему узлу или его потерю.
+func (r *Replica) setConnected(connected bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.connected = connected
+}
+
+// apply применяет сообщение ведущего узла к кэшу и обновляет смещение реплики.
+func (r *Replica) apply(ctx context.Context, msg Message) error {
+	switch msg.Op {
+	case OpSync:
+		if err := r.cache.EvictAll(ctx); err != nil {
+			return err
+		}
+
+		r.mu.Lock()
+		r.id, r.offset, r.snapshot, r.pending, r.synced = msg.ID, 0, msg.Offset, msg.Entries, false
+		r.fullSyncs++
+		if r.pending == 0 {
+			r.offset, r.synced = r.snapshot, true
+		}
+		r.mu.Unlock()
+
+		r.log.Info("replication full sync", slog.String("id", msg.ID),
+			slog.Uint64("offset", msg.Offset), slog.Int("entries", msg.Entries))
+	case OpContinue:
+		r.mu.Lock()
+		id := r.id
+		r.mu.Unlock()
+
+		if msg.ID != id {
+			return fmt.Errorf("unexpected replication id %q", msg.ID)
+		}
+	case OpPut:
+		if msg.ExpiresAt == nil {
+			return errors.New("put message without expiration time")
+		}
+
+		value, err := msg.value()
+		if err != nil {
+			return err
+		}
+
+		// Время истечения передается как абсолютное, поэтому часы узлов должны быть синхронизированы.
+		if ttl := time.Until(*msg.ExpiresAt); ttl > 0 {
+			err := r.cache.Put(ctx, msg.Key, value, ttl)
+			if errors.Is(err, lru.ErrEntryTooLarge) {
+				r.log.Warn("replicated entry is too large for the replica cache", slog.String("key", msg.Key))
+			} else if err != nil {
+				return err
+			}
+		} else if _, err := r.cache.Evict(ctx, msg.Key); err != nil && !errors.Is(err, lru.ErrKeyNotFound) {
+			return err
+		}
+	case OpEvict:
+		if _, err := r.cache.Evict(ctx, msg.Key); err != nil && !errors.Is(err, lru.ErrKeyNotFound) {
+			return err
+		}
+	case OpFlush:
+		if err := r.cache.EvictAll(ctx); err != nil {
+			return err
+		}
+	}
+
+	r.advance(msg)
+
+	return nil
+}
+
+// advance обновляет смещение реплики после применения сообщения.
+func (r *Replica) advance(msg Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.lastContact = now
+
+	switch msg.Op {
+	case OpPut, OpEvict, OpFlush:
+		if r.pending > 0 {
+			if r.pending--; r.pending == 0 {
+				r.offset, r.synced = r.snapshot, true
+			}
+		} else {
+			r.offset = msg.Offset
+		}
+	}
+	r.head = max(r.head, msg.Offset)
+
+	if r.pending == 0 && r.offset >= r.head {
+		r.syncedAt = now
+	}
+}
+
+// Status возвращает состояние репликации. LagSeconds - время с момента, когда реплика последний раз
+// применила все известные ей изменения ведущего узла; равно 0, пока реплика подключена и не отстает.
+func (r *Replica) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := Status{
+		Role:      RoleReplica,
+		ID:        r.id,
+		Offset:    r.offset,
+		Primary:   r.primary,
+		Connected: r.connected,
+		Synced:    r.synced,
+		FullSyncs: r.fullSyncs,
+	}
+	if r.head > r.offset {
+		status.LagEntries = r.head - r.offset
+	}
+	if !r.connected || status.LagEntries > 0 || r.pending > 0 {
+		status.LagSeconds = time.Since(r.syncedAt).Seconds()
+	}
+	if !r.lastContact.IsZero() {
+		lastContact := r.lastContact
+		status.LastContact = &lastContact
+	}
+
+	return status
+}
@@ -0,0 +1,117 @@
+// Package replication реализует репликацию ведущий-реплика: ведущий узел передает изменения кэша
+// по долгоживущему HTTP-потоку NDJSON, а реплика загружает снимок кэша, применяет поток изменений
+// и при переподключении продолжает его с последнего примененного смещения.
+//
+// Смещение - порядковый номер изменения кэша ведущего узла (lru.Event.Seq). Ведущий узел хранит
+// последние изменения в журнале ограниченного размера: если реплика отстала больше, чем на размер журнала,
+// или ведущий узел перезапущен, реплика заново загружает снимок.
+package replication
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/instinctG/lru-cache/internal/memcache"
+	"time"
+)
+
+// ContentType - тип содержимого потока репликации: по одному сообщению Message в строке.
+const ContentType = "application/x-ndjson"
+
+// Виды сообщений потока репликации.
+const (
+	OpSync     = "sync"     // OpSync - начало снимка: за ним следуют Entries сообщений OpPut со смещением снимка.
+	OpContinue = "continue" // OpContinue - поток продолжается после смещения, переданного репликой.
+	OpPut      = "put"      // OpPut - элемент добавлен, обновлен или изменено его время жизни.
+	OpEvict    = "evict"    // OpEvict - элемент удален.
+	OpFlush    = "flush"    // OpFlush - кэш очищен.
+	OpPing     = "ping"     // OpPing - проверка соединения, Offset содержит последнее смещение ведущего узла.
+)
+
+// Кодировки значений, тип которых не восстанавливается из JSON.
+const (
+	EncodingBytes    = "bytes"    // EncodingBytes - значение []byte передается строкой base64.
+	EncodingMemcache = "memcache" // EncodingMemcache - значение memcache.Value с флагами передается объектом {"data", "flags"}.
+)
+
+// Роли узла в репликации.
+const (
+	RolePrimary    = "primary"    // RolePrimary - ведущий узел, принимает запись и передает изменения репликам.
+	RoleReplica    = "replica"    // RoleReplica - реплика, обслуживает чтение и применяет изменения ведущего узла.
+	RoleStandalone = "standalone" // RoleStandalone - репликация отключена.
+)
+
+// Message - сообщение потока репликации.
+type Message struct {
+	Op        string      `json:"op"`                   // Вид сообщения.
+	ID        string      `json:"id,omitempty"`         // Идентификатор журнала ведущего узла для OpSync, OpContinue и OpPing.
+	Offset    uint64      `json:"offset"`               // Смещение изменения или снимка.
+	Key       string      `json:"key,omitempty"`        // Ключ элемента для OpPut и OpEvict.
+	Value     interface{} `json:"value,omitempty"`      // Значение элемента для OpPut.
+	Encoding  string      `json:"encoding,omitempty"`   // Кодировка Value, если его тип не восстанавливается из JSON.
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"` // Время истечения элемента для OpPut.
+	Entries   int         `json:"entries,omitempty"`    // Количество элементов снимка для OpSync.
+	Time      time.Time   `json:"time"`                 // Время изменения или отправки сообщения на ведущем узле.
+}
+
+// Status описывает состояние репликации узла.
+type Status struct {
+	Role        string          `json:"role"`                   // Роль узла.
+	ID          string          `json:"id,omitempty"`           // Идентификатор журнала ведущего узла.
+	Offset      uint64          `json:"offset"`                 // Последнее смещение журнала (ведущий узел) или последнее примененное смещение (реплика).
+	Primary     string          `json:"primary,omitempty"`      // Адрес ведущего узла для реплики.
+	Connected   bool            `json:"connected"`              // Реплика подключена к ведущему узлу.
+	Synced      bool            `json:"synced,omitempty"`       // Реплика загрузила снимок ведущего узла.
+	LagEntries  uint64          `json:"lag_entries"`            // Количество известных реплике, но еще не примененных изменений.
+	LagSeconds  float64         `json:"lag_seconds"`            // Время, в течение которого реплика не получила все изменения ведущего узла.
+	LastContact *time.Time      `json:"last_contact,omitempty"` // Время последнего сообщения от ведущего узла.
+	FullSyncs   uint64          `json:"full_syncs"`             // Количество загрузок снимка репликой.
+	Replicas    []ReplicaStatus `json:"replicas,omitempty"`     // Подключенные реплики ведущего узла.
+}
+
+// ReplicaStatus описывает реплику, подключенную к ведущему узлу.
+type ReplicaStatus struct {
+	Address    string    `json:"address"`     // Адрес реплики.
+	Offset     uint64    `json:"offset"`      // Последнее отправленное реплике смещение.
+	LagEntries uint64    `json:"lag_entries"` // Количество изменений журнала, еще не отправленных реплике.
+	Since      time.Time `json:"since"`       // Время подключения реплики.
+}
+
+// setValue записывает в сообщение значение элемента. Значения передаются в JSON, как в HTTP API,
+// а []byte и memcache.Value отмечаются кодировкой, чтобы реплика сохранила их тип.
+func (m *Message) setValue(value interface{}) {
+	m.Value = value
+	switch v := value.(type) {
+	case []byte:
+		m.Value, m.Encoding = base64.StdEncoding.EncodeToString(v), EncodingBytes
+	case memcache.Value:
+		m.Encoding = EncodingMemcache
+	}
+}
+
+// value возвращает значение элемента из сообщения.
+func (m *Message) value() (interface{}, error) {
+	switch m.Encoding {
+	case "":
+		return m.Value, nil
+	case EncodingBytes:
+		s, ok := m.Value.(string)
+		if !ok {
+			return nil, errors.New("bytes value must be a base64 string")
+		}
+		return base64.StdEncoding.DecodeString(s)
+	case EncodingMemcache:
+		data, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		var v memcache.Value
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("invalid memcache value: %w", err)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unknown value encoding %q", m.Encoding)
+	}
+}
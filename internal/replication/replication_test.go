@@ -0,0 +1,242 @@
+package replication_test
+
+import (
+	"context"
+	"github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"github.com/instinctG/lru-cache/internal/memcache"
+	"github.com/instinctG/lru-cache/internal/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// pair - ведущий узел и реплика, связанные через HTTP.
+type pair struct {
+	primaryCache *lru.Cache
+	replicaCache *lru.Cache
+	primary      *replication.Primary
+	replica      *replication.Replica
+	srv          *httptest.Server
+}
+
+// newPair запускает журнал ведущего узла с кэшем primaryCache и создает реплику без запуска.
+func newPair(t *testing.T, primaryCache *lru.Cache, opts ...replication.PrimaryOption) *pair {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts = append([]replication.PrimaryOption{replication.WithHeartbeat(20 * time.Millisecond)}, opts...)
+	p := &pair{
+		primaryCache: primaryCache,
+		replicaCache: lru.NewLRUCache(100, time.Minute),
+		primary:      replication.NewPrimary(primaryCache, logger.NewDiscardLogger(), opts...),
+	}
+	go p.primary.Run(ctx)
+
+	p.srv = httptest.NewServer(p.primary)
+	t.Cleanup(p.srv.Close)
+	t.Cleanup(p.primary.Close)
+
+	var err error
+	p.replica, err = replication.NewReplica(p.srv.URL, p.replicaCache, logger.NewDiscardLogger(),
+		replication.WithReadTimeout(time.Second), replication.WithRetryInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	return p
+}
+
+// startReplica запускает реплику до отмены возвращенной функцией или завершения теста.
+func (p *pair) startReplica(t *testing.T) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.replica.Run(ctx)
+		close(done)
+	}()
+
+	stop = func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+
+	return stop
+}
+
+// waitSynced ждет, пока реплика применит все изменения ведущего узла.
+func (p *pair) waitSynced(t *testing.T) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		status := p.replica.Status()
+		return status.Connected && status.Offset == p.primary.Status().Offset && status.LagEntries == 0
+	}, 2*time.Second, 5*time.Millisecond)
+}
+
+// entries возвращает элементы кэша по ключам.
+func entries(t *testing.T, cache *lru.Cache) map[string]interface{} {
+	t.Helper()
+
+	keys, values, err := cache.GetAll(context.Background())
+	if err != nil {
+		require.ErrorIs(t, err, lru.ErrCacheIsEmpty)
+	}
+
+	result := make(map[string]interface{}, len(keys))
+	for i, key := range keys {
+		result[key] = values[i]
+	}
+	return result
+}
+
+func TestReplication_FullSyncAndStream(t *testing.T) {
+	ctx := context.Background()
+	p := newPair(t, lru.NewLRUCache(100, time.Minute))
+
+	// Элементы, записанные до подключения реплики, передаются снимком.
+	require.NoError(t, p.primaryCache.Put(ctx, "a", "1", 0))
+	require.NoError(t, p.primaryCache.Put(ctx, "b", 2.0, 0))
+	require.NoError(t, p.primaryCache.Put(ctx, "bytes", []byte{0, 1, 2}, 0))
+
+	// Запись в кэш реплики до снимка удаляется.
+	require.NoError(t, p.replicaCache.Put(ctx, "stale", "x", 0))
+
+	p.startReplica(t)
+	p.waitSynced(t)
+	assert.Equal(t, entries(t, p.primaryCache), entries(t, p.replicaCache))
+	assert.Equal(t, uint64(1), p.replica.Status().FullSyncs)
+
+	// Изменения после снимка передаются потоком, включая время жизни.
+	require.NoError(t, p.primaryCache.Put(ctx, "c", "3", 0))
+	_, err := p.primaryCache.Evict(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, p.primaryCache.Expire(ctx, "b", time.Hour))
+
+	p.waitSynced(t)
+	assert.Equal(t, map[string]interface{}{"b": 2.0, "bytes": []byte{0, 1, 2}, "c": "3"}, entries(t, p.replicaCache))
+
+	_, expiresAt, err := p.replicaCache.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Second)
+
+	require.NoError(t, p.primaryCache.EvictAll(ctx))
+	p.waitSynced(t)
+	assert.Empty(t, entries(t, p.replicaCache))
+
+	status := p.primary.Status()
+	assert.Equal(t, replication.RolePrimary, status.Role)
+	require.Len(t, status.Replicas, 1)
+	assert.Equal(t, status.Offset, status.Replicas[0].Offset)
+
+	status = p.replica.Status()
+	assert.Equal(t, replication.RoleReplica, status.Role)
+	assert.Equal(t, p.primary.ID(), status.ID)
+	assert.Equal(t, p.srv.URL, status.Primary)
+	assert.True(t, status.Synced)
+	assert.Zero(t, status.LagSeconds)
+	assert.NotNil(t, status.LastContact)
+	assert.Equal(t, uint64(1), status.FullSyncs)
+}
+
+func TestReplication_MemcacheValue(t *testing.T) {
+	ctx := context.Background()
+	p := newPair(t, lru.NewLRUCache(100, time.Minute))
+
+	// Значения memcached с флагами сохраняют тип и в снимке, и в потоке изменений.
+	snapshot := memcache.Value{Data: "snapshot", Flags: 1}
+	require.NoError(t, p.primaryCache.Put(ctx, "snapshot", snapshot, 0))
+
+	p.startReplica(t)
+	p.waitSynced(t)
+
+	stream := memcache.Value{Data: `{"data":"x"}`, Flags: 42}
+	require.NoError(t, p.primaryCache.Put(ctx, "stream", stream, 0))
+	p.waitSynced(t)
+
+	assert.Equal(t, map[string]interface{}{"snapshot": snapshot, "stream": stream}, entries(t, p.replicaCache))
+}
+
+func TestReplication_ResumeFromOffset(t *testing.T) {
+	ctx := context.Background()
+	p := newPair(t, lru.NewLRUCache(100, time.Minute))
+
+	require.NoError(t, p.primaryCache.Put(ctx, "a", "1", 0))
+	stop := p.startReplica(t)
+	p.waitSynced(t)
+
+	// Пока реплика отключена, она отстает, а изменения накапливаются в журнале.
+	stop()
+	require.NoError(t, p.primaryCache.Put(ctx, "b", "2", 0))
+	require.NoError(t, p.primaryCache.Put(ctx, "c", "3", 0))
+	assert.False(t, p.replica.Status().Connected)
+	assert.Positive(t, p.replica.Status().LagSeconds)
+
+	// После переподключения поток продолжается без снимка.
+	p.startReplica(t)
+	p.waitSynced(t)
+	assert.Equal(t, entries(t, p.primaryCache), entries(t, p.replicaCache))
+	assert.Equal(t, uint64(1), p.replica.Status().FullSyncs)
+
+	// Обрыв соединения ведущим узлом тоже не требует снимка.
+	p.srv.CloseClientConnections()
+	require.NoError(t, p.primaryCache.Put(ctx, "d", "4", 0))
+	p.waitSynced(t)
+	assert.Equal(t, entries(t, p.primaryCache), entries(t, p.replicaCache))
+	assert.Equal(t, uint64(1), p.replica.Status().FullSyncs)
+}
+
+func TestReplication_ResyncAfterBacklogOverflow(t *testing.T) {
+	ctx := context.Background()
+	p := newPair(t, lru.NewLRUCache(100, time.Minute), replication.WithBacklog(2))
+
+	require.NoError(t, p.primaryCache.Put(ctx, "a", "1", 0))
+	stop := p.startReplica(t)
+	p.waitSynced(t)
+	stop()
+
+	// Изменений больше, чем помещается в журнал: реплика загружает снимок заново.
+	for _, key := range []string{"b", "c", "d", "e"} {
+		require.NoError(t, p.primaryCache.Put(ctx, key, key, 0))
+	}
+	require.Eventually(t, func() bool { return p.primary.Status().Offset == 5 }, time.Second, 5*time.Millisecond)
+
+	p.startReplica(t)
+	p.waitSynced(t)
+	assert.Equal(t, entries(t, p.primaryCache), entries(t, p.replicaCache))
+	assert.Equal(t, uint64(2), p.replica.Status().FullSyncs)
+}
+
+func TestNewReplica_InvalidPrimary(t *testing.T) {
+	for _, primary := range []string{"", "10.0.0.1:8080", "ftp://10.0.0.1", "http://10.0.0.1:8080/api"} {
+		_, err := replication.NewReplica(primary, lru.NewLRUCache(1, time.Minute), logger.NewDiscardLogger())
+		assert.Error(t, err, primary)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+	cache := lru.NewLRUCache(10, time.Minute)
+	require.NoError(t, cache.Put(ctx, "a", "1", 0))
+
+	ro := replication.ReadOnly{Cache: cache}
+
+	value, _, err := ro.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "1", value)
+
+	assert.ErrorIs(t, ro.Put(ctx, "b", "2", 0), replication.ErrReadOnly)
+	assert.ErrorIs(t, ro.Add(ctx, "b", "2", 0), replication.ErrReadOnly)
+	assert.ErrorIs(t, ro.Replace(ctx, "a", "2", 0), replication.ErrReadOnly)
+	assert.ErrorIs(t, ro.Expire(ctx, "a", time.Hour), replication.ErrReadOnly)
+	assert.ErrorIs(t, ro.EvictAll(ctx), replication.ErrReadOnly)
+	_, err = ro.Evict(ctx, "a")
+	assert.ErrorIs(t, err, replication.ErrReadOnly)
+	_, err = ro.Update(ctx, "a", func(old interface{}, exists bool) (interface{}, bool) { return "2", true })
+	assert.ErrorIs(t, err, replication.ErrReadOnly)
+
+	assert.Equal(t, map[string]interface{}{"a": "1"}, entries(t, cache))
+}
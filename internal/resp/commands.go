@@ -0,0 +1,698 @@
+package resp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"github.com/instinctG/lru-cache/internal/replication"
+	"log/slog"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version - версия сервера, сообщаемая клиентам в HELLO и INFO.
+const version = "7.2.0"
+
+// Тексты ошибок, совпадающие с ответами Redis, на которые рассчитывают клиентские библиотеки.
+const (
+	errSyntax     = "ERR syntax error"
+	errNotInteger = "ERR value is not an integer or out of range"
+	errOverflow   = "ERR increment or decrement would overflow"
+	errInvalidDB  = "ERR DB index is out of range"
+	errNoProto    = "NOPROTO unsupported protocol version"
+	errNoAuth     = "NOAUTH Authentication required."
+	errWrongPass  = "WRONGPASS invalid username-password pair or user is disabled."
+	errNoPassword = "ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?"
+)
+
+// client хранит состояние соединения: версию протокола, субъекта и буферы чтения и записи.
+type client struct {
+	id        int64
+	server    *Server
+	reader    reader
+	writer    writer
+	quit      bool
+	principal *mw_auth.Principal // Субъект, аутентифицированный командой AUTH, nil - клиент не аутентифицирован.
+}
+
+// command описывает обработчик команды, допустимое количество аргументов, включая имя команды,
+// и право, необходимое для ее выполнения при включенной аутентификации. Отрицательное arity означает "не меньше -arity".
+// Пустое право означает, что команда доступна любому аутентифицированному клиенту.
+type command struct {
+	arity int
+	fn    func(c *client, args []string)
+	scope string
+}
+
+// public - команды, доступные до аутентификации.
+var public = map[string]bool{"auth": true, "hello": true, "quit": true}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"ping":     {-1, (*client).ping, ""},
+		"echo":     {2, (*client).echo, ""},
+		"hello":    {-1, (*client).hello, ""},
+		"auth":     {-2, (*client).authCmd, ""},
+		"select":   {2, (*client).selectDB, ""},
+		"quit":     {-1, (*client).quitCmd, ""},
+		"command":  {-1, (*client).commandCmd, ""},
+		"client":   {-2, (*client).clientCmd, ""},
+		"get":      {2, (*client).get, mw_auth.ScopeRead},
+		"set":      {-3, (*client).set, mw_auth.ScopeWrite},
+		"del":      {-2, (*client).del, mw_auth.ScopeWrite},
+		"exists":   {-2, (*client).exists, mw_auth.ScopeRead},
+		"ttl":      {2, (*client).ttl, mw_auth.ScopeRead},
+		"pttl":     {2, (*client).pttl, mw_auth.ScopeRead},
+		"expire":   {3, (*client).expire, mw_auth.ScopeWrite},
+		"pexpire":  {3, (*client).pexpire, mw_auth.ScopeWrite},
+		"keys":     {2, (*client).keys, mw_auth.ScopeRead},
+		"dbsize":   {1, (*client).dbsize, mw_auth.ScopeRead},
+		"flushall": {-1, (*client).flushall, mw_auth.ScopeAdmin},
+		"flushdb":  {-1, (*client).flushall, mw_auth.ScopeAdmin},
+		"incr":     {2, (*client).incr, mw_auth.ScopeWrite},
+		"decr":     {2, (*client).decr, mw_auth.ScopeWrite},
+		"incrby":   {3, (*client).incrby, mw_auth.ScopeWrite},
+		"decrby":   {3, (*client).decrby, mw_auth.ScopeWrite},
+		"mget":     {-2, (*client).mget, mw_auth.ScopeRead},
+		"mset":     {-3, (*client).mset, mw_auth.ScopeWrite},
+		"info":     {-1, (*client).info, mw_auth.ScopeRead},
+	}
+}
+
+// execute находит обработчик команды, проверяет количество аргументов и выполняет ее.
+func (c *client) execute(args []string) {
+	name := strings.ToLower(args[0])
+
+	cmd, ok := commands[name]
+	if !ok {
+		// Как и Redis, повторяем не больше maxErrorArgsLength символов имени команды и ее аргументов.
+		var b strings.Builder
+		for _, arg := range args[1:] {
+			if b.Len() >= maxErrorArgsLength {
+				break
+			}
+			fmt.Fprintf(&b, "'%.*s' ", maxErrorArgsLength-b.Len(), arg)
+		}
+		c.writer.error(fmt.Sprintf("ERR unknown command '%.*s', with args beginning with: %s", maxErrorArgsLength, args[0], b.String()))
+		return
+	}
+
+	if c.server.auth.Enabled() && !public[name] {
+		if c.principal == nil {
+			c.writer.error(errNoAuth)
+			return
+		}
+		if cmd.scope != "" && !c.principal.HasScope(cmd.scope) {
+			c.writer.error(fmt.Sprintf("NOPERM User %s has no permissions to run the '%s' command", c.principal.Name, name))
+			return
+		}
+	}
+
+	if (cmd.arity > 0 && len(args) != cmd.arity) || (cmd.arity < 0 && len(args) < -cmd.arity) {
+		c.writer.error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", name))
+		return
+	}
+
+	cmd.fn(c, args)
+}
+
+// ctx возвращает контекст для операций с кэшем.
+func (c *client) ctx() context.Context {
+	return context.Background()
+}
+
+func (c *client) ping(args []string) {
+	switch len(args) {
+	case 1:
+		c.writer.simple("PONG")
+	case 2:
+		c.writer.bulk(args[1])
+	default:
+		c.writer.error("ERR wrong number of arguments for 'ping' command")
+	}
+}
+
+func (c *client) echo(args []string) {
+	c.writer.bulk(args[1])
+}
+
+// authCmd аутентифицирует клиента командой AUTH [username] password, где password - API-ключ.
+func (c *client) authCmd(args []string) {
+	if len(args) > 3 {
+		c.writer.error(errSyntax)
+		return
+	}
+	if !c.server.auth.Enabled() {
+		c.writer.error(errNoPassword)
+		return
+	}
+
+	username := "default"
+	if len(args) == 3 {
+		username = args[1]
+	}
+	if !c.login(username, args[len(args)-1]) {
+		c.writer.error(errWrongPass)
+		return
+	}
+	c.writer.simple("OK")
+}
+
+// login проверяет API-ключ password. Имя пользователя, отличное от "default", должно совпадать с именем ключа.
+// При неудачной попытке клиент остается с прежним субъектом.
+func (c *client) login(username, password string) bool {
+	r := &http.Request{Header: make(http.Header)}
+	r.Header.Set(mw_auth.APIKeyHeader, password)
+
+	principal, err := c.server.auth.Authenticate(r)
+	if err == nil && username != "default" && username != principal.Name {
+		err = mw_auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		c.server.Log.Debug("authentication failed", slog.Int64("client_id", c.id), sl.Err(err))
+		return false
+	}
+
+	c.principal = principal
+	return true
+}
+
+// hello переключает версию протокола и возвращает сведения о сервере.
+// Параметр AUTH username password аутентифицирует клиента так же, как команда AUTH.
+// При включенной аутентификации HELLO без AUTH доступен только аутентифицированному клиенту.
+func (c *client) hello(args []string) {
+	proto := c.writer.proto
+	if len(args) > 1 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil {
+			c.writer.error("ERR Protocol version is not an integer or out of range")
+			return
+		}
+		if v != 2 && v != 3 {
+			c.writer.error(errNoProto)
+			return
+		}
+		proto = v
+
+		for i := 2; i < len(args); i++ {
+			switch strings.ToLower(args[i]) {
+			case "setname":
+				if i+1 >= len(args) {
+					c.writer.error(errSyntax)
+					return
+				}
+				i++
+			case "auth":
+				if i+2 >= len(args) {
+					c.writer.error(errSyntax)
+					return
+				}
+				if !c.server.auth.Enabled() {
+					c.writer.error(errNoPassword)
+					return
+				}
+				if !c.login(args[i+1], args[i+2]) {
+					c.writer.error(errWrongPass)
+					return
+				}
+				i += 2
+			default:
+				c.writer.error(errSyntax)
+				return
+			}
+		}
+	}
+	if c.server.auth.Enabled() && c.principal == nil {
+		c.writer.error("NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
+		return
+	}
+	c.writer.proto = proto
+
+	c.writer.mapHeader(7)
+	c.writer.bulk("server")
+	c.writer.bulk("lru-cache")
+	c.writer.bulk("version")
+	c.writer.bulk(version)
+	c.writer.bulk("proto")
+	c.writer.integer(int64(proto))
+	c.writer.bulk("id")
+	c.writer.integer(c.id)
+	c.writer.bulk("mode")
+	c.writer.bulk("standalone")
+	c.writer.bulk("role")
+	c.writer.bulk("master")
+	c.writer.bulk("modules")
+	c.writer.array(0)
+}
+
+// selectDB поддерживает только базу 0: кэш не разделен на базы данных.
+func (c *client) selectDB(args []string) {
+	if args[1] != "0" {
+		c.writer.error(errInvalidDB)
+		return
+	}
+	c.writer.simple("OK")
+}
+
+func (c *client) quitCmd([]string) {
+	c.writer.simple("OK")
+	c.quit = true
+}
+
+// commandCmd возвращает пустой список команд: клиенты вызывают COMMAND только для справки.
+func (c *client) commandCmd([]string) {
+	c.writer.array(0)
+}
+
+// clientCmd принимает CLIENT SETNAME и CLIENT SETINFO, которые клиенты отправляют при подключении.
+func (c *client) clientCmd(args []string) {
+	switch strings.ToLower(args[1]) {
+	case "setname", "setinfo":
+		c.writer.simple("OK")
+	case "id":
+		c.writer.integer(c.id)
+	default:
+		c.writer.error(fmt.Sprintf("ERR unknown subcommand '%.*s'. Try CLIENT HELP.", maxErrorArgsLength, args[1]))
+	}
+}
+
+func (c *client) get(args []string) {
+	value, _, err := c.server.LRU.Get(c.ctx(), args[1])
+	if err != nil {
+		c.writer.null()
+		return
+	}
+	c.writer.bulk(formatValue(value))
+}
+
+// set поддерживает параметры NX, XX, EX и PX. При невыполненном условии NX/XX возвращается null.
+func (c *client) set(args []string) {
+	key, value := args[1], args[2]
+
+	var nx, xx bool
+	var ttl time.Duration
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToLower(args[i]); opt {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "ex", "px":
+			if ttl != 0 || i+1 >= len(args) {
+				c.writer.error(errSyntax)
+				return
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				c.writer.error(errNotInteger)
+				return
+			}
+			unit := time.Millisecond
+			if opt == "ex" {
+				unit = time.Second
+			}
+			if n <= 0 || n > math.MaxInt64/int64(unit) {
+				c.writer.error("ERR invalid expire time in 'set' command")
+				return
+			}
+			ttl = time.Duration(n) * unit
+		default:
+			c.writer.error(errSyntax)
+			return
+		}
+	}
+	if nx && xx {
+		c.writer.error(errSyntax)
+		return
+	}
+
+	var err error
+	switch {
+	case nx:
+		err = c.server.LRU.Add(c.ctx(), key, value, ttl)
+	case xx:
+		err = c.server.LRU.Replace(c.ctx(), key, value, ttl)
+	default:
+		err = c.server.LRU.Put(c.ctx(), key, value, ttl)
+	}
+
+	switch {
+	case errors.Is(err, lru.ErrKeyExists), errors.Is(err, lru.ErrKeyNotFound):
+		c.writer.null()
+	case err != nil:
+		c.writer.error(errorReply(err))
+	default:
+		c.writer.simple("OK")
+	}
+}
+
+func (c *client) del(args []string) {
+	var n int64
+	for _, key := range args[1:] {
+		_, err := c.server.LRU.Evict(c.ctx(), key)
+		switch {
+		case err == nil:
+			n++
+		case !errors.Is(err, lru.ErrKeyNotFound):
+			c.writer.error(errorReply(err))
+			return
+		}
+	}
+	c.writer.integer(n)
+}
+
+// exists возвращает количество существующих ключей. Повторяющиеся ключи учитываются каждый раз.
+func (c *client) exists(args []string) {
+	var n int64
+	for _, key := range args[1:] {
+		if _, _, err := c.server.LRU.Get(c.ctx(), key); err == nil {
+			n++
+		}
+	}
+	c.writer.integer(n)
+}
+
+func (c *client) ttl(args []string) {
+	c.replyTTL(args[1], time.Second)
+}
+
+func (c *client) pttl(args []string) {
+	c.replyTTL(args[1], time.Millisecond)
+}
+
+// replyTTL возвращает оставшееся время жизни ключа в единицах unit или -2, если ключа нет.
+// Время округляется вверх, чтобы существующий ключ не получал TTL 0.
+func (c *client) replyTTL(key string, unit time.Duration) {
+	_, expiresAt, err := c.server.LRU.Get(c.ctx(), key)
+	if err != nil {
+		c.writer.integer(-2)
+		return
+	}
+
+	remaining := time.Until(expiresAt)
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.writer.integer(int64((remaining + unit - 1) / unit))
+}
+
+func (c *client) expire(args []string) {
+	c.setExpire("expire", args[1], args[2], time.Second)
+}
+
+func (c *client) pexpire(args []string) {
+	c.setExpire("pexpire", args[1], args[2], time.Millisecond)
+}
+
+// setExpire задает время жизни ключа. Неположительное значение удаляет ключ, как в Redis.
+func (c *client) setExpire(name, key, value string, unit time.Duration) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		c.writer.error(errNotInteger)
+		return
+	}
+	if n > math.MaxInt64/int64(unit) {
+		c.writer.error(fmt.Sprintf("ERR invalid expire time in '%s' command", name))
+		return
+	}
+
+	var ttl time.Duration
+	if n > 0 {
+		ttl = time.Duration(n) * unit
+	}
+
+	err = c.server.LRU.Expire(c.ctx(), key, ttl)
+	switch {
+	case errors.Is(err, lru.ErrKeyNotFound):
+		c.writer.integer(0)
+	case err != nil:
+		c.writer.error(errorReply(err))
+	default:
+		c.writer.integer(1)
+	}
+}
+
+func (c *client) keys(args []string) {
+	all, _, err := c.server.LRU.GetAll(c.ctx())
+	if err != nil && !errors.Is(err, lru.ErrCacheIsEmpty) {
+		c.writer.error(errorReply(err))
+		return
+	}
+
+	matched := make([]string, 0, len(all))
+	for _, key := range all {
+		if matchPattern(args[1], key) {
+			matched = append(matched, key)
+		}
+	}
+
+	c.writer.array(len(matched))
+	for _, key := range matched {
+		c.writer.bulk(key)
+	}
+}
+
+func (c *client) dbsize([]string) {
+	all, _, _ := c.server.LRU.GetAll(c.ctx())
+	c.writer.integer(int64(len(all)))
+}
+
+// flushall удаляет все элементы. Параметры SYNC и ASYNC принимаются, очистка всегда синхронная.
+func (c *client) flushall(args []string) {
+	if len(args) > 2 {
+		c.writer.error(errSyntax)
+		return
+	}
+	if len(args) == 2 {
+		if mode := strings.ToLower(args[1]); mode != "sync" && mode != "async" {
+			c.writer.error(errSyntax)
+			return
+		}
+	}
+
+	if err := c.server.LRU.EvictAll(c.ctx()); err != nil {
+		c.writer.error(errorReply(err))
+		return
+	}
+	c.writer.simple("OK")
+}
+
+func (c *client) incr(args []string) {
+	c.incrBy(args[1], 1)
+}
+
+func (c *client) decr(args []string) {
+	c.incrBy(args[1], -1)
+}
+
+func (c *client) incrby(args []string) {
+	delta, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		c.writer.error(errNotInteger)
+		return
+	}
+	c.incrBy(args[1], delta)
+}
+
+func (c *client) decrby(args []string) {
+	delta, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil || delta == math.MinInt64 {
+		c.writer.error(errNotInteger)
+		return
+	}
+	c.incrBy(args[1], -delta)
+}
+
+// incrBy атомарно увеличивает целое значение ключа на delta, сохраняя время его истечения.
+// Отсутствующий ключ считается равным 0. Числа, записанные через HTTP API как JSON, остаются числами.
+func (c *client) incrBy(key string, delta int64) {
+	var result int64
+	var replyErr string
+
+	_, err := c.server.LRU.Update(c.ctx(), key, func(old interface{}, exists bool) (interface{}, bool) {
+		var current int64
+		if exists {
+			n, ok := parseInteger(old)
+			if !ok {
+				replyErr = errNotInteger
+				return old, true
+			}
+			current = n
+		}
+
+		if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+			replyErr = errOverflow
+			return old, exists
+		}
+		result = current + delta
+
+		if _, isFloat := old.(float64); isFloat {
+			return float64(result), true
+		}
+		return strconv.FormatInt(result, 10), true
+	})
+
+	switch {
+	case replyErr != "":
+		c.writer.error(replyErr)
+	case err != nil:
+		c.writer.error(errorReply(err))
+	default:
+		c.writer.integer(result)
+	}
+}
+
+func (c *client) mget(args []string) {
+	c.writer.array(len(args) - 1)
+	for _, key := range args[1:] {
+		value, _, err := c.server.LRU.Get(c.ctx(), key)
+		if err != nil {
+			c.writer.null()
+			continue
+		}
+		c.writer.bulk(formatValue(value))
+	}
+}
+
+func (c *client) mset(args []string) {
+	if len(args)%2 != 1 {
+		c.writer.error("ERR wrong number of arguments for 'mset' command")
+		return
+	}
+
+	for i := 1; i < len(args); i += 2 {
+		if err := c.server.LRU.Put(c.ctx(), args[i], args[i+1], 0); err != nil {
+			c.writer.error(errorReply(err))
+			return
+		}
+	}
+	c.writer.simple("OK")
+}
+
+// info возвращает разделы server, clients, stats и keyspace в формате INFO Redis.
+func (c *client) info(args []string) {
+	sections := map[string]bool{}
+	for _, arg := range args[1:] {
+		sections[strings.ToLower(arg)] = true
+	}
+	all := len(sections) == 0 || sections["all"] || sections["default"] || sections["everything"]
+
+	stats := c.server.LRU.Stats()
+
+	var b strings.Builder
+	if all || sections["server"] {
+		b.WriteString("# Server\r\n")
+		fmt.Fprintf(&b, "redis_version:%s\r\n", version)
+		b.WriteString("redis_mode:standalone\r\n")
+		fmt.Fprintf(&b, "go_version:%s\r\n", runtime.Version())
+		fmt.Fprintf(&b, "tcp_port:%s\r\n", portOf(c.server.Addr))
+		fmt.Fprintf(&b, "uptime_in_seconds:%d\r\n", int64(time.Since(c.server.started).Seconds()))
+		b.WriteString("\r\n")
+	}
+	if all || sections["clients"] {
+		c.server.mu.Lock()
+		connected := len(c.server.conns)
+		c.server.mu.Unlock()
+
+		b.WriteString("# Clients\r\n")
+		fmt.Fprintf(&b, "connected_clients:%d\r\n", connected)
+		b.WriteString("\r\n")
+	}
+	if all || sections["stats"] {
+		var evicted uint64
+		byReason := make(map[string]uint64, len(stats.Evictions))
+		reasons := make([]string, 0, len(stats.Evictions))
+		for reason, n := range stats.Evictions {
+			evicted += n
+			byReason[reason.String()] = n
+			reasons = append(reasons, reason.String())
+		}
+		sort.Strings(reasons)
+
+		b.WriteString("# Stats\r\n")
+		fmt.Fprintf(&b, "total_connections_received:%d\r\n", c.server.connections.Load())
+		fmt.Fprintf(&b, "total_commands_processed:%d\r\n", c.server.commands.Load())
+		fmt.Fprintf(&b, "keyspace_hits:%d\r\n", stats.Hits)
+		fmt.Fprintf(&b, "keyspace_misses:%d\r\n", stats.Misses)
+		fmt.Fprintf(&b, "evicted_keys:%d\r\n", evicted)
+		for _, name := range reasons {
+			fmt.Fprintf(&b, "evicted_keys_%s:%d\r\n", name, byReason[name])
+		}
+		b.WriteString("\r\n")
+	}
+	if all || sections["keyspace"] {
+		b.WriteString("# Keyspace\r\n")
+		if stats.Size > 0 {
+			fmt.Fprintf(&b, "db0:keys=%d,expires=%d,capacity=%d\r\n", stats.Size, stats.Size, stats.Capacity)
+		}
+		b.WriteString("\r\n")
+	}
+
+	c.writer.bulk(strings.TrimSuffix(b.String(), "\r\n"))
+}
+
+// errorReply формирует текст ошибки RESP для ошибки кэша.
+// Запись в кэш реплики возвращает ошибку READONLY, как у реплик Redis.
+func errorReply(err error) string {
+	if errors.Is(err, replication.ErrReadOnly) {
+		return "READONLY " + err.Error()
+	}
+	return "ERR " + err.Error()
+}
+
+// formatValue представляет значение кэша строкой. Значения, записанные через HTTP API
+// не строками, возвращаются в виде JSON.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+// parseInteger возвращает целое значение элемента для INCR и DECR.
+func parseInteger(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// portOf возвращает порт из адреса host:port.
+func portOf(address string) string {
+	if i := strings.LastIndexByte(address, ':'); i >= 0 {
+		return address[i+1:]
+	}
+	return address
+}
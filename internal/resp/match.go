@@ -0,0 +1,91 @@
+package resp
+
+// matchPattern сопоставляет строку с glob-шаблоном Redis: '*' - любая последовательность,
+// '?' - любой символ, '[abc]', '[^a]' и '[a-z]' - классы символов, '\' экранирует следующий символ.
+func matchPattern(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchPattern(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			matched, rest := matchClass(pattern[1:], s[0])
+			if !matched {
+				return false
+			}
+			s = s[1:]
+			pattern = rest
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass проверяет символ ch по классу символов, начинающемуся после '['.
+// Возвращает результат и остаток шаблона после закрывающей ']'.
+func matchClass(pattern string, ch byte) (bool, string) {
+	negate := false
+	if len(pattern) > 0 && pattern[0] == '^' {
+		negate = true
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			if pattern[1] == ch {
+				matched = true
+			}
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if ch >= lo && ch <= hi {
+				matched = true
+			}
+			pattern = pattern[3:]
+		default:
+			if pattern[0] == ch {
+				matched = true
+			}
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+
+	return matched != negate, pattern
+}
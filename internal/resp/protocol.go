@@ -0,0 +1,175 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Ограничения на размер команд, защищающие сервер от исчерпания памяти.
+const (
+	DefaultMaxBulkLength = 64 << 20 // DefaultMaxBulkLength - максимальная длина аргумента команды в байтах по умолчанию.
+	maxArrayLength       = 1 << 20  // maxArrayLength - максимальное количество аргументов команды.
+	maxInlineLength      = 64 << 10 // maxInlineLength - максимальная длина inline-команды.
+)
+
+// maxErrorArgsLength - максимальная длина аргументов клиента, повторяемых в тексте ошибки, как в Redis.
+const maxErrorArgsLength = 128
+
+// errorSanitizer заменяет переводы строк в тексте ошибки: ошибка передается простой строкой RESP,
+// и аргумент клиента с "\r\n" иначе добавил бы в поток ответов лишние ответы.
+var errorSanitizer = strings.NewReplacer("\r", " ", "\n", " ")
+
+// errProtocol возвращается при нарушении формата RESP. После такой ошибки соединение закрывается.
+var errProtocol = errors.New("Protocol error")
+
+// reader читает команды клиента: массивы bulk-строк RESP и inline-команды, разделенные пробелами.
+type reader struct {
+	r             *bufio.Reader
+	maxBulkLength int
+}
+
+// readCommand читает следующую команду. Для пустой inline-строки возвращается пустой список аргументов.
+func (r *reader) readCommand() ([]string, error) {
+	prefix, err := r.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix != '*' {
+		if err = r.r.UnreadByte(); err != nil {
+			return nil, err
+		}
+		line, err := r.readLine(maxInlineLength)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(line), nil
+	}
+
+	n, err := r.readLength(maxArrayLength)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		prefix, err = r.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if prefix != '$' {
+			return nil, fmt.Errorf("%w: expected '$', got '%c'", errProtocol, prefix)
+		}
+
+		length, err := r.readLength(r.maxBulkLength)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, length+2)
+		if _, err = io.ReadFull(r.r, buf); err != nil {
+			return nil, err
+		}
+		if buf[length] != '\r' || buf[length+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk string is not terminated by CRLF", errProtocol)
+		}
+
+		args = append(args, string(buf[:length]))
+	}
+
+	return args, nil
+}
+
+// readLength читает неотрицательную длину массива или bulk-строки, не превышающую limit.
+func (r *reader) readLength(limit int) (int, error) {
+	line, err := r.readLine(32)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.Atoi(line)
+	if err != nil || n < 0 || n > limit {
+		return 0, fmt.Errorf("%w: invalid length %q", errProtocol, line)
+	}
+	return n, nil
+}
+
+// readLine читает строку до CRLF (или LF для inline-команд) длиной не больше limit.
+func (r *reader) readLine(limit int) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > limit {
+			return "", fmt.Errorf("%w: too big request", errProtocol)
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+// writer формирует ответы в формате RESP2 или RESP3 в зависимости от версии протокола соединения.
+type writer struct {
+	w     *bufio.Writer
+	proto int
+}
+
+func (w *writer) simple(s string) {
+	w.w.WriteByte('+')
+	w.w.WriteString(s)
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) error(s string) {
+	w.w.WriteByte('-')
+	w.w.WriteString(errorSanitizer.Replace(s))
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) integer(n int64) {
+	w.w.WriteByte(':')
+	w.w.WriteString(strconv.FormatInt(n, 10))
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) bulk(s string) {
+	w.w.WriteByte('$')
+	w.w.WriteString(strconv.Itoa(len(s)))
+	w.w.WriteString("\r\n")
+	w.w.WriteString(s)
+	w.w.WriteString("\r\n")
+}
+
+// null записывает отсутствующее значение: "$-1" в RESP2 и "_" в RESP3.
+func (w *writer) null() {
+	if w.proto >= 3 {
+		w.w.WriteString("_\r\n")
+		return
+	}
+	w.w.WriteString("$-1\r\n")
+}
+
+func (w *writer) array(n int) {
+	w.w.WriteByte('*')
+	w.w.WriteString(strconv.Itoa(n))
+	w.w.WriteString("\r\n")
+}
+
+// mapHeader начинает словарь из n пар: "%" в RESP3 и массив из 2n элементов в RESP2.
+func (w *writer) mapHeader(n int) {
+	if w.proto >= 3 {
+		w.w.WriteByte('%')
+		w.w.WriteString(strconv.Itoa(n))
+		w.w.WriteString("\r\n")
+		return
+	}
+	w.array(2 * n)
+}
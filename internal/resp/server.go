@@ -0,0 +1,256 @@
+// Package resp реализует TCP-сервер, совместимый с протоколом Redis (RESP2/RESP3),
+// который обслуживает команды клиентов Redis поверх LRU-кэша.
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	sl "github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrServerClosed возвращается методами Serve и ListenAndServe после вызова Shutdown.
+var ErrServerClosed = errors.New("resp: server closed")
+
+// Cache описывает операции кэша, используемые сервером RESP.
+// Реализуется *lru.Cache, тот же экземпляр обслуживает HTTP-запросы.
+type Cache interface {
+	// Put добавляет или обновляет элемент в кэше.
+	Put(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// Get возвращает значение и время истечения элемента по ключу.
+	Get(ctx context.Context, key string) (value interface{}, expiresAt time.Time, err error)
+	// GetAll возвращает все ключи и значения кэша.
+	GetAll(ctx context.Context) (keys []string, values []interface{}, err error)
+	// Evict удаляет элемент из кэша по ключу.
+	Evict(ctx context.Context, key string) (value interface{}, err error)
+	// EvictAll удаляет все элементы из кэша.
+	EvictAll(ctx context.Context) error
+	// Add добавляет элемент, только если ключа нет в кэше.
+	Add(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// Replace заменяет значение, только если ключ есть в кэше.
+	Replace(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// Expire задает новое время жизни элемента.
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	// Update атомарно изменяет значение элемента, сохраняя время его истечения.
+	Update(ctx context.Context, key string, fn lru.ComputeFunc) (value interface{}, err error)
+	// Stats возвращает статистику кэша.
+	Stats() lru.Stats
+}
+
+// Server обслуживает клиентов Redis по протоколу RESP.
+type Server struct {
+	Addr string       // Адрес, на котором сервер принимает соединения.
+	LRU  Cache        // Кэш, над которым выполняются команды.
+	Log  *slog.Logger // Логгер для записи событий сервера.
+
+	maxBulkLength int            // Максимальная длина аргумента команды в байтах.
+	started       time.Time      // Время создания сервера, отдается в INFO.
+	auth          mw_auth.Config // Параметры аутентификации клиентов командой AUTH.
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+
+	nextID      atomic.Int64  // Идентификатор следующего соединения.
+	connections atomic.Uint64 // Количество принятых соединений.
+	commands    atomic.Uint64 // Количество выполненных команд.
+}
+
+// Option задает дополнительные параметры Server.
+type Option func(s *Server)
+
+// WithMaxBulkLength задает максимальную длину аргумента команды в байтах.
+// Клиент, приславший аргумент большей длины, получает ошибку протокола, соединение закрывается.
+func WithMaxBulkLength(n int) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxBulkLength = n
+		}
+	}
+}
+
+// WithAuth включает аутентификацию клиентов: до команды AUTH или HELLO ... AUTH клиенту доступны только
+// AUTH, HELLO и QUIT, а остальные команды требуют тех же прав, что и HTTP API: чтение - read, изменение - write,
+// FLUSHALL и FLUSHDB - admin. Паролем служит API-ключ, имя пользователя - имя ключа или "default".
+// Если не задан ни один способ аутентификации, команды не проверяются.
+func WithAuth(cfg mw_auth.Config) Option {
+	return func(s *Server) {
+		s.auth = cfg
+	}
+}
+
+// NewServer создает сервер RESP, который будет принимать соединения по адресу address.
+func NewServer(address string, cache Cache, log *slog.Logger, opts ...Option) *Server {
+	s := &Server{
+		Addr:          address,
+		LRU:           cache,
+		Log:           log.With(slog.String("component", "resp")),
+		maxBulkLength: DefaultMaxBulkLength,
+		started:       time.Now(),
+		conns:         make(map[net.Conn]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// ListenAndServe открывает TCP-сокет по адресу Addr и обслуживает соединения до вызова Shutdown.
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve принимает соединения из ln и обслуживает каждое в отдельной горутине.
+// Всегда возвращает ненулевую ошибку, после Shutdown - ErrServerClosed.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = ln.Close()
+		return ErrServerClosed
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	s.Log.Info("starting resp server on " + ln.Addr().String())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		if !s.track(conn) {
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+// Shutdown прекращает прием соединений и ожидает завершения текущих команд.
+// Соединения закрываются после отправки ответа на выполняемую команду.
+// Если ctx завершается раньше, оставшиеся соединения закрываются принудительно.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	// Прерываем ожидание следующей команды, ответ на текущую команду будет дописан.
+	for conn := range s.conns {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			_ = conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// track регистрирует соединение. Возвращает false, если сервер уже останавливается.
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	s.connections.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+// serveConn читает команды соединения и отвечает на них в порядке поступления.
+// Ответы буферизуются и отправляются, когда прочитаны все уже полученные команды,
+// поэтому конвейер (pipelining) из многих команд обходится одной записью в сокет.
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.untrack(conn)
+	defer conn.Close()
+
+	c := &client{
+		id:     s.nextID.Add(1),
+		server: s,
+		reader: reader{r: bufio.NewReader(conn), maxBulkLength: s.maxBulkLength},
+		writer: writer{w: bufio.NewWriter(conn), proto: 2},
+	}
+	log := s.Log.With(slog.String("remote_addr", conn.RemoteAddr().String()), slog.Int64("client_id", c.id))
+
+	for {
+		args, err := c.reader.readCommand()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				c.writer.error("ERR " + err.Error())
+				_ = c.writer.w.Flush()
+				log.Warn("closing connection", sl.Err(err))
+			}
+			return
+		}
+
+		if len(args) > 0 {
+			s.commands.Add(1)
+			c.execute(args)
+		}
+
+		if c.reader.r.Buffered() == 0 || c.quit {
+			if err = c.writer.w.Flush(); err != nil {
+				log.Debug("failed to write reply", sl.Err(err))
+				return
+			}
+		}
+		if c.quit {
+			return
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package resp_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"github.com/instinctG/lru-cache/internal/replication"
+	"github.com/instinctG/lru-cache/internal/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer запускает сервер RESP на свободном порту и возвращает его кэш и адрес.
+func startServer(t *testing.T, opts ...resp.Option) (*lru.Cache, string) {
+	t.Helper()
+
+	cache := lru.NewLRUCache(100, time.Minute)
+	return cache, serve(t, cache, opts...)
+}
+
+// serve запускает сервер над переданным кэшем на свободном порту и возвращает его адрес.
+func serve(t *testing.T, cache resp.Cache, opts ...resp.Option) string {
+	t.Helper()
+
+	srv := resp.NewServer("127.0.0.1:0", cache, slog.New(slog.NewTextHandler(io.Discard, nil)), opts...)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ln) }()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		require.NoError(t, srv.Shutdown(ctx))
+		require.ErrorIs(t, <-done, resp.ErrServerClosed)
+	})
+
+	return ln.Addr().String()
+}
+
+// encode кодирует команду в массив bulk-строк RESP.
+func encode(args ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return b.String()
+}
+
+// roundTrip отправляет запрос и читает ровно столько байт, сколько занимает ожидаемый ответ.
+func roundTrip(t *testing.T, conn net.Conn, r *bufio.Reader, request, want string) {
+	t.Helper()
+
+	_, err := conn.Write([]byte(request))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	got := make([]byte, len(want))
+	_, err = io.ReadFull(r, got)
+	require.NoError(t, err, "partial reply: %q", got)
+	assert.Equal(t, want, string(got))
+}
+
+func TestServerCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{"ping", encode("PING"), "+PONG\r\n"},
+		{"ping message", encode("ping", "hi"), "$2\r\nhi\r\n"},
+		{"inline ping", "PING\r\n", "+PONG\r\n"},
+		{"get missing", encode("GET", "a"), "$-1\r\n"},
+		{"set", encode("SET", "a", "1"), "+OK\r\n"},
+		{"get", encode("GET", "a"), "$1\r\n1\r\n"},
+		{"set nx existing", encode("SET", "a", "2", "NX"), "$-1\r\n"},
+		{"set nx new", encode("SET", "b", "2", "nx", "EX", "100"), "+OK\r\n"},
+		{"set xx missing", encode("SET", "c", "3", "XX"), "$-1\r\n"},
+		{"set xx existing", encode("SET", "a", "10", "XX", "PX", "50000"), "+OK\r\n"},
+		{"set nx and xx", encode("SET", "a", "1", "NX", "XX"), "-ERR syntax error\r\n"},
+		{"set unknown option", encode("SET", "a", "1", "KEEP"), "-ERR syntax error\r\n"},
+		{"set invalid expire", encode("SET", "a", "1", "EX", "0"), "-ERR invalid expire time in 'set' command\r\n"},
+		{"set expire not integer", encode("SET", "a", "1", "EX", "x"), "-ERR value is not an integer or out of range\r\n"},
+		{"ttl", encode("TTL", "b"), ":100\r\n"},
+		{"ttl missing", encode("TTL", "missing"), ":-2\r\n"},
+		{"pttl missing", encode("PTTL", "missing"), ":-2\r\n"},
+		{"ttl px", encode("TTL", "a"), ":50\r\n"},
+		{"exists", encode("EXISTS", "a", "b", "a", "missing"), ":3\r\n"},
+		{"incr", encode("INCR", "a"), ":11\r\n"},
+		{"incr missing", encode("INCR", "counter"), ":1\r\n"},
+		{"incrby", encode("INCRBY", "counter", "-5"), ":-4\r\n"},
+		{"decr", encode("DECR", "counter"), ":-5\r\n"},
+		{"incr keeps ttl", encode("TTL", "a"), ":50\r\n"},
+		{"incr not integer", encode("INCRBY", "a", "x"), "-ERR value is not an integer or out of range\r\n"},
+		{"mset odd", encode("MSET", "x", "1", "y"), "-ERR wrong number of arguments for 'mset' command\r\n"},
+		{"mset", encode("MSET", "x", "1", "y", "2"), "+OK\r\n"},
+		{"mget", encode("MGET", "x", "missing", "y"), "*3\r\n$1\r\n1\r\n$-1\r\n$1\r\n2\r\n"},
+		{"incr on text", encode("INCR", "text"), ":1\r\n"},
+		{"pexpire", encode("PEXPIRE", "x", "2000"), ":1\r\n"},
+		{"pexpire missing", encode("PEXPIRE", "missing", "2000"), ":0\r\n"},
+		{"ttl after pexpire", encode("TTL", "x"), ":2\r\n"},
+		{"pexpire non positive deletes", encode("PEXPIRE", "y", "0"), ":1\r\n"},
+		{"deleted by pexpire", encode("EXISTS", "y"), ":0\r\n"},
+		{"del", encode("DEL", "x", "missing", "text"), ":2\r\n"},
+		{"keys", encode("KEYS", "[^b-z]"), "*1\r\n$1\r\na\r\n"},
+		{"keys glob", encode("KEYS", "c?u*r"), "*1\r\n$7\r\ncounter\r\n"},
+		{"keys none", encode("KEYS", "z*"), "*0\r\n"},
+		{"select", encode("SELECT", "0"), "+OK\r\n"},
+		{"select other db", encode("SELECT", "1"), "-ERR DB index is out of range\r\n"},
+		{"unknown command", encode("SUBSCRIBE", "news", "sport"), "-ERR unknown command 'SUBSCRIBE', with args beginning with: 'news' 'sport' \r\n"},
+		{"wrong arity", encode("GET"), "-ERR wrong number of arguments for 'get' command\r\n"},
+		{"flushall", encode("FLUSHALL"), "+OK\r\n"},
+		{"flushall invalid mode", encode("FLUSHALL", "LATER"), "-ERR syntax error\r\n"},
+		{"empty after flushall", encode("KEYS", "*"), "*0\r\n"},
+		{"hello unsupported", encode("HELLO", "4"), "-NOPROTO unsupported protocol version\r\n"},
+	}
+
+	_, addr := startServer(t)
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// Команды выполняются последовательно в одном соединении, каждая зависит от предыдущих.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roundTrip(t, conn, r, tt.request, tt.want)
+		})
+	}
+}
+
+func TestServerPipelining(t *testing.T) {
+	_, addr := startServer(t)
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var request, want strings.Builder
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		request.WriteString(encode("SET", key, "v"))
+		request.WriteString(encode("INCR", "n"))
+		want.WriteString("+OK\r\n")
+		fmt.Fprintf(&want, ":%d\r\n", i+1)
+	}
+	request.WriteString(encode("GET", "n"))
+	want.WriteString("$4\r\n1000\r\n")
+
+	roundTrip(t, conn, bufio.NewReader(conn), request.String(), want.String())
+}
+
+func TestServerSharesCache(t *testing.T) {
+	cache, addr := startServer(t)
+	require.NoError(t, cache.Put(context.Background(), "num", float64(41), 0))
+	require.NoError(t, cache.Put(context.Background(), "flag", true, 0))
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	roundTrip(t, conn, r, encode("GET", "flag"), "$4\r\ntrue\r\n")
+	roundTrip(t, conn, r, encode("INCR", "num"), ":42\r\n")
+	roundTrip(t, conn, r, encode("SET", "str", "value"), "+OK\r\n")
+
+	// Числа, записанные через HTTP API, остаются числами после INCR.
+	value, _, err := cache.Get(context.Background(), "num")
+	require.NoError(t, err)
+	assert.Equal(t, float64(42), value)
+
+	value, _, err = cache.Get(context.Background(), "str")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
+
+func TestServerRESP3(t *testing.T) {
+	_, addr := startServer(t)
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	_, err = conn.Write([]byte(encode("HELLO", "3")))
+	require.NoError(t, err)
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "%7\r\n", line)
+
+	// Пропускаем 7 пар ключ-значение: 6 скалярных значений и пустой массив modules.
+	for i := 0; i < 13; i++ {
+		line, err = r.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "$") {
+			_, err = r.ReadString('\n')
+			require.NoError(t, err)
+		}
+	}
+	line, err = r.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "*0\r\n", line)
+
+	roundTrip(t, conn, r, encode("GET", "missing"), "_\r\n")
+	roundTrip(t, conn, r, encode("MGET", "missing"), "*1\r\n_\r\n")
+}
+
+func TestServerProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{"invalid bulk prefix", "*1\r\n:1\r\n", "-ERR Protocol error: expected '$', got ':'\r\n"},
+		{"invalid length", "*x\r\n", "-ERR Protocol error: invalid length \"x\"\r\n"},
+		{"bulk too long", "*1\r\n$100\r\n", "-ERR Protocol error: invalid length \"100\"\r\n"},
+	}
+
+	_, addr := startServer(t, resp.WithMaxBulkLength(10))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", addr)
+			require.NoError(t, err)
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+
+			roundTrip(t, conn, r, tt.request, tt.want)
+
+			// После ошибки протокола сервер закрывает соединение.
+			_, err = r.ReadByte()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestServerErrorEcho(t *testing.T) {
+	_, addr := startServer(t)
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// Перевод строки в аргументе не добавляет в поток лишних ответов.
+	roundTrip(t, conn, r, encode("EVIL\r\n+OK", "a\r\n:1"), "-ERR unknown command 'EVIL  +OK', with args beginning with: 'a  :1' \r\n")
+	roundTrip(t, conn, r, encode("CLIENT", "x\r\n+OK"), "-ERR unknown subcommand 'x  +OK'. Try CLIENT HELP.\r\n")
+
+	// Повторяются не больше 128 символов аргументов.
+	long := strings.Repeat("a", 200)
+	roundTrip(t, conn, r, encode("CLIENT", long), "-ERR unknown subcommand '"+long[:128]+"'. Try CLIENT HELP.\r\n")
+	roundTrip(t, conn, r, encode("NOPE", long, "b"), "-ERR unknown command 'NOPE', with args beginning with: '"+long[:128]+"' \r\n")
+	roundTrip(t, conn, r, encode("PING"), "+PONG\r\n")
+}
+
+func TestServerQuitAndInfo(t *testing.T) {
+	_, addr := startServer(t)
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	roundTrip(t, conn, r, encode("SET", "a", "1"), "+OK\r\n")
+
+	_, err = conn.Write([]byte(encode("INFO")))
+	require.NoError(t, err)
+	header, err := r.ReadString('\n')
+	require.NoError(t, err)
+	var size int
+	_, err = fmt.Sscanf(header, "$%d\r\n", &size)
+	require.NoError(t, err)
+	body := make([]byte, size+2)
+	_, err = io.ReadFull(r, body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "# Keyspace\r\ndb0:keys=1,")
+	assert.Contains(t, string(body), "connected_clients:1\r\n")
+
+	roundTrip(t, conn, r, encode("QUIT"), "+OK\r\n")
+	_, err = r.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestServerAuth(t *testing.T) {
+	_, addr := startServer(t, resp.WithAuth(mw_auth.Config{
+		Authenticators: []mw_auth.Authenticator{mw_auth.NewAPIKeyAuthenticator(map[string]string{
+			"consumer": "consumer-key",
+			"service":  "service-key",
+			"ops":      "ops-key",
+		})},
+		Scopes: map[string][]string{
+			"consumer": {mw_auth.ScopeRead},
+			"service":  {mw_auth.ScopeWrite},
+			"ops":      {mw_auth.ScopeAdmin},
+		},
+	}))
+
+	dial := func(t *testing.T) (net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn, bufio.NewReader(conn)
+	}
+
+	t.Run("commands require auth", func(t *testing.T) {
+		conn, r := dial(t)
+
+		roundTrip(t, conn, r, encode("FLUSHALL"), "-NOAUTH Authentication required.\r\n")
+		roundTrip(t, conn, r, encode("SET", "a", "1"), "-NOAUTH Authentication required.\r\n")
+		roundTrip(t, conn, r, encode("PING"), "-NOAUTH Authentication required.\r\n")
+		roundTrip(t, conn, r, encode("AUTH", "wrong"), "-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+		roundTrip(t, conn, r, encode("AUTH", "consumer", "service-key"), "-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+		roundTrip(t, conn, r, encode("GET", "a"), "-NOAUTH Authentication required.\r\n")
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		conn, r := dial(t)
+
+		roundTrip(t, conn, r, encode("AUTH", "service-key"), "+OK\r\n")
+		roundTrip(t, conn, r, encode("SET", "a", "1"), "+OK\r\n")
+		roundTrip(t, conn, r, encode("GET", "a"), "$1\r\n1\r\n")
+		roundTrip(t, conn, r, encode("FLUSHALL"), "-NOPERM User service has no permissions to run the 'flushall' command\r\n")
+
+		roundTrip(t, conn, r, encode("AUTH", "consumer", "consumer-key"), "+OK\r\n")
+		roundTrip(t, conn, r, encode("DEL", "a"), "-NOPERM User consumer has no permissions to run the 'del' command\r\n")
+		roundTrip(t, conn, r, encode("EXISTS", "a"), ":1\r\n")
+
+		roundTrip(t, conn, r, encode("AUTH", "default", "ops-key"), "+OK\r\n")
+		roundTrip(t, conn, r, encode("FLUSHALL"), "+OK\r\n")
+	})
+
+	t.Run("hello auth", func(t *testing.T) {
+		conn, r := dial(t)
+
+		roundTrip(t, conn, r, encode("HELLO", "2"), "-NOAUTH HELLO must be called with the client already authenticated, "+
+			"otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time\r\n")
+		roundTrip(t, conn, r, encode("HELLO", "2", "AUTH", "ops", "wrong"), "-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+
+		_, err := conn.Write([]byte(encode("HELLO", "2", "AUTH", "ops", "ops-key")))
+		require.NoError(t, err)
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "*14\r\n", line)
+		for i := 0; i < 13; i++ {
+			line, err = r.ReadString('\n')
+			require.NoError(t, err)
+			if strings.HasPrefix(line, "$") {
+				_, err = r.ReadString('\n')
+				require.NoError(t, err)
+			}
+		}
+		line, err = r.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "*0\r\n", line)
+
+		roundTrip(t, conn, r, encode("FLUSHALL"), "+OK\r\n")
+	})
+}
+
+func TestServerAuthNotConfigured(t *testing.T) {
+	_, addr := startServer(t)
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	roundTrip(t, conn, r, encode("AUTH", "secret"), "-ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?\r\n")
+	roundTrip(t, conn, r, encode("SET", "a", "1"), "+OK\r\n")
+}
+
+func TestServerReadOnly(t *testing.T) {
+	cache := lru.NewLRUCache(100, time.Minute)
+	require.NoError(t, cache.Put(context.Background(), "a", "1", 0))
+	addr := serve(t, replication.ReadOnly{Cache: cache})
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	readOnly := "-READONLY " + replication.ErrReadOnly.Error() + "\r\n"
+	roundTrip(t, conn, r, encode("GET", "a"), "$1\r\n1\r\n")
+	roundTrip(t, conn, r, encode("SET", "a", "2"), readOnly)
+	roundTrip(t, conn, r, encode("DEL", "a"), readOnly)
+	roundTrip(t, conn, r, encode("EXPIRE", "a", "10"), readOnly)
+	roundTrip(t, conn, r, encode("INCR", "a"), readOnly)
+	roundTrip(t, conn, r, encode("FLUSHALL"), readOnly)
+	roundTrip(t, conn, r, encode("GET", "a"), "$1\r\n1\r\n")
+}
@@ -0,0 +1,193 @@
+// Package tenant изолирует данные команд (арендаторов), которые используют одно развертывание кэша.
+// У каждого арендатора свой кэш с квотой на количество элементов и их суммарный размер,
+// поэтому вытеснение из-за нагрузки одного арендатора удаляет только его элементы.
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"github.com/instinctG/lru-cache/pkg/lrucache"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidQuota возвращается, если квота арендатора задана неверно.
+var ErrInvalidQuota = errors.New("invalid tenant quota")
+
+// Quota описывает ограничения арендатора.
+type Quota struct {
+	MaxEntries int           // Максимальное количество элементов.
+	MaxBytes   int64         // Максимальный суммарный размер элементов в байтах, 0 - без ограничения.
+	TTL        time.Duration // Время жизни элемента по умолчанию.
+}
+
+// Validate проверяет квоту.
+func (q Quota) Validate() error {
+	if q.MaxEntries <= 0 {
+		return fmt.Errorf("%w: max entries must be positive", ErrInvalidQuota)
+	}
+	if q.MaxBytes < 0 {
+		return fmt.Errorf("%w: max bytes must not be negative", ErrInvalidQuota)
+	}
+	if q.TTL < 0 {
+		return fmt.Errorf("%w: ttl must not be negative", ErrInvalidQuota)
+	}
+	return nil
+}
+
+// Tenant - арендатор и его кэш.
+type Tenant struct {
+	Name  string          // Имя арендатора: имя API-ключа или значение заголовка запроса.
+	Cache *lrucache.Cache // Кэш арендатора.
+
+	mu    sync.RWMutex
+	quota Quota
+}
+
+// Quota возвращает текущую квоту арендатора.
+func (t *Tenant) Quota() Quota {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.quota
+}
+
+// setQuota применяет квоту к кэшу арендатора. Если кэш превышает новую квоту, лишние элементы вытесняются.
+func (t *Tenant) setQuota(q Quota) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Cache.SetLimits(q.MaxEntries, q.MaxBytes)
+	t.Cache.SetTTL(q.TTL)
+	t.quota = q
+}
+
+// Registry хранит арендаторов.
+type Registry struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	tenants map[string]*Tenant
+}
+
+// NewRegistry создает пустой реестр. ttl - время жизни элементов для квот, в которых оно не задано.
+func NewRegistry(ttl time.Duration) *Registry {
+	if ttl <= 0 {
+		ttl = lrucache.DefaultTTL
+	}
+
+	return &Registry{
+		ttl:     ttl,
+		tenants: make(map[string]*Tenant),
+	}
+}
+
+// Set задает квоту арендатора, создавая его, если арендатора еще нет. Нулевой TTL заменяется TTL реестра.
+// Возвращает арендатора и признак того, что он создан.
+func (r *Registry) Set(name string, q Quota) (*Tenant, bool, error) {
+	if name == "" {
+		return nil, false, fmt.Errorf("%w: empty tenant name", ErrInvalidQuota)
+	}
+	if q.TTL == 0 {
+		q.TTL = r.ttl
+	}
+	if err := q.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	r.mu.Lock()
+	t, exists := r.tenants[name]
+	if !exists {
+		t = &Tenant{
+			Name:  name,
+			quota: q,
+			Cache: lrucache.New(
+				lrucache.WithCapacity(q.MaxEntries),
+				lrucache.WithMaxBytes(q.MaxBytes),
+				lrucache.WithTTL(q.TTL),
+			),
+		}
+		r.tenants[name] = t
+	}
+	r.mu.Unlock()
+
+	if exists {
+		t.setQuota(q)
+	}
+
+	return t, !exists, nil
+}
+
+// Get возвращает арендатора по имени.
+func (r *Registry) Get(name string) (*Tenant, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.tenants[name]
+	return t, ok
+}
+
+// List возвращает арендаторов в порядке имен.
+func (r *Registry) List() []*Tenant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*Tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list
+}
+
+// ParseQuotas разбирает квоты арендаторов в формате "имя=элементы[:байты[:ttl]],...",
+// например "team-a=10000:67108864:5m,team-b=500". Размер 0 или не указанный - без ограничения,
+// не указанный TTL заменяется TTL реестра при создании арендатора.
+func ParseQuotas(spec string) (map[string]Quota, error) {
+	quotas := make(map[string]Quota)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, params, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		parts := strings.Split(params, ":")
+		if !ok || name == "" || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid tenant quota %q, expected name=entries[:bytes[:ttl]]", entry)
+		}
+
+		var (
+			q   Quota
+			err error
+		)
+		if q.MaxEntries, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+			return nil, fmt.Errorf("tenant %q: invalid max entries %q", name, parts[0])
+		}
+		if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" {
+			if q.MaxBytes, err = strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64); err != nil {
+				return nil, fmt.Errorf("tenant %q: invalid max bytes %q", name, parts[1])
+			}
+		}
+		if len(parts) > 2 && strings.TrimSpace(parts[2]) != "" {
+			if q.TTL, err = time.ParseDuration(strings.TrimSpace(parts[2])); err != nil {
+				return nil, fmt.Errorf("tenant %q: invalid ttl: %w", name, err)
+			}
+		}
+
+		if err := q.Validate(); err != nil {
+			return nil, fmt.Errorf("tenant %q: %w", name, err)
+		}
+		if _, exists := quotas[name]; exists {
+			return nil, fmt.Errorf("duplicate tenant %q", name)
+		}
+		quotas[name] = q
+	}
+
+	return quotas, nil
+}
@@ -0,0 +1,106 @@
+package tenant_test
+
+import (
+	"context"
+	"github.com/instinctG/lru-cache/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseQuotas(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected map[string]tenant.Quota
+		wantErr  bool
+	}{
+		{name: "Empty", spec: "", expected: map[string]tenant.Quota{}},
+		{
+			name: "Entries only",
+			spec: "team-a=100",
+			expected: map[string]tenant.Quota{
+				"team-a": {MaxEntries: 100},
+			},
+		},
+		{
+			name: "Full",
+			spec: " team-a=100:4096:5m , team-b=10::1h,",
+			expected: map[string]tenant.Quota{
+				"team-a": {MaxEntries: 100, MaxBytes: 4096, TTL: 5 * time.Minute},
+				"team-b": {MaxEntries: 10, TTL: time.Hour},
+			},
+		},
+		{name: "Without entries", spec: "team-a", wantErr: true},
+		{name: "Empty name", spec: "=10", wantErr: true},
+		{name: "Invalid entries", spec: "team-a=many", wantErr: true},
+		{name: "Zero entries", spec: "team-a=0", wantErr: true},
+		{name: "Invalid bytes", spec: "team-a=1:big", wantErr: true},
+		{name: "Negative bytes", spec: "team-a=1:-1", wantErr: true},
+		{name: "Invalid ttl", spec: "team-a=1:0:forever", wantErr: true},
+		{name: "Too many parts", spec: "team-a=1:2:1m:x", wantErr: true},
+		{name: "Duplicate tenant", spec: "team-a=1,team-a=2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotas, err := tenant.ParseQuotas(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, quotas)
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	reg := tenant.NewRegistry(time.Minute)
+
+	a, created, err := reg.Set("team-a", tenant.Quota{MaxEntries: 3})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, time.Minute, a.Quota().TTL, "zero TTL is taken from the registry")
+
+	b, _, err := reg.Set("team-b", tenant.Quota{MaxEntries: 1})
+	require.NoError(t, err)
+
+	_, _, err = reg.Set("", tenant.Quota{MaxEntries: 1})
+	assert.ErrorIs(t, err, tenant.ErrInvalidQuota)
+	_, _, err = reg.Set("team-c", tenant.Quota{})
+	assert.ErrorIs(t, err, tenant.ErrInvalidQuota)
+
+	// Нагрузка одного арендатора вытесняет только его элементы.
+	require.NoError(t, b.Cache.Put(ctx, "keep", 1, 0))
+	for _, key := range []string{"k1", "k2", "k3", "k4"} {
+		require.NoError(t, a.Cache.Put(ctx, key, key, 0))
+	}
+	assert.Equal(t, 3, a.Cache.Stats().Size)
+	assert.Equal(t, 1, b.Cache.Stats().Size)
+
+	// Изменение квоты применяется к тому же кэшу и вытесняет лишние элементы.
+	updated, created, err := reg.Set("team-a", tenant.Quota{MaxEntries: 1, MaxBytes: 100, TTL: time.Hour})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Same(t, a, updated)
+	assert.Equal(t, tenant.Quota{MaxEntries: 1, MaxBytes: 100, TTL: time.Hour}, a.Quota())
+
+	keys, _, err := a.Cache.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k4"}, keys)
+	assert.Equal(t, int64(100), a.Cache.Stats().MaxBytes)
+
+	got, ok := reg.Get("team-b")
+	require.True(t, ok)
+	assert.Same(t, b, got)
+	_, ok = reg.Get("team-x")
+	assert.False(t, ok)
+
+	list := reg.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "team-a", list[0].Name)
+	assert.Equal(t, "team-b", list[1].Name)
+}
@@ -0,0 +1,384 @@
+// Package client реализует Go-клиент HTTP API сервиса lru-cache.
+//
+// Client реализует тот же набор методов, что и handler.ILRUCache, поэтому удаленный кэш
+// можно использовать вместо локального. Клиент работает с API v2, повторяет запросы при сетевых
+// ошибках и ответах 429, 502, 503 и 504 с экспоненциальной задержкой и переиспользует соединения.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/instinctG/lru-cache/internal/models"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Значения параметров клиента по умолчанию.
+const (
+	DefaultTimeout      = 10 * time.Second       // DefaultTimeout - таймаут одной попытки запроса.
+	DefaultMaxRetries   = 2                      // DefaultMaxRetries - число повторов после первой попытки.
+	DefaultMinBackoff   = 100 * time.Millisecond // DefaultMinBackoff - задержка перед первым повтором.
+	DefaultMaxBackoff   = 2 * time.Second        // DefaultMaxBackoff - максимальная задержка между повторами.
+	DefaultMaxIdleConns = 100                    // DefaultMaxIdleConns - число простаивающих соединений с сервером.
+)
+
+// entriesPath - путь коллекции элементов кэша в API v2.
+const entriesPath = "/api/v2/cache/entries"
+
+// Client - клиент HTTP API сервиса lru-cache. Безопасен для одновременного использования.
+type Client struct {
+	baseURL *url.URL
+	http    *http.Client
+
+	timeout      time.Duration
+	maxIdleConns int
+	maxRetries   int
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+
+	apiKey      string
+	bearerToken string
+}
+
+// Option задает параметр клиента.
+type Option func(*Client)
+
+// WithTimeout задает таймаут одной попытки запроса, включая чтение ответа.
+// Общее время вызова дополнительно ограничивается контекстом.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetry задает число повторов запроса и границы экспоненциальной задержки между ними.
+// При maxRetries = 0 запросы не повторяются.
+func WithRetry(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithMaxIdleConns задает число простаивающих соединений с сервером, которые хранит пул.
+func WithMaxIdleConns(n int) Option {
+	return func(c *Client) {
+		c.maxIdleConns = n
+	}
+}
+
+// WithHTTPClient задает собственный http.Client, например с настроенным TLS.
+// В этом случае WithTimeout и WithMaxIdleConns не применяются.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithAPIKey передает API-ключ в заголовке X-API-Key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithBearerToken передает JWT в заголовке Authorization: Bearer.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.bearerToken = token
+	}
+}
+
+// New создает клиент сервиса, доступного по адресу baseURL (например, http://localhost:8080).
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base url %q: scheme must be http or https", baseURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid base url %q: host is empty", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:      u,
+		timeout:      DefaultTimeout,
+		maxIdleConns: DefaultMaxIdleConns,
+		maxRetries:   DefaultMaxRetries,
+		minBackoff:   DefaultMinBackoff,
+		maxBackoff:   DefaultMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.http == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConns = c.maxIdleConns
+		transport.MaxIdleConnsPerHost = c.maxIdleConns
+
+		c.http = &http.Client{Transport: transport, Timeout: c.timeout}
+	}
+
+	return c, nil
+}
+
+// Close закрывает простаивающие соединения пула.
+func (c *Client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// Put записывает значение по ключу. TTL округляется вверх до целых секунд,
+// при ttl = 0 используется TTL сервера по умолчанию.
+func (c *Client) Put(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	body, err := json.Marshal(models.PutEntryRequest{Value: value, TTLSeconds: ttlSeconds(ttl)})
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+
+	return c.do(ctx, http.MethodPut, entryPath(key), body, nil, nil)
+}
+
+// Get возвращает значение по ключу и время истечения его срока действия.
+func (c *Client) Get(ctx context.Context, key string) (interface{}, time.Time, error) {
+	var entry models.Entry
+	if err := c.do(ctx, http.MethodGet, entryPath(key), nil, nil, &entry); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var exp time.Time
+	if entry.ExpiresAt != nil {
+		exp = *entry.ExpiresAt
+	}
+
+	return entry.Value, exp, nil
+}
+
+// GetAll возвращает все ключи и значения кэша. Если кэш пуст, возвращается ErrCacheIsEmpty.
+func (c *Client) GetAll(ctx context.Context) ([]string, []interface{}, error) {
+	var list models.EntryList
+	if err := c.do(ctx, http.MethodGet, entriesPath, nil, nil, &list); err != nil {
+		return nil, nil, err
+	}
+
+	if len(list.Items) == 0 {
+		return nil, nil, ErrCacheIsEmpty
+	}
+
+	keys := make([]string, 0, len(list.Items))
+	values := make([]interface{}, 0, len(list.Items))
+	for _, item := range list.Items {
+		keys = append(keys, item.Key)
+		values = append(values, item.Value)
+	}
+
+	return keys, values, nil
+}
+
+// Entries возвращает все элементы кэша вместе со временем истечения одним запросом
+// в порядке вытеснения (от давно использованных к недавно использованным). Чтение не меняет порядок вытеснения.
+func (c *Client) Entries(ctx context.Context) ([]models.Entry, error) {
+	var list models.EntryList
+	if err := c.do(ctx, http.MethodGet, entriesPath, nil, nil, &list); err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// Evict удаляет элемент по ключу и возвращает его значение.
+func (c *Client) Evict(ctx context.Context, key string) (interface{}, error) {
+	header := http.Header{"Prefer": {"return=representation"}}
+
+	var entry models.Entry
+	if err := c.do(ctx, http.MethodDelete, entryPath(key), nil, header, &entry); err != nil {
+		return nil, err
+	}
+
+	return entry.Value, nil
+}
+
+// EvictAll удаляет все элементы кэша.
+func (c *Client) EvictAll(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, entriesPath, nil, nil, nil)
+}
+
+// Metrics возвращает значения метрик сервиса из эндпоинта /metrics. Ключ - имя серии вместе с метками,
+// например lru_cache_evictions_total{reason="expired"}.
+func (c *Client) Metrics(ctx context.Context) (map[string]float64, error) {
+	var body []byte
+	if err := c.do(ctx, http.MethodGet, "/metrics", nil, nil, &body); err != nil {
+		return nil, err
+	}
+
+	metrics := make(map[string]float64)
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			continue
+		}
+		metrics[line[:i]] = value
+	}
+
+	return metrics, nil
+}
+
+// do выполняет запрос с повторами и декодирует JSON-ответ в out, если он не nil.
+// Если out имеет тип *[]byte, в него записывается тело ответа без декодирования.
+// Все методы API v2, которые использует клиент, идемпотентны, поэтому повторять можно любой из них.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, header http.Header, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, path, body, header, out)
+		if err == nil || attempt >= c.maxRetries || !retryable(ctx, err) {
+			return err
+		}
+
+		delay := c.backoff(attempt)
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+			delay = apiErr.RetryAfter
+		}
+
+		// Если задержка не укладывается в срок контекста, повтор все равно не успеет выполниться.
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt выполняет одну попытку запроса.
+func (c *Client) attempt(ctx context.Context, method, path string, body []byte, header http.Header, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, reader)
+	if err != nil {
+		return err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Тело дочитывается до конца, чтобы соединение вернулось в пул.
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if raw, ok := out.(*[]byte); ok {
+		if *raw, err = io.ReadAll(resp.Body); err != nil {
+			return fmt.Errorf("read response: %w", err)
+		}
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
+
+// backoff возвращает задержку перед повтором с номером attempt: экспоненциальный рост
+// от minBackoff до maxBackoff со случайным разбросом, чтобы клиенты не повторяли запросы одновременно.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := float64(c.minBackoff) * math.Pow(2, float64(attempt))
+	if d > float64(c.maxBackoff) {
+		d = float64(c.maxBackoff)
+	}
+
+	half := d / 2
+	return time.Duration(half + rand.Float64()*half)
+}
+
+// retryable сообщает, имеет ли смысл повторить запрос после ошибки err.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	// Ошибки сети, включая таймаут попытки, повторяются, а ошибки декодирования ответа - нет.
+	var netErr net.Error
+	var urlErr *url.Error
+	return errors.As(err, &netErr) || errors.As(err, &urlErr)
+}
+
+// entryPath возвращает путь элемента кэша. Ключ экранируется, поэтому может содержать '/' и '?'.
+func entryPath(key string) string {
+	return entriesPath + "/" + url.PathEscape(key)
+}
+
+// ttlSeconds переводит TTL в целое число секунд с округлением вверх.
+func ttlSeconds(ttl time.Duration) int {
+	if ttl <= 0 {
+		return int(ttl / time.Second)
+	}
+	return int((ttl + time.Second - 1) / time.Second)
+}
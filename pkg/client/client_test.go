@@ -0,0 +1,319 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"github.com/instinctG/lru-cache/internal/http-server/handler"
+	mw_auth "github.com/instinctG/lru-cache/internal/http-server/middleware/auth"
+	"github.com/instinctG/lru-cache/internal/logger"
+	"github.com/instinctG/lru-cache/internal/lru"
+	"github.com/instinctG/lru-cache/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Клиент должен быть взаимозаменяем с локальным кэшом.
+var _ handler.ILRUCache = (*client.Client)(nil)
+
+// newServer запускает httptest-сервер с настоящим обработчиком и возвращает клиент для него.
+func newServer(t *testing.T, opts ...handler.Option) (*httptest.Server, *client.Client) {
+	t.Helper()
+
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger(), opts...)
+	srv := httptest.NewServer(h.Router)
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL, client.WithRetry(0, 0, 0))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	return srv, c
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		wantErr bool
+	}{
+		{name: "HTTP", baseURL: "http://localhost:8080"},
+		{name: "HTTPS with path", baseURL: "https://cache.example.com/prefix/"},
+		{name: "No scheme", baseURL: "localhost:8080", wantErr: true},
+		{name: "Unsupported scheme", baseURL: "ftp://localhost", wantErr: true},
+		{name: "No host", baseURL: "http://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.New(tt.baseURL)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestClient_RoundTrip(t *testing.T) {
+	_, c := newServer(t)
+	ctx := context.Background()
+
+	_, _, err := c.GetAll(ctx)
+	assert.ErrorIs(t, err, client.ErrCacheIsEmpty)
+
+	require.NoError(t, c.Put(ctx, "greeting", "hello", 1500*time.Millisecond))
+	require.NoError(t, c.Put(ctx, "answer", 42, 0))
+	require.NoError(t, c.Put(ctx, "dir/with?query#frag", true, time.Hour))
+
+	val, exp, err := c.Get(ctx, "greeting")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", val)
+	assert.WithinDuration(t, time.Now().Add(2*time.Second), exp, time.Second)
+
+	val, _, err = c.Get(ctx, "answer")
+	require.NoError(t, err)
+	assert.Equal(t, float64(42), val)
+
+	val, _, err = c.Get(ctx, "dir/with?query#frag")
+	require.NoError(t, err)
+	assert.Equal(t, true, val)
+
+	keys, vals, err := c.GetAll(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"greeting", "answer", "dir/with?query#frag"}, keys)
+	assert.Len(t, vals, 3)
+
+	entries, err := c.Entries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "dir/with?query#frag", entries[2].Key)
+	require.NotNil(t, entries[2].ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *entries[2].ExpiresAt, time.Second)
+
+	val, err = c.Evict(ctx, "dir/with?query#frag")
+	require.NoError(t, err)
+	assert.Equal(t, true, val)
+
+	_, _, err = c.Get(ctx, "dir/with?query#frag")
+	assert.ErrorIs(t, err, client.ErrKeyNotFound)
+
+	require.NoError(t, c.EvictAll(ctx))
+
+	_, _, err = c.GetAll(ctx)
+	assert.ErrorIs(t, err, lru.ErrCacheIsEmpty)
+}
+
+func TestClient_Errors(t *testing.T) {
+	_, c := newServer(t, handler.WithLimits(handler.Limits{MaxValueSize: 8}))
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		call       func() error
+		target     error
+		statusCode int
+		code       string
+	}{
+		{
+			name:       "Get missing key",
+			call:       func() error { _, _, err := c.Get(ctx, "missing"); return err },
+			target:     lru.ErrKeyNotFound,
+			statusCode: http.StatusNotFound,
+			code:       "key_not_found",
+		},
+		{
+			name:       "Evict missing key",
+			call:       func() error { _, err := c.Evict(ctx, "missing"); return err },
+			target:     client.ErrKeyNotFound,
+			statusCode: http.StatusNotFound,
+			code:       "key_not_found",
+		},
+		{
+			name:       "Negative TTL",
+			call:       func() error { return c.Put(ctx, "key", "value", -time.Minute) },
+			target:     client.ErrInvalidRequest,
+			statusCode: http.StatusBadRequest,
+			code:       "validation_failed",
+		},
+		{
+			name:       "Value too large",
+			call:       func() error { return c.Put(ctx, "key", strings.Repeat("x", 64), 0) },
+			target:     client.ErrTooLarge,
+			statusCode: http.StatusRequestEntityTooLarge,
+			code:       "value_too_large",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			require.ErrorIs(t, err, tt.target)
+
+			var apiErr *client.Error
+			require.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, tt.statusCode, apiErr.StatusCode)
+			assert.Equal(t, tt.code, apiErr.Code)
+		})
+	}
+}
+
+func TestClient_Auth(t *testing.T) {
+	srv, _ := newServer(t, handler.WithAuth(mw_auth.Config{
+		Authenticators: []mw_auth.Authenticator{mw_auth.NewAPIKeyAuthenticator(map[string]string{
+			"consumer": "consumer-key",
+			"service":  "service-key",
+		})},
+		Scopes: map[string][]string{
+			"consumer": {mw_auth.ScopeRead},
+			"service":  {mw_auth.ScopeRead, mw_auth.ScopeWrite},
+		},
+	}))
+	ctx := context.Background()
+
+	anonymous, err := client.New(srv.URL)
+	require.NoError(t, err)
+	assert.ErrorIs(t, anonymous.Put(ctx, "key", "value", 0), client.ErrUnauthorized)
+
+	consumer, err := client.New(srv.URL, client.WithAPIKey("consumer-key"))
+	require.NoError(t, err)
+	assert.ErrorIs(t, consumer.Put(ctx, "key", "value", 0), client.ErrForbidden)
+
+	service, err := client.New(srv.URL, client.WithAPIKey("service-key"))
+	require.NoError(t, err)
+	require.NoError(t, service.Put(ctx, "key", "value", 0))
+
+	val, _, err := consumer.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", val)
+}
+
+// flaky отвечает 503 на первые failures запросов, а остальные передает обработчику кэша.
+func flaky(t *testing.T, failures int32, retryAfter string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger())
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"type":"about:blank","title":"Service Unavailable","status":503,"code":"overloaded"}`))
+			return
+		}
+		h.Router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func TestClient_Retry(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int32
+		maxRetries    int
+		expectedErr   error
+		expectedCalls int32
+	}{
+		{name: "Succeeds after retries", failures: 2, maxRetries: 2, expectedCalls: 3},
+		{name: "Retries exhausted", failures: 5, maxRetries: 2, expectedErr: client.ErrOverloaded, expectedCalls: 3},
+		{name: "Retries disabled", failures: 1, maxRetries: 0, expectedErr: client.ErrOverloaded, expectedCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := flaky(t, tt.failures, "")
+
+			c, err := client.New(srv.URL, client.WithRetry(tt.maxRetries, time.Millisecond, 5*time.Millisecond))
+			require.NoError(t, err)
+
+			err = c.Put(context.Background(), "key", "value", 0)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedCalls, calls.Load())
+		})
+	}
+}
+
+func TestClient_RetryAfterExceedsDeadline(t *testing.T) {
+	srv, calls := flaky(t, 1, "30")
+
+	c, err := client.New(srv.URL, client.WithRetry(3, time.Millisecond, time.Millisecond))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = c.EvictAll(ctx)
+	require.ErrorIs(t, err, client.ErrOverloaded)
+
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 30*time.Second, apiErr.RetryAfter)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	c, err := client.New(srv.URL, client.WithTimeout(50*time.Millisecond), client.WithRetry(0, 0, 0))
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, _, err = c.Get(context.Background(), "key")
+	require.Error(t, err)
+
+	var netErr net.Error
+	require.True(t, errors.As(err, &netErr))
+	assert.True(t, netErr.Timeout())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestClient_ConnectionPooling(t *testing.T) {
+	h := handler.NewHandler(lru.NewLRUCache(10, time.Minute), ":0", logger.NewDiscardLogger())
+
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(h.Router)
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, c.Put(ctx, "key", i, 0))
+		_, _, err := c.Get(ctx, "missing")
+		require.ErrorIs(t, err, client.ErrKeyNotFound)
+	}
+
+	assert.Equal(t, int32(1), conns.Load())
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/instinctG/lru-cache/pkg/lrucache"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ошибки, в которые отображаются ответы сервера. Проверяются через errors.Is.
+var (
+	ErrKeyNotFound  = lrucache.ErrKeyNotFound  // ErrKeyNotFound - ключ не найден в кэше (404).
+	ErrCacheIsEmpty = lrucache.ErrCacheIsEmpty // ErrCacheIsEmpty - в кэше нет элементов.
+
+	ErrInvalidRequest = errors.New("invalid request")        // ErrInvalidRequest - запрос не прошел валидацию (400).
+	ErrUnauthorized   = errors.New("unauthorized")           // ErrUnauthorized - запрос не аутентифицирован (401).
+	ErrForbidden      = errors.New("forbidden")              // ErrForbidden - у клиента нет прав на операцию (403).
+	ErrConflict       = errors.New("conflict")               // ErrConflict - запрос конфликтует с состоянием ресурса (409, 412).
+	ErrTooLarge       = errors.New("request too large")      // ErrTooLarge - тело запроса или значение превышает лимит (413).
+	ErrRateLimited    = errors.New("rate limited")           // ErrRateLimited - превышена частота запросов (429).
+	ErrOverloaded     = errors.New("server overloaded")      // ErrOverloaded - сервер перегружен (503).
+	ErrServer         = errors.New("server error")           // ErrServer - прочие ошибки сервера (5xx).
+	ErrUnexpected     = errors.New("unexpected status code") // ErrUnexpected - ответ с неожиданным статусом.
+)
+
+// FieldError описывает ошибку валидации отдельного поля запроса.
+type FieldError struct {
+	Field   string `json:"field"`   // Имя поля в JSON.
+	Rule    string `json:"rule"`    // Нарушенное правило валидации.
+	Message string `json:"message"` // Описание ошибки.
+}
+
+// Error - ошибка, полученная от сервера в формате application/problem+json.
+// Unwrap возвращает одну из ошибок пакета, соответствующую статусу ответа.
+type Error struct {
+	StatusCode int           // HTTP-статус ответа.
+	Code       string        // Машиночитаемый код ошибки из поля code.
+	Title      string        // Краткое описание типа ошибки.
+	Detail     string        // Подробное описание ошибки.
+	Fields     []FieldError  // Поля, не прошедшие валидацию.
+	RetryAfter time.Duration // Значение заголовка Retry-After, если сервер его передал.
+}
+
+// Error возвращает текстовое описание ошибки.
+func (e *Error) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Title
+	}
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("lru-cache: %d %s: %s", e.StatusCode, e.Code, msg)
+	}
+	return fmt.Sprintf("lru-cache: %d: %s", e.StatusCode, msg)
+}
+
+// Unwrap возвращает ошибку пакета, соответствующую статусу ответа.
+func (e *Error) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return ErrInvalidRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrKeyNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ErrConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrTooLarge
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusServiceUnavailable:
+		return ErrOverloaded
+	}
+	if e.StatusCode >= 500 {
+		return ErrServer
+	}
+	return ErrUnexpected
+}
+
+// maxErrorBody ограничивает объем тела ответа с ошибкой, который читает клиент.
+const maxErrorBody = 64 << 10
+
+// newError создает Error из ответа сервера. Тело разбирается как application/problem+json,
+// а если это не удается, его текст попадает в Detail.
+func newError(resp *http.Response) *Error {
+	e := &Error{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	var p struct {
+		Title  string       `json:"title"`
+		Detail string       `json:"detail"`
+		Code   string       `json:"code"`
+		Errors []FieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &p); err == nil {
+		e.Title, e.Detail, e.Code, e.Fields = p.Title, p.Detail, p.Code, p.Errors
+		return e
+	}
+
+	e.Detail = strings.TrimSpace(string(body))
+	return e
+}
+
+// parseRetryAfter разбирает заголовок Retry-After в виде числа секунд или HTTP-даты.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
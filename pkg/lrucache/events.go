@@ -0,0 +1,98 @@
+package lrucache
+
+import "time"
+
+// EventType описывает вид изменения элемента кэша.
+type EventType int
+
+const (
+	EventPut   EventType = iota + 1 // EventPut - элемент добавлен, обновлен или изменено его время жизни.
+	EventEvict                      // EventEvict - элемент удален, причина указана в Event.Reason.
+	EventFlush                      // EventFlush - кэш очищен вызовом EvictAll.
+)
+
+// Event описывает изменение элемента кэша, доставляемое подписчикам Subscribe.
+type Event struct {
+	Type      EventType      // Вид изменения.
+	Key       string         // Ключ элемента, пустой для EventFlush.
+	Value     interface{}    // Значение элемента для EventPut.
+	ExpiresAt time.Time      // Время истечения элемента для EventPut.
+	Reason    EvictionReason // Причина удаления для EventEvict.
+	Seq       uint64         // Порядковый номер изменения, растет на 1 с каждым изменением кэша.
+}
+
+// Entry описывает элемент кэша в снимке Snapshot.
+type Entry struct {
+	Key       string      // Ключ элемента.
+	Value     interface{} // Значение элемента.
+	ExpiresAt time.Time   // Время истечения элемента.
+}
+
+// subscriber - канал подписчика на изменения кэша.
+type subscriber struct {
+	events chan Event
+	closed bool
+}
+
+// Subscribe подписывает на изменения элементов кэша. События доставляются в канал с буфером
+// на buffer событий. Запись в кэш не ждет подписчиков: если подписчик не успевает читать
+// и буфер заполнен, канал закрывается, и подписчик должен подписаться заново.
+// Функция cancel отменяет подписку и закрывает канал.
+func (c *Cache) Subscribe(buffer int) (events <-chan Event, cancel func()) {
+	sub := &subscriber{events: make(chan Event, buffer)}
+
+	c.mu.Lock()
+	if c.subscribers == nil {
+		c.subscribers = make(map[*subscriber]struct{})
+	}
+	c.subscribers[sub] = struct{}{}
+	c.mu.Unlock()
+
+	return sub.events, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.unsubscribe(sub)
+	}
+}
+
+// unsubscribe удаляет подписчика и закрывает его канал. Вызывающий должен удерживать c.mu.
+func (c *Cache) unsubscribe(sub *subscriber) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(c.subscribers, sub)
+	close(sub.events)
+}
+
+// Snapshot возвращает неистекшие элементы кэша в порядке вытеснения и порядковый номер (Event.Seq)
+// последнего изменения, отраженного в снимке. Снимок вместе с событиями Subscribe, у которых Seq больше seq,
+// воспроизводит состояние кэша без пропусков и повторов.
+func (c *Cache) Snapshot() (entries []Entry, seq uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries = make([]Entry, 0, len(c.items))
+	for node := c.head.next; node != c.tail; node = node.next {
+		if !c.expired(node) {
+			entries = append(entries, Entry{Key: node.key, Value: node.value, ExpiresAt: node.expiresAt})
+		}
+	}
+
+	return entries, c.seq
+}
+
+// publish присваивает событию порядковый номер и доставляет его подписчикам. Вызывающий должен удерживать c.mu.
+func (c *Cache) publish(event Event) {
+	c.seq++
+	event.Seq = c.seq
+
+	for sub := range c.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			c.unsubscribe(sub)
+		}
+	}
+}
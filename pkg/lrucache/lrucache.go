@@ -0,0 +1,619 @@
+// Package lrucache реализует потокобезопасный кэш в памяти с вытеснением по принципу LRU (Least Recently Used)
+// и временем жизни элементов (TTL).
+//
+// Кэш создается функцией New с функциональными опциями:
+//
+//	cache := lrucache.New(
+//		lrucache.WithCapacity(1000),
+//		lrucache.WithTTL(5*time.Minute),
+//		lrucache.WithOnEvict(func(key string, value interface{}, reason lrucache.EvictionReason) {
+//			log.Printf("evicted %s: %s", key, reason)
+//		}),
+//	)
+//
+// Внутреннее состояние кэша скрыто, поэтому его нельзя повредить извне.
+// Этот же пакет используется сервисом lru-cache для HTTP, gRPC, RESP и memcached API.
+package lrucache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrCacheIsEmpty = errors.New("cache is empty") // ErrCacheIsEmpty возвращается, если кэш пустой.
+	ErrKeyNotFound  = errors.New("key not found")  // ErrKeyNotFound возвращается, если ключ не найден в кэше.
+	ErrKeyExists    = errors.New("key exists")     // ErrKeyExists возвращается, если ключ уже есть в кэше.
+
+	// ErrEntryTooLarge возвращается при записи элемента, размер которого превышает ограничение WithMaxBytes.
+	ErrEntryTooLarge = errors.New("entry exceeds the cache size limit")
+
+	// ErrVersionMismatch возвращается CompareAndSwap, если элемент изменился после чтения.
+	ErrVersionMismatch = errors.New("version mismatch")
+)
+
+// node представляет элемент в кэше.
+type node struct {
+	key       string      // Ключ элемента.
+	value     interface{} // Значение элемента.
+	expiresAt time.Time   // Время истечения срока действия элемента.
+	version   uint64      // Версия элемента, меняется при каждой записи значения.
+	size      int64       // Размер элемента в байтах, учитывается только при ограничении maxBytes.
+	prev      *node       // Указатель на предыдущий элемент.
+	next      *node       // Указатель на следующий элемент.
+}
+
+// Cache представляет кэш с вытеснением по принципу LRU. Создается функцией New.
+type Cache struct {
+	capacity   int              // Максимальная емкость кэша.
+	maxBytes   int64            // Максимальный суммарный размер элементов в байтах, 0 - без ограничения.
+	bytes      int64            // Суммарный размер элементов в байтах при ограничении maxBytes.
+	sizer      Sizer            // Функция вычисления размера элемента.
+	items      map[string]*node // Хранилище для элементов кэша.
+	head, tail *node            // Начало и конец двусвязного списка, от давно использованных к недавно использованным.
+	mu         sync.RWMutex     // Мьютекс для обеспечения потокобезопасности.
+	ttl        time.Duration    // Время жизни элемента по умолчанию.
+	clock      Clock            // Источник текущего времени.
+	policy     Policy           // Политика вытеснения.
+
+	onEvict EvictFunc // Обработчик удаления элементов, см. WithOnEvict.
+	evicted []evicted // Элементы, удаленные под c.mu, для которых еще не вызван onEvict.
+
+	version     atomic.Uint64            // Последняя выданная версия элемента.
+	subscribers map[*subscriber]struct{} // Подписчики на изменения элементов, см. Subscribe.
+	seq         uint64                   // Порядковый номер последнего изменения, см. Event.Seq.
+
+	locksMu sync.Mutex          // Мьютекс для таблицы блокировок ключей.
+	locks   map[string]*keyLock // Блокировки отдельных ключей, используемые Compute.
+
+	hits      atomic.Uint64                  // Количество успешных чтений.
+	misses    atomic.Uint64                  // Количество чтений отсутствующих ключей.
+	evictions [evictionReasons]atomic.Uint64 // Количество удаленных элементов по причинам.
+}
+
+// evicted - удаленный элемент, ожидающий вызова обработчика onEvict.
+type evicted struct {
+	key    string
+	value  interface{}
+	reason EvictionReason
+}
+
+// EvictionReason описывает причину удаления элемента из кэша.
+type EvictionReason int
+
+// Причины удаления элементов из кэша.
+const (
+	EvictionCapacity EvictionReason = iota // EvictionCapacity - элемент вытеснен при превышении емкости.
+	EvictionExpired                        // EvictionExpired - истек срок действия элемента.
+	EvictionExplicit                       // EvictionExplicit - элемент удален вызовом Evict или Compute.
+	EvictionFlush                          // EvictionFlush - элемент удален вызовом EvictAll.
+
+	evictionReasons = iota
+)
+
+// String возвращает название причины удаления.
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictionCapacity:
+		return "capacity"
+	case EvictionExpired:
+		return "expired"
+	case EvictionExplicit:
+		return "explicit"
+	case EvictionFlush:
+		return "flush"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText возвращает название причины удаления, чтобы статистика кодировалась в JSON с понятными ключами.
+func (r EvictionReason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText разбирает название причины удаления.
+func (r *EvictionReason) UnmarshalText(text []byte) error {
+	for reason := EvictionReason(0); reason < evictionReasons; reason++ {
+		if reason.String() == string(text) {
+			*r = reason
+			return nil
+		}
+	}
+	return errors.New("unknown eviction reason: " + string(text))
+}
+
+// Stats содержит статистику использования кэша.
+type Stats struct {
+	Size      int                       `json:"size"`      // Текущее количество элементов.
+	Capacity  int                       `json:"capacity"`  // Максимальная емкость кэша.
+	Bytes     int64                     `json:"bytes"`     // Суммарный размер элементов в байтах, учитывается только при MaxBytes > 0.
+	MaxBytes  int64                     `json:"max_bytes"` // Максимальный суммарный размер элементов в байтах, 0 - без ограничения.
+	Hits      uint64                    `json:"hits"`      // Количество успешных чтений.
+	Misses    uint64                    `json:"misses"`    // Количество чтений отсутствующих или истекших ключей.
+	Evictions map[EvictionReason]uint64 `json:"evictions"` // Количество удаленных элементов по причинам.
+}
+
+// keyLock представляет блокировку отдельного ключа со счетчиком ожидающих горутин.
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// ComputeFunc вычисляет новое значение элемента по старому.
+// old - текущее значение (nil, если ключа нет или он истек), exists - признак наличия ключа.
+// Если keep равен false, элемент удаляется из кэша.
+type ComputeFunc func(old interface{}, exists bool) (new interface{}, keep bool)
+
+func (c *Cache) remove(n *node) {
+	prev, next := n.prev, n.next
+	prev.next, next.prev = next, prev
+}
+
+func (c *Cache) insert(n *node) {
+	prev, next := c.tail.prev, c.tail
+	prev.next, next.prev = n, n
+	n.prev, n.next = prev, next
+}
+
+// touch отмечает чтение элемента. При политике PolicyLRU элемент перемещается в конец очереди вытеснения.
+func (c *Cache) touch(n *node) {
+	if c.policy == PolicyFIFO {
+		return
+	}
+	c.remove(n)
+	c.insert(n)
+}
+
+// New создает кэш с заданными опциями. Без опций емкость равна DefaultCapacity,
+// время жизни элементов по умолчанию - DefaultTTL, а политика вытеснения - PolicyLRU.
+func New(opts ...Option) *Cache {
+	head, tail := new(node), new(node)
+	head.next, tail.prev = tail, head
+
+	c := &Cache{
+		capacity: DefaultCapacity,
+		items:    make(map[string]*node),
+		head:     head,
+		tail:     tail,
+		ttl:      DefaultTTL,
+		clock:    systemClock{},
+		policy:   PolicyLRU,
+		sizer:    DefaultSizer,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// unlock освобождает c.mu и вызывает обработчик удаления для элементов, удаленных под блокировкой.
+// Обработчик вызывается без блокировки, поэтому может обращаться к кэшу.
+func (c *Cache) unlock() {
+	pending := c.evicted
+	c.evicted = nil
+	c.mu.Unlock()
+
+	for _, e := range pending {
+		c.onEvict(e.key, e.value, e.reason)
+	}
+}
+
+// Put добавляет элемент в кэш. Если ключ уже существует, элемент и TTL обновляется.
+// Если емкость или ограничение размера превышены, удаляются самые старые элементы.
+func (c *Cache) Put(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	unlock := c.lockKey(key)
+	defer unlock()
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	return c.put(key, value, ttl)
+}
+
+// put добавляет элемент в кэш. Вызывающий должен удерживать c.mu.
+// Если элемент больше ограничения maxBytes, кэш не изменяется и возвращается ErrEntryTooLarge.
+func (c *Cache) put(key string, value interface{}, ttl time.Duration) error {
+	var size int64
+	if c.maxBytes > 0 {
+		if size = c.sizer(key, value); size > c.maxBytes {
+			return ErrEntryTooLarge
+		}
+	}
+
+	if ttl == 0 {
+		ttl = c.ttl
+	}
+	expiresAt := c.clock.Now().Add(ttl)
+
+	if node, exists := c.items[key]; exists {
+		c.remove(node)
+		c.bytes -= node.size
+	}
+
+	c.items[key] = &node{key: key, value: value, expiresAt: expiresAt, version: c.version.Add(1), size: size}
+	c.insert(c.items[key])
+	c.bytes += size
+	c.publish(Event{Type: EventPut, Key: key, Value: value, ExpiresAt: expiresAt})
+
+	c.shrink()
+
+	return nil
+}
+
+// shrink вытесняет элементы в порядке политики вытеснения, пока количество элементов превышает емкость,
+// а их суммарный размер - ограничение maxBytes. Вызывающий должен удерживать c.mu.
+func (c *Cache) shrink() {
+	for c.head.next != c.tail && (len(c.items) > c.capacity || c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.evictElement(c.head.next, EvictionCapacity)
+	}
+}
+
+// SetLimits изменяет емкость кэша и ограничение суммарного размера элементов в байтах (0 - без ограничения).
+// Если кэш превышает новые ограничения, лишние элементы вытесняются в порядке политики вытеснения.
+func (c *Cache) SetLimits(capacity int, maxBytes int64) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	switch {
+	case maxBytes > 0 && c.maxBytes == 0:
+		// Размеры элементов не учитывались без ограничения, поэтому вычисляются заново.
+		c.bytes = 0
+		for n := c.head.next; n != c.tail; n = n.next {
+			n.size = c.sizer(n.key, n.value)
+			c.bytes += n.size
+		}
+	case maxBytes == 0:
+		c.bytes = 0
+		for n := c.head.next; n != c.tail; n = n.next {
+			n.size = 0
+		}
+	}
+
+	c.capacity, c.maxBytes = capacity, maxBytes
+	c.shrink()
+}
+
+// SetTTL изменяет время жизни элементов по умолчанию. Время истечения записанных элементов не меняется.
+func (c *Cache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.ttl = ttl
+}
+
+// Get возвращает значение и время истечения для указанного ключа.
+// Если ключ отсутствует или истек, возвращается ошибка.
+func (c *Cache) Get(ctx context.Context, key string) (value interface{}, expiresAt time.Time, err error) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	node, exists := c.items[key]
+	if !exists {
+		c.misses.Add(1)
+		return nil, time.Time{}, ErrKeyNotFound
+	}
+
+	if c.expired(node) {
+		c.evictElement(node, EvictionExpired)
+		c.misses.Add(1)
+		return nil, time.Time{}, ErrKeyNotFound
+	}
+
+	c.hits.Add(1)
+	c.touch(node)
+	return node.value, node.expiresAt, nil
+}
+
+// GetVersion работает как Get и дополнительно возвращает версию элемента для CompareAndSwap.
+func (c *Cache) GetVersion(ctx context.Context, key string) (value interface{}, expiresAt time.Time, version uint64, err error) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	node, exists := c.items[key]
+	if !exists {
+		c.misses.Add(1)
+		return nil, time.Time{}, 0, ErrKeyNotFound
+	}
+
+	if c.expired(node) {
+		c.evictElement(node, EvictionExpired)
+		c.misses.Add(1)
+		return nil, time.Time{}, 0, ErrKeyNotFound
+	}
+
+	c.hits.Add(1)
+	c.touch(node)
+	return node.value, node.expiresAt, node.version, nil
+}
+
+// GetAll возвращает все ключи и значения, которые еще не истекли.
+// Если кэш пуст, возвращается ошибка.
+func (c *Cache) GetAll(ctx context.Context) (keys []string, values []interface{}, err error) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	node := c.head.next
+
+	for node != c.tail {
+		if !c.expired(node) {
+			keys = append(keys, node.key)
+			values = append(values, node.value)
+		} else {
+			c.evictElement(node, EvictionExpired)
+		}
+		node = node.next
+	}
+
+	if len(c.items) == 0 {
+		return nil, nil, ErrCacheIsEmpty
+	}
+
+	return keys, values, nil
+}
+
+// Evict удаляет указанный ключ из кэша и возвращает его значение.
+// Если ключ отсутствует или истек, возвращается ошибка ErrKeyNotFound.
+func (c *Cache) Evict(ctx context.Context, key string) (value interface{}, err error) {
+	unlock := c.lockKey(key)
+	defer unlock()
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	node, exists := c.items[key]
+	if !exists {
+		return nil, ErrKeyNotFound
+	}
+
+	if c.expired(node) {
+		c.evictElement(node, EvictionExpired)
+		return nil, ErrKeyNotFound
+	}
+
+	c.evictElement(node, EvictionExplicit)
+	return node.value, nil
+}
+
+// EvictAll удаляет все элементы из кэша.
+func (c *Cache) EvictAll(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.evictions[EvictionFlush].Add(uint64(len(c.items)))
+
+	if c.onEvict != nil {
+		for node := c.head.next; node != c.tail; node = node.next {
+			c.evicted = append(c.evicted, evicted{key: node.key, value: node.value, reason: EvictionFlush})
+		}
+	}
+
+	c.items = make(map[string]*node)
+	c.head.next, c.tail.prev = c.tail, c.head
+	c.bytes = 0
+	c.publish(Event{Type: EventFlush})
+
+	return nil
+}
+
+// Compute атомарно относительно ключа key читает текущее значение, вычисляет новое с помощью fn
+// и записывает его обратно. Функция fn выполняется под блокировкой только этого ключа,
+// поэтому операции с другими ключами не ждут ее завершения.
+// Записанный элемент перемещается в начало очереди LRU, его TTL устанавливается по умолчанию.
+// Если fn возвращает keep == false, элемент удаляется, а Compute возвращает ErrKeyNotFound.
+func (c *Cache) Compute(ctx context.Context, key string, fn ComputeFunc) (value interface{}, err error) {
+	return c.compute(ctx, key, fn, false)
+}
+
+// Update работает как Compute, но сохраняет время истечения существующего элемента.
+// Новый элемент получает TTL по умолчанию. Используется для операций вроде инкремента,
+// которые не должны продлевать жизнь элемента.
+func (c *Cache) Update(ctx context.Context, key string, fn ComputeFunc) (value interface{}, err error) {
+	return c.compute(ctx, key, fn, true)
+}
+
+func (c *Cache) compute(ctx context.Context, key string, fn ComputeFunc, keepTTL bool) (value interface{}, err error) {
+	unlock := c.lockKey(key)
+	defer unlock()
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	var old interface{}
+	var expiresAt time.Time
+	node, exists := c.items[key]
+	if exists && c.expired(node) {
+		c.evictElement(node, EvictionExpired)
+		exists = false
+	}
+	if exists {
+		old, expiresAt = node.value, node.expiresAt
+	}
+	c.unlock()
+
+	value, keep := fn(old, exists)
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	if !keep {
+		if node, ok := c.items[key]; ok {
+			c.evictElement(node, EvictionExplicit)
+		}
+		return nil, ErrKeyNotFound
+	}
+
+	var ttl time.Duration
+	if keepTTL && exists {
+		// Элемент мог истечь, пока выполнялась fn: минимальный TTL сохраняет его до следующего чтения, которое его удалит.
+		ttl = max(expiresAt.Sub(c.clock.Now()), time.Nanosecond)
+	}
+	if err := c.put(key, value, ttl); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// Add добавляет элемент, только если ключа нет в кэше или он истек.
+// Если ключ уже есть, возвращается ErrKeyExists.
+func (c *Cache) Add(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	unlock := c.lockKey(key)
+	defer unlock()
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	if node, exists := c.items[key]; exists && !c.expired(node) {
+		return ErrKeyExists
+	}
+
+	return c.put(key, value, ttl)
+}
+
+// Replace обновляет элемент, только если ключ есть в кэше и не истек.
+// Если ключа нет, возвращается ErrKeyNotFound.
+func (c *Cache) Replace(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	unlock := c.lockKey(key)
+	defer unlock()
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	node, exists := c.items[key]
+	if !exists {
+		return ErrKeyNotFound
+	}
+	if c.expired(node) {
+		c.evictElement(node, EvictionExpired)
+		return ErrKeyNotFound
+	}
+
+	return c.put(key, value, ttl)
+}
+
+// Expire устанавливает новое время жизни элемента, не изменяя его значение и положение в очереди LRU.
+// Если ttl не положителен, элемент удаляется. Если ключа нет, возвращается ErrKeyNotFound.
+func (c *Cache) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	unlock := c.lockKey(key)
+	defer unlock()
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	node, exists := c.items[key]
+	if !exists {
+		return ErrKeyNotFound
+	}
+	if c.expired(node) {
+		c.evictElement(node, EvictionExpired)
+		return ErrKeyNotFound
+	}
+
+	if ttl <= 0 {
+		c.evictElement(node, EvictionExplicit)
+		return nil
+	}
+
+	node.expiresAt = c.clock.Now().Add(ttl)
+	c.publish(Event{Type: EventPut, Key: key, Value: node.value, ExpiresAt: node.expiresAt})
+
+	return nil
+}
+
+// CompareAndSwap записывает значение, только если версия элемента равна version,
+// то есть элемент не изменялся после чтения через GetVersion.
+// Если ключа нет, возвращается ErrKeyNotFound, если версия отличается - ErrVersionMismatch.
+func (c *Cache) CompareAndSwap(ctx context.Context, key string, value interface{}, ttl time.Duration, version uint64) error {
+	unlock := c.lockKey(key)
+	defer unlock()
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	node, exists := c.items[key]
+	if !exists {
+		return ErrKeyNotFound
+	}
+	if c.expired(node) {
+		c.evictElement(node, EvictionExpired)
+		return ErrKeyNotFound
+	}
+	if node.version != version {
+		return ErrVersionMismatch
+	}
+
+	return c.put(key, value, ttl)
+}
+
+// lockKey захватывает блокировку ключа и возвращает функцию для ее освобождения.
+func (c *Cache) lockKey(key string) (unlock func()) {
+	c.locksMu.Lock()
+	if c.locks == nil {
+		c.locks = make(map[string]*keyLock)
+	}
+	l, ok := c.locks[key]
+	if !ok {
+		l = new(keyLock)
+		c.locks[key] = l
+	}
+	l.refs++
+	c.locksMu.Unlock()
+
+	l.mu.Lock()
+
+	return func() {
+		l.mu.Unlock()
+
+		c.locksMu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(c.locks, key)
+		}
+		c.locksMu.Unlock()
+	}
+}
+
+// expired проверяет, истек ли срок действия элемента.
+func (c *Cache) expired(n *node) bool { return c.clock.Now().After(n.expiresAt) }
+
+// Stats возвращает статистику использования кэша.
+func (c *Cache) Stats() Stats {
+	c.mu.RLock()
+	size, capacity, bytes, maxBytes := len(c.items), c.capacity, c.bytes, c.maxBytes
+	c.mu.RUnlock()
+
+	stats := Stats{
+		Size:      size,
+		Capacity:  capacity,
+		Bytes:     bytes,
+		MaxBytes:  maxBytes,
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: make(map[EvictionReason]uint64, evictionReasons),
+	}
+	for reason := EvictionReason(0); reason < evictionReasons; reason++ {
+		stats.Evictions[reason] = c.evictions[reason].Load()
+	}
+
+	return stats
+}
+
+func (c *Cache) evictElement(node *node, reason EvictionReason) {
+	c.remove(node)
+	delete(c.items, node.key)
+	c.bytes -= node.size
+	c.evictions[reason].Add(1)
+	c.publish(Event{Type: EventEvict, Key: node.key, Reason: reason})
+
+	if c.onEvict != nil {
+		c.evicted = append(c.evicted, evicted{key: node.key, value: node.value, reason: reason})
+	}
+}
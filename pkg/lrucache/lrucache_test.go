@@ -0,0 +1,294 @@
+package lrucache_test
+
+import (
+	"context"
+	"github.com/instinctG/lru-cache/pkg/lrucache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock - управляемый источник времени.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestNew_Defaults(t *testing.T) {
+	ctx := context.Background()
+	cache := lrucache.New()
+
+	require.NoError(t, cache.Put(ctx, "key", "value", 0))
+
+	_, expiresAt, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(lrucache.DefaultTTL), expiresAt, time.Second)
+	assert.Equal(t, lrucache.DefaultCapacity, cache.Stats().Capacity)
+}
+
+func TestWithClock(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := lrucache.New(lrucache.WithClock(clock), lrucache.WithTTL(time.Minute))
+
+	require.NoError(t, cache.Put(ctx, "default", 1, 0))
+	require.NoError(t, cache.Put(ctx, "long", 2, time.Hour))
+
+	_, expiresAt, err := cache.Get(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, clock.now.Add(time.Minute), expiresAt)
+
+	clock.Advance(time.Minute + time.Second)
+
+	_, _, err = cache.Get(ctx, "default")
+	assert.ErrorIs(t, err, lrucache.ErrKeyNotFound)
+
+	value, _, err := cache.Get(ctx, "long")
+	require.NoError(t, err)
+	assert.Equal(t, 2, value)
+
+	// Update сохраняет время истечения по тем же часам.
+	_, err = cache.Update(ctx, "long", func(old interface{}, exists bool) (interface{}, bool) {
+		return old.(int) + 1, true
+	})
+	require.NoError(t, err)
+
+	_, expiresAt, err = cache.Get(ctx, "long")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC), expiresAt)
+}
+
+func TestWithPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   lrucache.Policy
+		expected []string
+	}{
+		{name: "LRU keeps recently read key", policy: lrucache.PolicyLRU, expected: []string{"a", "c"}},
+		{name: "FIFO evicts oldest write", policy: lrucache.PolicyFIFO, expected: []string{"b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := lrucache.New(lrucache.WithCapacity(2), lrucache.WithPolicy(tt.policy))
+
+			require.NoError(t, cache.Put(ctx, "a", 1, 0))
+			require.NoError(t, cache.Put(ctx, "b", 2, 0))
+			_, _, err := cache.Get(ctx, "a")
+			require.NoError(t, err)
+			require.NoError(t, cache.Put(ctx, "c", 3, 0))
+
+			keys, _, err := cache.GetAll(ctx)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, keys)
+		})
+	}
+}
+
+func TestWithOnEvict(t *testing.T) {
+	ctx := context.Background()
+
+	type eviction struct {
+		key    string
+		value  interface{}
+		reason lrucache.EvictionReason
+	}
+
+	var (
+		cache     *lrucache.Cache
+		evictions []eviction
+	)
+	cache = lrucache.New(
+		lrucache.WithCapacity(2),
+		lrucache.WithOnEvict(func(key string, value interface{}, reason lrucache.EvictionReason) {
+			evictions = append(evictions, eviction{key, value, reason})
+			// Обработчик вызывается без блокировки кэша и может к нему обращаться.
+			_ = cache.Stats()
+			_, _, _ = cache.Get(ctx, key)
+		}),
+	)
+
+	require.NoError(t, cache.Put(ctx, "a", 1, 0))
+	require.NoError(t, cache.Put(ctx, "b", 2, -time.Minute))
+	require.NoError(t, cache.Put(ctx, "c", 3, 0))
+	_, _, err := cache.Get(ctx, "b")
+	assert.ErrorIs(t, err, lrucache.ErrKeyNotFound)
+	require.NoError(t, cache.Put(ctx, "d", 4, 0))
+	_, err = cache.Evict(ctx, "c")
+	require.NoError(t, err)
+	require.NoError(t, cache.EvictAll(ctx))
+
+	assert.Equal(t, []eviction{
+		{"a", 1, lrucache.EvictionCapacity},
+		{"b", 2, lrucache.EvictionExpired},
+		{"c", 3, lrucache.EvictionExplicit},
+		{"d", 4, lrucache.EvictionFlush},
+	}, evictions)
+}
+
+func TestWithOnEvict_Expired(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Now()}
+
+	var evicted []string
+	cache := lrucache.New(
+		lrucache.WithClock(clock),
+		lrucache.WithOnEvict(func(key string, _ interface{}, reason lrucache.EvictionReason) {
+			if reason == lrucache.EvictionExpired {
+				evicted = append(evicted, key)
+			}
+		}),
+	)
+
+	require.NoError(t, cache.Put(ctx, "a", 1, time.Second))
+	require.NoError(t, cache.Put(ctx, "b", 2, time.Second))
+	clock.Advance(2 * time.Second)
+
+	_, err := cache.Compute(ctx, "a", func(old interface{}, exists bool) (interface{}, bool) {
+		assert.False(t, exists)
+		return 10, true
+	})
+	require.NoError(t, err)
+
+	_, _, err = cache.GetAll(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a", "b"}, evicted)
+}
+
+func TestWithMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	cache := lrucache.New(lrucache.WithMaxBytes(10))
+
+	// Размер элемента по умолчанию - длина ключа и значения.
+	require.NoError(t, cache.Put(ctx, "a", "1234", 0))
+	require.NoError(t, cache.Put(ctx, "b", "1234", 0))
+	assert.Equal(t, int64(10), cache.Stats().Bytes)
+
+	// Перезапись учитывает новый размер элемента.
+	require.NoError(t, cache.Put(ctx, "b", "12", 0))
+	assert.Equal(t, int64(8), cache.Stats().Bytes)
+
+	require.NoError(t, cache.Put(ctx, "c", "123", 0))
+
+	keys, _, err := cache.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, keys)
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(7), stats.Bytes)
+	assert.Equal(t, uint64(1), stats.Evictions[lrucache.EvictionCapacity])
+
+	assert.ErrorIs(t, cache.Put(ctx, "d", "1234567890", 0), lrucache.ErrEntryTooLarge)
+	assert.ErrorIs(t, cache.Put(ctx, "b", "1234567890", 0), lrucache.ErrEntryTooLarge)
+
+	value, _, err := cache.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, "12", value, "a rejected entry keeps the old value")
+
+	require.NoError(t, cache.EvictAll(ctx))
+	assert.Equal(t, int64(0), cache.Stats().Bytes)
+}
+
+func TestSetLimits(t *testing.T) {
+	ctx := context.Background()
+	cache := lrucache.New(
+		lrucache.WithCapacity(10),
+		lrucache.WithSizer(func(key string, value interface{}) int64 { return int64(value.(int)) }),
+	)
+
+	for i, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, cache.Put(ctx, key, i+1, 0))
+	}
+	assert.Equal(t, int64(0), cache.Stats().Bytes, "sizes are not tracked without a byte limit")
+
+	// Включение ограничения пересчитывает размеры и вытесняет старые элементы.
+	cache.SetLimits(10, 7)
+
+	keys, _, err := cache.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "d"}, keys)
+	assert.Equal(t, int64(7), cache.Stats().Bytes)
+
+	cache.SetLimits(1, 0)
+
+	keys, _, err = cache.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d"}, keys)
+
+	stats := cache.Stats()
+	assert.Equal(t, 1, stats.Capacity)
+	assert.Equal(t, int64(0), stats.Bytes)
+	assert.Equal(t, int64(0), stats.MaxBytes)
+}
+
+func TestSetTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := lrucache.New(lrucache.WithClock(clock), lrucache.WithTTL(time.Minute))
+
+	require.NoError(t, cache.Put(ctx, "old", 1, 0))
+	cache.SetTTL(time.Hour)
+	require.NoError(t, cache.Put(ctx, "new", 2, 0))
+
+	_, expiresAt, err := cache.Get(ctx, "old")
+	require.NoError(t, err)
+	assert.Equal(t, clock.now.Add(time.Minute), expiresAt)
+
+	_, expiresAt, err = cache.Get(ctx, "new")
+	require.NoError(t, err)
+	assert.Equal(t, clock.now.Add(time.Hour), expiresAt)
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := lrucache.New(lrucache.WithClock(clock), lrucache.WithTTL(time.Minute))
+
+	entries, seq := cache.Snapshot()
+	assert.Empty(t, entries)
+	assert.Zero(t, seq)
+
+	require.NoError(t, cache.Put(ctx, "short", 1, time.Second))
+	require.NoError(t, cache.Put(ctx, "a", 2, 0))
+	require.NoError(t, cache.Put(ctx, "b", 3, 0))
+	clock.Advance(2 * time.Second)
+
+	// Истекшие элементы не попадают в снимок, но и не удаляются им.
+	entries, seq = cache.Snapshot()
+	assert.Equal(t, []lrucache.Entry{
+		{Key: "a", Value: 2, ExpiresAt: clock.now.Add(time.Minute - 2*time.Second)},
+		{Key: "b", Value: 3, ExpiresAt: clock.now.Add(time.Minute - 2*time.Second)},
+	}, entries)
+	assert.Equal(t, uint64(3), seq)
+
+	// События после снимка продолжают нумерацию.
+	events, cancel := cache.Subscribe(10)
+	defer cancel()
+
+	_, err := cache.Evict(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, cache.EvictAll(ctx))
+
+	event := <-events
+	assert.Equal(t, lrucache.EventEvict, event.Type)
+	assert.Equal(t, seq+1, event.Seq)
+	event = <-events
+	assert.Equal(t, lrucache.EventFlush, event.Type)
+	assert.Equal(t, seq+2, event.Seq)
+}
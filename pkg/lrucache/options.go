@@ -0,0 +1,152 @@
+package lrucache
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Значения параметров кэша по умолчанию.
+const (
+	DefaultCapacity = 1000        // DefaultCapacity - максимальное количество элементов.
+	DefaultTTL      = time.Minute // DefaultTTL - время жизни элемента, если при записи TTL не указан.
+)
+
+// Option задает параметр кэша при создании через New.
+type Option func(*Cache)
+
+// Clock - источник текущего времени для проверки срока действия элементов.
+// Позволяет управлять временем в тестах.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock - источник системного времени.
+type systemClock struct{}
+
+// Now возвращает текущее системное время.
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Policy - политика вытеснения, определяющая, какой элемент удаляется при превышении емкости.
+type Policy int
+
+// Поддерживаемые политики вытеснения.
+const (
+	PolicyLRU  Policy = iota // PolicyLRU - вытесняется элемент, который дольше всех не читали и не записывали.
+	PolicyFIFO               // PolicyFIFO - вытесняется элемент, который дольше всех не записывали; чтение не меняет порядок.
+)
+
+// String возвращает название политики вытеснения.
+func (p Policy) String() string {
+	switch p {
+	case PolicyLRU:
+		return "lru"
+	case PolicyFIFO:
+		return "fifo"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText возвращает название политики вытеснения.
+func (p Policy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText разбирает название политики вытеснения.
+func (p *Policy) UnmarshalText(text []byte) error {
+	policy, err := ParsePolicy(string(text))
+	if err != nil {
+		return err
+	}
+	*p = policy
+	return nil
+}
+
+// ParsePolicy разбирает название политики вытеснения: lru или fifo без учета регистра.
+func ParsePolicy(s string) (Policy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "lru":
+		return PolicyLRU, nil
+	case "fifo":
+		return PolicyFIFO, nil
+	}
+	return PolicyLRU, errors.New("unknown eviction policy: " + s)
+}
+
+// Sizer возвращает размер элемента в байтах.
+type Sizer func(key string, value interface{}) int64
+
+// DefaultSizer оценивает размер элемента как длину ключа и длину значения:
+// строки и []byte учитываются по длине, остальные значения - по длине JSON-представления.
+func DefaultSizer(key string, value interface{}) int64 {
+	switch v := value.(type) {
+	case string:
+		return int64(len(key) + len(v))
+	case []byte:
+		return int64(len(key) + len(v))
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return int64(len(key))
+	}
+	return int64(len(key) + len(b))
+}
+
+// EvictFunc вызывается для каждого удаленного из кэша элемента с причиной удаления.
+type EvictFunc func(key string, value interface{}, reason EvictionReason)
+
+// WithCapacity задает максимальное количество элементов в кэше.
+func WithCapacity(capacity int) Option {
+	return func(c *Cache) {
+		c.capacity = capacity
+	}
+}
+
+// WithMaxBytes задает максимальный суммарный размер элементов в байтах, по умолчанию размер не ограничен.
+// При превышении ограничения элементы вытесняются так же, как при превышении емкости,
+// а запись элемента больше ограничения завершается ошибкой ErrEntryTooLarge.
+func WithMaxBytes(maxBytes int64) Option {
+	return func(c *Cache) {
+		c.maxBytes = maxBytes
+	}
+}
+
+// WithSizer задает функцию вычисления размера элемента для WithMaxBytes. По умолчанию используется DefaultSizer.
+func WithSizer(fn Sizer) Option {
+	return func(c *Cache) {
+		c.sizer = fn
+	}
+}
+
+// WithTTL задает время жизни элемента, если при записи TTL равен нулю.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithClock задает источник текущего времени. По умолчанию используется системное время.
+func WithClock(clock Clock) Option {
+	return func(c *Cache) {
+		c.clock = clock
+	}
+}
+
+// WithPolicy задает политику вытеснения. По умолчанию используется PolicyLRU.
+func WithPolicy(policy Policy) Option {
+	return func(c *Cache) {
+		c.policy = policy
+	}
+}
+
+// WithOnEvict задает обработчик удаления элементов: вытеснения по емкости, истечения срока действия,
+// явного удаления и очистки кэша. Обработчик вызывается синхронно в горутине, выполнившей операцию,
+// после освобождения блокировки кэша, поэтому может обращаться к кэшу, но не должен выполняться долго.
+func WithOnEvict(fn EvictFunc) Option {
+	return func(c *Cache) {
+		c.onEvict = fn
+	}
+}